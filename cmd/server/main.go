@@ -9,19 +9,27 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"gateway-digiflazz/internal/config"
 	"gateway-digiflazz/internal/handlers"
 	"gateway-digiflazz/internal/middleware"
+	"gateway-digiflazz/internal/repositories"
 	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/internal/validation"
 	"gateway-digiflazz/pkg/cache"
 	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/metrics"
+	"gateway-digiflazz/pkg/operator"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 // Version and build information (set during build)
@@ -45,7 +53,7 @@ func setDefaultEnvVars() {
 		"DIGIFLAZZ_BASE_URL":  "https://api.digiflazz.com",
 		"DIGIFLAZZ_TIMEOUT":   "30s",
 		"DIGIFLAZZ_RETRY_ATTEMPTS": "3",
-		"OTOMAX_SECRET_KEY":   "default-secret-key",
+		"OTOMAX_SECRET_KEY":   services.DefaultOtomaxSecretKey,
 		"OTOMAX_CALLBACK_URL": "http://localhost:8080/otomax/callback",
 	}
 
@@ -81,10 +89,17 @@ LOG_FORMAT=text
 DIGIFLAZZ_USERNAME=your_username
 DIGIFLAZZ_API_KEY=your_api_key
 DIGIFLAZZ_BASE_URL=https://api.digiflazz.com
+# Log level of the redacted request/response bodies (debug, info, ...) or off
+DIGIFLAZZ_BODY_LOG_LEVEL=debug
 
 # Otomax Configuration
 OTOMAX_SECRET_KEY=default-secret-key
 OTOMAX_CALLBACK_URL=http://localhost:8080/otomax/callback
+# Opt-in request signing per reseller: id:algorithm:secret (algorithm md5 or hmac-sha256)
+OTOMAX_RESELLERS=
+OTOMAX_MAX_CLOCK_SKEW=5m
+# Response and callback signatures: md5 (ref_id + status + secret) or hmac-sha256
+OTOMAX_RESPONSE_SIGNING=md5
 
 # Database Configuration (if needed)
 DB_HOST=localhost
@@ -93,18 +108,44 @@ DB_NAME=digiflazz_gateway
 DB_USER=your_db_user
 DB_PASSWORD=your_db_password
 
-# Cache Configuration
+# Cache Configuration (sqlite, redis or memory)
 CACHE_TYPE=sqlite
-CACHE_SQLITE_PATH=cache.db
+# CACHE_DB_PATH=data/cache.db
+# Key prefix used by the redis cache
+CACHE_KEY_PREFIX=gateway:
+CACHE_SQLITE_MAX_OPEN_CONNS=8
+CACHE_SQLITE_BUSY_TIMEOUT=5s
+# In-memory LRU tier in front of the cache backend
+CACHE_MEMORY_TIER=true
+CACHE_MEMORY_MAX_ENTRIES=10000
+CACHE_MEMORY_MAX_BYTES=16777216
+CACHE_MEMORY_TTL=5m
 CACHE_TTL=24h
 
 # Security Configuration
 JWT_SECRET=your-jwt-secret-key
-API_KEY=your-api-key
-
-# Rate Limiting
+# Plaintext key with every scope; prefer hashed keys in API_KEYS (name:sha256:scope+scope[:role],...)
+# Generate hashes with: -hash-api-key <key>
+API_KEY=
+API_KEYS=
+
+# Client IP resolution and source IP allowlists (comma separated CIDRs or addresses)
+# Forwarded headers are only trusted from TRUSTED_PROXIES
+TRUSTED_PROXIES=
+IP_ALLOWLIST_OTOMAX=
+IP_ALLOWLIST_ADMIN=
+
+# Audit trail of destructive management actions (default: audit.log next to the cache database)
+AUDIT_LOG_PATH=
+
+# Rate Limiting (token bucket per API key or client IP; 0 requests disables)
+RATE_LIMIT_STORE=memory
 RATE_LIMIT_REQUESTS=100
 RATE_LIMIT_WINDOW=1m
+# Per route group budgets as requests or requests/window, e.g. 30/1m
+RATE_LIMIT_TRANSACT=
+RATE_LIMIT_INQUIRY=
+RATE_LIMIT_ADMIN=
 
 # Health Check
 HEALTH_CHECK_INTERVAL=30s
@@ -156,6 +197,14 @@ func main() {
 		showVersion = flag.Bool("version", false, "Show version information")
 		showConfig  = flag.Bool("config", false, "Show configuration information")
 	)
+	hashAPIKey := flag.String("hash-api-key", "", "Print the SHA-256 hash of an API key for security.api_keys and exit")
+	var cacheCmd plnCacheCommand
+	flag.StringVar(&cacheCmd.exportPath, "export-pln-cache", "", "Export the PLN cache to a file (- for stdout) and exit")
+	flag.StringVar(&cacheCmd.importPath, "import-pln-cache", "", "Import the PLN cache from a file (- for stdin) and exit")
+	flag.StringVar(&cacheCmd.warmPath, "warm-pln-cache", "", "Warm the PLN cache from a file of customer numbers (- for stdin) and exit")
+	flag.StringVar(&cacheCmd.format, "cache-format", "", "Export/import format: jsonl or csv (default: from the file extension)")
+	flag.StringVar(&cacheCmd.importPolicy, "import-policy", services.ImportPolicySkip, "Import conflict policy: skip, overwrite or newer")
+	flag.IntVar(&cacheCmd.warmConcurrency, "warm-concurrency", services.DefaultWarmConcurrency, "Parallel Digiflazz inquiries during a warm-up")
 	flag.Parse()
 
 	// Handle help flag
@@ -164,6 +213,12 @@ func main() {
 		return
 	}
 
+	// Handle hash-api-key flag
+	if *hashAPIKey != "" {
+		fmt.Println(middleware.HashAPIKey(*hashAPIKey))
+		return
+	}
+
 	// Handle version flag
 	if *showVersion {
 		showVersionInfo()
@@ -172,7 +227,9 @@ func main() {
 
 	// Handle config flag
 	if *showConfig {
-		showConfigInfo()
+		if err := showConfigInfo(); err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
 		return
 	}
 
@@ -200,33 +257,99 @@ func main() {
 		logger.SetFormatter(&logrus.JSONFormatter{})
 	}
 
-	// Initialize Digiflazz client
-	digiflazzClient := digiflazz.NewClient(cfg.Digiflazz, logger)
+	// Initialize Prometheus metrics; a nil collector records nothing
+	var gatewayMetrics *metrics.Metrics
+	if cfg.Monitoring.EnableMetrics {
+		gatewayMetrics = metrics.New()
+	}
 
-	// Initialize SQLite cache with proper path handling
+	// Initialize Digiflazz client pool
+	digiflazzPool, err := digiflazz.NewPool(cfg.Digiflazz, logger)
+	if err != nil {
+		log.Fatalf("Failed to initialize Digiflazz accounts: %v", err)
+	}
+	digiflazzPool.SetMetrics(gatewayMetrics)
+	digiflazzClient := digiflazzPool.Default()
+	logger.WithField("accounts", digiflazzPool.Accounts()).Info("Digiflazz accounts configured")
+
+	// Initialize cache backend selected by CACHE_TYPE
 	cachePath := getCachePath()
-	logger.WithField("cache_path", cachePath).Info("Initializing SQLite cache")
-	
-	sqliteCache, err := cache.NewSQLiteCache(cachePath)
+	if cfg.Cache.SQLitePath == "" {
+		cfg.Cache.SQLitePath = cachePath
+	}
+	logger.WithFields(logrus.Fields{
+		"cache_type": cfg.Cache.Type,
+		"cache_path": cfg.Cache.SQLitePath,
+	}).Info("Initializing cache")
+
+	cacheBackend, err := cache.New(cfg.Cache, cfg.Redis)
 	if err != nil {
-		logger.WithError(err).WithField("cache_path", cachePath).Error("Failed to initialize SQLite cache")
-		log.Fatalf("Failed to initialize SQLite cache: %v", err)
+		logger.WithError(err).WithField("cache_type", cfg.Cache.Type).Error("Failed to initialize cache")
+		log.Fatalf("Failed to initialize cache: %v", err)
 	}
-	defer sqliteCache.Close()
+	defer cacheBackend.Close()
+
+	// Initialize generic product code resolver
+	productResolver := operator.NewResolver(cfg.Products.Generic)
+	registerKnownProducts(gatewayMetrics, cfg)
+
+	// Initialize customer number validators
+	validators := validation.NewRegistry(cfg.Validation)
 
 	// Initialize services
 	transactionService := services.NewTransactionService(digiflazzClient, logger)
-	balanceService := services.NewBalanceService(digiflazzClient, logger)
+	transactionService.SetProductResolver(productResolver)
+	transactionService.SetValidators(validators)
+	transactionService.SetMetrics(gatewayMetrics)
+	balanceService := services.NewBalanceService(digiflazzPool, logger)
 	priceService := services.NewPriceService(digiflazzClient, logger)
+	priceService.SetMetrics(gatewayMetrics)
 	pascabayarService := services.NewPascabayarService(digiflazzClient, logger)
-	plnInquiryService := services.NewPLNInquiryService(digiflazzClient, logger, sqliteCache)
+	pascabayarService.SetValidators(validators)
+	pascabayarService.SetMetrics(gatewayMetrics)
+	plnInquiryService := services.NewPLNInquiryService(digiflazzClient, logger, cacheBackend)
+	plnInquiryService.SetValidators(validators)
+	plnInquiryService.SetMetrics(gatewayMetrics)
+	plnInquiryService.SetConfigRepository(repositories.NewFilePLNCacheConfigRepository(
+		filepath.Join(filepath.Dir(cfg.Cache.SQLitePath), "pln_cache_config.json")))
+	if err := plnInquiryService.LoadCacheConfig(); err != nil {
+		logger.WithError(err).Warn("Failed to load persisted PLN cache configuration, using defaults")
+	}
+
+	// Run a PLN cache maintenance command instead of starting the server
+	if cacheCmd.requested() {
+		if err := cacheCmd.run(plnInquiryService); err != nil {
+			logger.WithError(err).Error("PLN cache command failed")
+			cacheBackend.Close()
+			os.Exit(1)
+		}
+		return
+	}
 	
 	// Initialize Otomax service
-	otomaxSecretKey := os.Getenv("OTOMAX_SECRET_KEY")
+	otomaxSecretKey := cfg.Otomax.SecretKey
 	if otomaxSecretKey == "" {
-		otomaxSecretKey = "default-secret-key" // TODO: Use proper secret key management
+		otomaxSecretKey = services.DefaultOtomaxSecretKey
 	}
-	otomaxService := services.NewOtomaxService(digiflazzClient, logger, otomaxSecretKey)
+	// NewOtomaxSigner refuses signing resellers that would fall back to a placeholder key
+	if services.IsPlaceholderSecretKey(otomaxSecretKey) {
+		logger.Warn("OTOMAX_SECRET_KEY is a published placeholder; signatures made with it can be forged and Digiflazz callbacks are rejected")
+	}
+	otomaxCfg := cfg.Otomax
+	otomaxCfg.SecretKey = otomaxSecretKey
+	otomaxSigner, err := services.NewOtomaxSigner(otomaxCfg)
+	if err != nil {
+		log.Fatalf("Invalid Otomax reseller configuration: %v", err)
+	}
+	otomaxTransactionRepository := repositories.NewMemoryOtomaxTransactionRepository()
+	otomaxService := services.NewOtomaxService(digiflazzPool, otomaxTransactionRepository, logger, otomaxSecretKey)
+	otomaxService.SetFallbackChains(cfg.Fallback.Chains)
+	otomaxService.SetProductResolver(productResolver)
+	otomaxService.SetValidators(validators)
+	otomaxService.SetPLNInquiryService(plnInquiryService)
+	otomaxService.SetSigner(otomaxSigner)
+	otomaxService.SetMetrics(gatewayMetrics)
+	gatewayMetrics.RegisterPendingTransactions(otomaxService.PendingTransactions)
 
 	// Initialize handlers
 	transactionHandler := handlers.NewTransactionHandler(transactionService, logger)
@@ -235,9 +358,56 @@ func main() {
 	pascabayarHandler := handlers.NewPascabayarHandler(pascabayarService, logger)
 	plnInquiryHandler := handlers.NewPLNInquiryHandler(plnInquiryService, logger)
 	otomaxHandler := handlers.NewOtomaxHandler(otomaxService, plnInquiryService, logger)
+	operatorHandler := handlers.NewOperatorHandler(productResolver, logger)
+
+	// Initialize API key authentication
+	apiKeys, err := middleware.NewAPIKeyStore(cfg.Security.APIKeys)
+	if err != nil {
+		log.Fatalf("Invalid API key configuration: %v", err)
+	}
+	if apiKeys.Enabled() {
+		logger.WithField("api_keys", len(cfg.Security.APIKeys)).Info("API key authentication enabled")
+	} else {
+		logger.Warn("No API keys configured, routes are open and management routes are disabled; set API_KEY, API_KEYS or security.api_keys")
+	}
+	authHandler := handlers.NewAuthHandler(apiKeys, logger)
+
+	// Destructive management actions are recorded in the audit log
+	auditLogPath := cfg.Security.AuditLogPath
+	if auditLogPath == "" {
+		auditLogPath = filepath.Join(filepath.Dir(cfg.Cache.SQLitePath), "audit.log")
+	}
+	auditLog := repositories.NewFileAuditLogRepository(auditLogPath)
+	authHandler.SetAuditLog(auditLog)
+
+	// Initialize rate limiting
+	rateLimiter, closeRateLimiter, err := newRateLimiter(cfg, logger)
+	if err != nil {
+		log.Fatalf("Invalid rate limit configuration: %v", err)
+	}
+	defer closeRateLimiter()
+
+	// Initialize source IP allowlists
+	ipAllowlists, err := middleware.NewIPAllowlists(cfg.Security.IPAllowlists)
+	if err != nil {
+		log.Fatalf("Invalid IP allowlist configuration: %v", err)
+	}
+	for group, list := range ipAllowlists {
+		if list.Enabled() {
+			logger.WithFields(logrus.Fields{
+				"group":    group,
+				"networks": cfg.Security.IPAllowlists[group],
+			}).Info("IP allowlist enabled")
+		}
+	}
 
 	// Setup router
-	router := setupRouter(transactionHandler, balanceHandler, priceHandler, pascabayarHandler, plnInquiryHandler, otomaxHandler, logger)
+	router := setupRouter(transactionHandler, balanceHandler, priceHandler, pascabayarHandler, plnInquiryHandler, otomaxHandler, operatorHandler, authHandler, apiKeys, rateLimiter, ipAllowlists, auditLog, gatewayMetrics, logger)
+
+	// Only trust forwarded client IPs from configured proxies
+	if err := router.SetTrustedProxies(cfg.Security.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxy configuration: %v", err)
+	}
 
 	// Create server
 	server := &http.Server{
@@ -256,6 +426,18 @@ func main() {
 		}
 	}()
 
+	// Serve /metrics on its own port so it is not exposed with the API
+	var metricsServer *http.Server
+	if gatewayMetrics != nil {
+		metricsServer = newMetricsServer(cfg, gatewayMetrics)
+		go func() {
+			logger.Infof("Metrics server starting on %s", metricsServer.Addr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatalf("Failed to start metrics server: %v", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -270,6 +452,11 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			logger.WithError(err).Warn("Metrics server forced to shutdown")
+		}
+	}
 
 	logger.Info("Server exited")
 }
@@ -281,6 +468,13 @@ func setupRouter(
 	pascabayarHandler *handlers.PascabayarHandler,
 	plnInquiryHandler *handlers.PLNInquiryHandler,
 	otomaxHandler *handlers.OtomaxHandler,
+	operatorHandler *handlers.OperatorHandler,
+	authHandler *handlers.AuthHandler,
+	apiKeys *middleware.APIKeyStore,
+	rateLimiter *middleware.RateLimiter,
+	ipAllowlists map[string]*middleware.IPAllowlist,
+	auditLog repositories.AuditLogRepository,
+	gatewayMetrics *metrics.Metrics,
 	logger *logrus.Logger,
 ) *gin.Engine {
 	// Set Gin mode
@@ -291,87 +485,226 @@ func setupRouter(
 	router := gin.New()
 
 	// Middleware
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Metrics(gatewayMetrics))
 	router.Use(middleware.Logger(logger))
 	router.Use(middleware.Recovery(logger))
 	router.Use(middleware.CORS())
-	router.Use(middleware.RateLimit())
 	router.Use(middleware.ResponseInterceptor(logger))
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
+		middleware.JSON(c, http.StatusOK, gin.H{
 			"status":  "ok",
 			"service": "digiflazz-gateway",
 			"time":    time.Now().UTC(),
 		})
 	})
 
+	// API key scopes required per endpoint
+	transact := middleware.APIKeyAuth(apiKeys, middleware.ScopeTransact, logger)
+	inquiry := middleware.APIKeyAuth(apiKeys, middleware.ScopeInquiry, logger)
+	admin := middleware.APIKeyAuth(apiKeys, middleware.ScopeAdmin, logger)
+
+	// Separate rate limit budgets per route group, applied after authentication
+	transactLimit := middleware.RateLimit(rateLimiter, middleware.ScopeTransact)
+	inquiryLimit := middleware.RateLimit(rateLimiter, middleware.ScopeInquiry)
+	adminLimit := middleware.RateLimit(rateLimiter, middleware.ScopeAdmin)
+
+	// Source IP allowlists, checked before authentication
+	otomaxAllow := middleware.IPAllowlistAuth(ipAllowlists[middleware.AllowlistGroupOtomax], logger)
+	adminAllow := middleware.IPAllowlistAuth(ipAllowlists[middleware.AllowlistGroupAdmin], logger)
+
+	// Management roles on top of the admin scope: operators may read and warm the
+	// cache, only admins may clear it, import into it or change its configuration.
+	// Audited actions are recorded after authentication and the rate limit, so callers
+	// without a valid key cannot grow the audit log.
+	operatorRole := middleware.RequireRole(apiKeys, middleware.RoleOperator, logger)
+	adminRole := middleware.RequireRole(apiKeys, middleware.RoleAdmin, logger)
+	audit := func(action string) gin.HandlerFunc {
+		return middleware.Audit(auditLog, action, logger)
+	}
+
 	// API routes
 	v1 := router.Group("/api/v1")
 	{
 		// Balance routes
-		v1.GET("/balance", balanceHandler.GetBalance)
+		v1.GET("/balance", inquiry, inquiryLimit, balanceHandler.GetBalance)
+		v1.GET("/balance/accounts", inquiry, inquiryLimit, balanceHandler.GetAccountBalances)
 
 		// Price routes
-		v1.GET("/prices", priceHandler.GetPrices)
+		v1.GET("/prices", inquiry, inquiryLimit, priceHandler.GetPrices)
+
+		// Operator detection routes
+		v1.GET("/operators", inquiry, inquiryLimit, operatorHandler.ListOperators)
+		v1.GET("/operators/detect", inquiry, inquiryLimit, operatorHandler.Detect)
 
 		// Transaction routes
 		transactions := v1.Group("/transactions")
 		{
-			transactions.POST("/topup", transactionHandler.Topup)
-			transactions.POST("/pay", transactionHandler.Pay)
-			transactions.GET("/:ref_id/status", transactionHandler.GetStatus)
+			transactions.POST("/topup", transact, transactLimit, transactionHandler.Topup)
+			transactions.POST("/pay", transact, transactLimit, transactionHandler.Pay)
+			transactions.GET("/:ref_id/status", inquiry, inquiryLimit, transactionHandler.GetStatus)
 		}
 
 		// Pascabayar routes
 		pascabayar := v1.Group("/pascabayar")
 		{
-			pascabayar.POST("/check", pascabayarHandler.CheckBill)
-			pascabayar.POST("/pay", pascabayarHandler.PayBill)
-			pascabayar.GET("/:ref_id", pascabayarHandler.GetTransaction)
+			pascabayar.POST("/check", inquiry, inquiryLimit, pascabayarHandler.CheckBill)
+			pascabayar.POST("/pay", transact, transactLimit, pascabayarHandler.PayBill)
+			pascabayar.GET("/:ref_id", inquiry, inquiryLimit, pascabayarHandler.GetTransaction)
 		}
 
 		// PLN Inquiry routes
 		pln := v1.Group("/pln")
 		{
-			pln.POST("/inquiry", plnInquiryHandler.InquiryPLN)
-			pln.GET("/stats", plnInquiryHandler.GetStats)
-			pln.DELETE("/cache/:customer_no", plnInquiryHandler.ClearCache)
-			pln.DELETE("/cache", plnInquiryHandler.ClearAllCache)
-			pln.PUT("/cache/config", plnInquiryHandler.UpdateCacheConfig)
+			pln.POST("/inquiry", inquiry, inquiryLimit, plnInquiryHandler.InquiryPLN)
+			pln.GET("/stats", adminAllow, admin, operatorRole, adminLimit, plnInquiryHandler.GetStats)
+			pln.GET("/cache", adminAllow, admin, operatorRole, adminLimit, plnInquiryHandler.SearchCache)
+			pln.DELETE("/cache/:customer_no", adminAllow, admin, adminLimit, audit("pln.cache.clear"), adminRole, plnInquiryHandler.ClearCache)
+			pln.DELETE("/cache", adminAllow, admin, adminLimit, audit("pln.cache.clear_all"), adminRole, plnInquiryHandler.ClearAllCache)
+			pln.PUT("/cache/config", adminAllow, admin, adminLimit, audit("pln.cache.config.update"), adminRole, plnInquiryHandler.UpdateCacheConfig)
+			pln.GET("/cache/export", adminAllow, admin, operatorRole, adminLimit, plnInquiryHandler.ExportCache)
+			pln.POST("/cache/import", adminAllow, admin, adminLimit, audit("pln.cache.import"), adminRole, plnInquiryHandler.ImportCache)
+			pln.POST("/cache/warm", adminAllow, admin, operatorRole, adminLimit, plnInquiryHandler.WarmCache)
+			pln.GET("/cache/warm/:job_id", adminAllow, admin, operatorRole, adminLimit, plnInquiryHandler.GetWarmJob)
 		}
+
+		// API key administration
+		v1.GET("/admin/api-keys", adminAllow, admin, adminRole, adminLimit, authHandler.ListAPIKeys)
+		v1.GET("/admin/audit", adminAllow, admin, adminRole, adminLimit, authHandler.ListAuditLog)
 	}
 
+	// Callback handling (POST for Digiflazz callbacks, which carry no API key and
+	// come from Digiflazz rather than Otomax, so the Otomax allowlist does not apply)
+	router.POST("/otomax/callback", otomaxHandler.ProcessCallback)
+
 	// Otomax API routes (GET with query parameters)
-	otomax := router.Group("/otomax")
+	otomax := router.Group("/otomax", otomaxAllow)
 	{
 		// Transaction processing via GET with query parameters
-		otomax.GET("/transaction", otomaxHandler.ProcessTransaction)
+		otomax.GET("/transaction", transact, transactLimit, otomaxHandler.ProcessTransaction)
 		
 		// Status check via GET with query parameters
-		otomax.GET("/status", otomaxHandler.CheckStatus)
-		
-		// Callback handling (POST for Digiflazz callbacks)
-		otomax.POST("/callback", otomaxHandler.ProcessCallback)
+		otomax.GET("/status", inquiry, inquiryLimit, otomaxHandler.CheckStatus)
 		
 		// Pascabayar endpoints for Otomax
-		otomax.GET("/pascabayar/check", otomaxHandler.CheckPascabayarBill)
-		otomax.GET("/pascabayar/pay", otomaxHandler.PayPascabayarBill)
-
-		otomax.GET("/pln/inquiry", otomaxHandler.InquiryPLN)
-		otomax.GET("/pln/stats", otomaxHandler.GetPLNStats)
-		otomax.DELETE("/pln/cache/:customer_no", otomaxHandler.ClearPLNCache)
-		otomax.DELETE("/pln/cache", otomaxHandler.ClearAllPLNCache)
-		otomax.PUT("/pln/cache/config", otomaxHandler.UpdatePLNCacheConfig)
+		otomax.GET("/pascabayar/check", inquiry, inquiryLimit, otomaxHandler.CheckPascabayarBill)
+		otomax.GET("/pascabayar/pay", transact, transactLimit, otomaxHandler.PayPascabayarBill)
+
+		// Lets resellers test their signing against the gateway
+		otomax.GET("/signature/check", inquiry, inquiryLimit, otomaxHandler.CheckSignature)
+
+		otomax.GET("/pln/inquiry", inquiry, inquiryLimit, otomaxHandler.InquiryPLN)
+		otomax.GET("/pln/token", transact, transactLimit, otomaxHandler.PurchasePLNToken)
+		otomax.GET("/pln/stats", adminAllow, admin, operatorRole, adminLimit, otomaxHandler.GetPLNStats)
+		otomax.DELETE("/pln/cache/:customer_no", adminAllow, admin, adminLimit, audit("pln.cache.clear"), adminRole, otomaxHandler.ClearPLNCache)
+		otomax.DELETE("/pln/cache", adminAllow, admin, adminLimit, audit("pln.cache.clear_all"), adminRole, otomaxHandler.ClearAllPLNCache)
+		otomax.PUT("/pln/cache/config", adminAllow, admin, adminLimit, audit("pln.cache.config.update"), adminRole, otomaxHandler.UpdatePLNCacheConfig)
 		
 		// Additional Otomax endpoints
-		otomax.GET("/history", otomaxHandler.GetTransactionHistory)
-		otomax.GET("/products", otomaxHandler.GetProductList)
+		otomax.GET("/history", inquiry, inquiryLimit, otomaxHandler.GetTransactionHistory)
+		otomax.GET("/products", inquiry, inquiryLimit, otomaxHandler.GetProductList)
 	}
 
 	return router
 }
 
+// defaultMetricsPort is used when metrics are enabled without monitoring.metrics_port
+const defaultMetricsPort = 9090
+
+// newMetricsServer creates the listener serving /metrics on the monitoring port
+func newMetricsServer(cfg *config.Config, gatewayMetrics *metrics.Metrics) *http.Server {
+	port := cfg.Monitoring.MetricsPort
+	if port == 0 {
+		port = defaultMetricsPort
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", gatewayMetrics.Handler())
+	return &http.Server{
+		Addr:        cfg.Server.Host + ":" + strconv.Itoa(port),
+		Handler:     mux,
+		ReadTimeout: cfg.Server.ReadTimeout,
+		IdleTimeout: cfg.Server.IdleTimeout,
+	}
+}
+
+// registerKnownProducts marks the configured generic product and fallback chain SKUs as
+// known, so their failed transactions keep their SKU label
+func registerKnownProducts(gatewayMetrics *metrics.Metrics, cfg *config.Config) {
+	for _, product := range cfg.Products.Generic {
+		for _, sku := range product.SKUs {
+			gatewayMetrics.AddKnownProduct(sku, product.Category)
+		}
+	}
+	for _, chain := range cfg.Fallback.Chains {
+		gatewayMetrics.AddKnownProduct(chain.SKU, "")
+		for _, sku := range chain.Fallbacks {
+			gatewayMetrics.AddKnownProduct(sku, "")
+		}
+	}
+}
+
+// newRateLimiter creates the rate limiter and its bucket store; it returns a nil limiter
+// when rate limiting is disabled. The returned func releases the store.
+func newRateLimiter(cfg *config.Config, logger *logrus.Logger) (*middleware.RateLimiter, func(), error) {
+	rateCfg := cfg.Security.RateLimit
+	if rateCfg.Requests <= 0 {
+		logger.Warn("Rate limiting disabled; set RATE_LIMIT_REQUESTS or security.rate_limit.requests")
+		return nil, func() {}, nil
+	}
+
+	storeType := strings.ToLower(rateCfg.Store)
+	if storeType == "" {
+		storeType = middleware.RateLimitStoreMemory
+	}
+
+	var store middleware.RateLimitStore
+	closeStore := func() {}
+	switch storeType {
+	case middleware.RateLimitStoreMemory:
+		store = middleware.NewMemoryRateLimitStore()
+	case middleware.RateLimitStoreRedis:
+		host := cfg.Redis.Host
+		if host == "" {
+			host = "localhost"
+		}
+		port := cfg.Redis.Port
+		if port == 0 {
+			port = 6379
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:     host + ":" + strconv.Itoa(port),
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+			PoolSize: cfg.Redis.PoolSize,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			return nil, nil, fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		store = middleware.NewRedisRateLimitStore(client, cfg.Cache.KeyPrefix+"ratelimit:")
+		closeStore = func() { client.Close() }
+	default:
+		return nil, nil, fmt.Errorf("unsupported rate limit store %q (expected memory or redis)", rateCfg.Store)
+	}
+
+	limiter := middleware.NewRateLimiter(rateCfg, store, logger)
+	for _, group := range []string{middleware.ScopeTransact, middleware.ScopeInquiry, middleware.ScopeAdmin} {
+		rule := limiter.Rule(group)
+		logger.WithFields(logrus.Fields{
+			"group":    group,
+			"requests": rule.Requests,
+			"window":   rule.Window,
+			"burst":    rule.Burst,
+			"store":    storeType,
+		}).Info("Rate limit configured")
+	}
+	return limiter, closeStore, nil
+}
+
 // showHelpInfo displays help information
 func showHelpInfo() {
 	fmt.Printf(`Digiflazz Gateway API Server
@@ -382,7 +715,14 @@ USAGE:
 OPTIONS:
     -help, --help     Show this help message
     -version, --version  Show version information
-    -config, --config    Show configuration information
+    -config, --config    Show the effective configuration with secrets masked
+    -hash-api-key KEY        Print the hash to store for an API key and exit
+    -export-pln-cache FILE   Export the PLN cache (jsonl or csv, - for stdout) and exit
+    -import-pln-cache FILE   Import a PLN cache export (- for stdin) and exit
+    -warm-pln-cache FILE     Look up the customer numbers in FILE, one per line, and exit
+    -cache-format FORMAT     jsonl or csv (default: from the file extension)
+    -import-policy POLICY    skip (default), overwrite or newer
+    -warm-concurrency N      Parallel Digiflazz inquiries during a warm-up (default: 4)
 
 ENVIRONMENT VARIABLES:
     SERVER_HOST         Server host (default: 0.0.0.0)
@@ -391,15 +731,23 @@ ENVIRONMENT VARIABLES:
     DIGIFLAZZ_USERNAME  Digiflazz API username
     DIGIFLAZZ_API_KEY   Digiflazz API key
     DIGIFLAZZ_BASE_URL  Digiflazz API base URL (default: https://api.digiflazz.com)
+    DIGIFLAZZ_BODY_LOG_LEVEL  Log level of redacted Digiflazz bodies, or off (default: debug)
+    API_KEY             Plaintext API key granted every scope
+    API_KEYS            Hashed API keys: name:sha256:scope+scope[:role],...
+
+    Secrets (DIGIFLAZZ_API_KEY, DB_PASSWORD, REDIS_PASSWORD, JWT_SECRET, API_KEY,
+    API_KEYS, OTOMAX_SECRET_KEY, OTOMAX_RESELLERS) can be read from a file instead,
+    e.g. a Docker secret: DIGIFLAZZ_API_KEY_FILE=/run/secrets/digiflazz_api_key
 
 EXAMPLES:
     %s                    # Start server with default configuration
     %s -version          # Show version information
-    %s -config           # Show configuration information
+    %s -config           # Show the effective configuration
     %s -help             # Show this help message
+    %s -export-pln-cache data/pln_cache.jsonl
 
 For more information, visit: https://developer.digiflazz.com/api/
-`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 }
 
 // showVersionInfo displays version information
@@ -414,41 +762,22 @@ Copyright (c) 2024 Digiflazz Gateway
 `, version, buildTime, fmt.Sprintf("%s %s/%s", "go1.21", "linux", "amd64"))
 }
 
-// showConfigInfo displays configuration information
-func showConfigInfo() {
-	fmt.Printf(`Digiflazz Gateway API Server - Configuration
-
-Current Configuration:
-  Server Host: %s
-  Server Port: %s
-  Log Level: %s
-  Digiflazz Username: %s
-  Digiflazz API Key: %s
-  Digiflazz Base URL: %s
-  Cache Type: %s
-  Cache Path: %s
-
-Environment Variables:
-`, 
-		os.Getenv("SERVER_HOST"),
-		os.Getenv("SERVER_PORT"),
-		os.Getenv("LOG_LEVEL"),
-		os.Getenv("DIGIFLAZZ_USERNAME"),
-		maskString(os.Getenv("DIGIFLAZZ_API_KEY")),
-		os.Getenv("DIGIFLAZZ_BASE_URL"),
-		os.Getenv("CACHE_TYPE"),
-		os.Getenv("CACHE_SQLITE_PATH"))
-
-	// Show all environment variables
-	for _, env := range os.Environ() {
-		fmt.Printf("  %s\n", env)
-	}
-}
+// showConfigInfo displays the effective configuration, merged from configs/config.yaml,
+// .env and the environment, with every secret masked
+func showConfigInfo() error {
+	// .env is optional here; -config never creates it
+	_ = godotenv.Load()
+	setDefaultEnvVars()
 
-// maskString masks sensitive strings for display
-func maskString(s string) string {
-	if len(s) <= 8 {
-		return "***"
+	cfg, err := config.Load()
+	if err != nil {
+		return err
 	}
-	return s[:4] + "***" + s[len(s)-4:]
+	data, err := yaml.Marshal(cfg.Masked())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Digiflazz Gateway API Server - Configuration\n\n%s", data)
+	return nil
 }
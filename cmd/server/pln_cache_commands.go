@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gateway-digiflazz/internal/services"
+)
+
+// plnCacheCommand holds the PLN cache maintenance flags
+type plnCacheCommand struct {
+	exportPath      string
+	importPath      string
+	warmPath        string
+	format          string
+	importPolicy    string
+	warmConcurrency int
+}
+
+// requested reports whether a PLN cache command was given instead of starting the server
+func (cmd plnCacheCommand) requested() bool {
+	return cmd.exportPath != "" || cmd.importPath != "" || cmd.warmPath != ""
+}
+
+// run executes the requested PLN cache command; "-" reads stdin or writes stdout
+func (cmd plnCacheCommand) run(plnInquiryService *services.PLNInquiryService) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch {
+	case cmd.exportPath != "":
+		return cmd.export(ctx, plnInquiryService)
+	case cmd.importPath != "":
+		return cmd.importEntries(ctx, plnInquiryService)
+	default:
+		return cmd.warm(ctx, plnInquiryService)
+	}
+}
+
+// formatFor returns the -cache-format flag, or the format implied by path
+func (cmd plnCacheCommand) formatFor(path string) string {
+	if cmd.format != "" {
+		return strings.ToLower(cmd.format)
+	}
+	return services.CacheFormatForFile(path)
+}
+
+func (cmd plnCacheCommand) export(ctx context.Context, plnInquiryService *services.PLNInquiryService) error {
+	var w io.Writer = os.Stdout
+	if cmd.exportPath != "-" {
+		file, err := os.Create(cmd.exportPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	count, err := plnInquiryService.ExportCache(ctx, w, cmd.formatFor(cmd.exportPath))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d PLN cache entries\n", count)
+	return nil
+}
+
+func (cmd plnCacheCommand) importEntries(ctx context.Context, plnInquiryService *services.PLNInquiryService) error {
+	r, closeInput, err := openInput(cmd.importPath)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	result, err := plnInquiryService.ImportCache(ctx, r, cmd.formatFor(cmd.importPath), cmd.importPolicy)
+	if err != nil {
+		return err
+	}
+	return printJSON(result)
+}
+
+func (cmd plnCacheCommand) warm(ctx context.Context, plnInquiryService *services.PLNInquiryService) error {
+	r, closeInput, err := openInput(cmd.warmPath)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	// One customer number per line; blank lines and # comments are ignored
+	var customerNos []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		customerNos = append(customerNos, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	result, err := plnInquiryService.WarmCache(ctx, customerNos, cmd.warmConcurrency)
+	if err != nil {
+		return err
+	}
+	return printJSON(result)
+}
+
+// openInput opens path for reading, or stdin for "-"
+func openInput(path string) (io.Reader, func(), error) {
+	if path == "-" {
+		return os.Stdin, func() {}, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// printJSON prints v as indented JSON on stdout
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
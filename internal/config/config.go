@@ -1,9 +1,12 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -15,9 +18,14 @@ type Config struct {
 	Digiflazz  DigiflazzConfig  `yaml:"digiflazz"`
 	Database   DatabaseConfig   `yaml:"database"`
 	Redis      RedisConfig      `yaml:"redis"`
+	Cache      CacheConfig      `yaml:"cache"`
 	Logging    LoggingConfig    `yaml:"logging"`
 	Security   SecurityConfig   `yaml:"security"`
 	Monitoring MonitoringConfig `yaml:"monitoring"`
+	Fallback   FallbackConfig   `yaml:"fallback"`
+	Products   ProductConfig    `yaml:"products"`
+	Validation ValidationConfig `yaml:"validation"`
+	Otomax     OtomaxConfig     `yaml:"otomax"`
 }
 
 // ServerConfig holds server configuration
@@ -37,6 +45,29 @@ type DigiflazzConfig struct {
 	IPWhitelist  string        `yaml:"ip_whitelist"`
 	Timeout      time.Duration `yaml:"timeout"`
 	RetryAttempts int          `yaml:"retry_attempts"`
+	Failover     string        `yaml:"failover"`
+	Accounts     []DigiflazzAccountConfig `yaml:"accounts"`
+	Routes       []DigiflazzRouteConfig   `yaml:"routes"`
+	// BodyLogLevel is the log level of the redacted request and response bodies
+	// (debug by default), or off
+	BodyLogLevel string `yaml:"body_log_level"`
+}
+
+// DigiflazzAccountConfig holds configuration for an additional Digiflazz account
+type DigiflazzAccountConfig struct {
+	Name        string `yaml:"name"`
+	Username    string `yaml:"username"`
+	APIKey      string `yaml:"api_key"`
+	BaseURL     string `yaml:"base_url"`
+	IPWhitelist string `yaml:"ip_whitelist"`
+	Failover    string `yaml:"failover"`
+}
+
+// DigiflazzRouteConfig routes transactions to an account by reseller or product category
+type DigiflazzRouteConfig struct {
+	Reseller string `yaml:"reseller"`
+	Category string `yaml:"category"`
+	Account  string `yaml:"account"`
 }
 
 // DatabaseConfig holds database configuration
@@ -62,6 +93,21 @@ type RedisConfig struct {
 	MinIdleConnections int  `yaml:"min_idle_connections"`
 }
 
+// CacheConfig selects and configures the cache backend
+type CacheConfig struct {
+	Type       string `yaml:"type"`
+	SQLitePath string `yaml:"sqlite_path"`
+	KeyPrefix  string `yaml:"key_prefix"`
+	// SQLite connection pool; the database runs in WAL mode so readers don't block the writer
+	SQLiteMaxOpenConns int           `yaml:"sqlite_max_open_conns"`
+	SQLiteBusyTimeout  time.Duration `yaml:"sqlite_busy_timeout"`
+	// In-memory LRU tier in front of the sqlite or redis backend
+	MemoryTier       bool          `yaml:"memory_tier"`
+	MemoryMaxEntries int           `yaml:"memory_max_entries"`
+	MemoryMaxBytes   int64         `yaml:"memory_max_bytes"`
+	MemoryTTL        time.Duration `yaml:"memory_ttl"`
+}
+
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
 	Level      string `yaml:"level"`
@@ -80,6 +126,48 @@ type SecurityConfig struct {
 	CORSOrigins []string `yaml:"cors_origins"`
 	CORSMethods []string `yaml:"cors_methods"`
 	CORSHeaders []string `yaml:"cors_headers"`
+	// API keys accepted by the auth middleware; authentication is enforced once any key is configured
+	APIKeys []APIKeyConfig `yaml:"api_keys"`
+	// Token bucket rate limiting per API client (or client IP) and route group
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	// Proxies whose X-Forwarded-For / X-Real-IP headers are trusted for the client IP;
+	// none by default, so the client IP is the connection's remote address
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// CIDRs or addresses allowed per route group (otomax, admin); an empty list allows any IP
+	IPAllowlists map[string][]string `yaml:"ip_allowlists"`
+	// JSON lines file recording destructive management actions; defaults to audit.log
+	// next to the SQLite cache
+	AuditLogPath string `yaml:"audit_log_path"`
+}
+
+// RateLimitConfig holds the rate limit budgets. Requests per Window is the default
+// budget of every route group; zero requests disables rate limiting.
+type RateLimitConfig struct {
+	// Store keeps the buckets: memory (per instance) or redis (shared across instances)
+	Store    string        `yaml:"store"`
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+	// Burst is the bucket size; defaults to Requests
+	Burst int `yaml:"burst"`
+	// Groups overrides the budget of the transact, inquiry and admin route groups
+	Groups map[string]RateLimitRuleConfig `yaml:"groups"`
+}
+
+// RateLimitRuleConfig is the budget of a single route group
+type RateLimitRuleConfig struct {
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+	Burst    int           `yaml:"burst"`
+}
+
+// APIKeyConfig defines a named API key. Only the hex SHA-256 of the key is stored.
+type APIKeyConfig struct {
+	Name    string   `yaml:"name"`
+	KeyHash string   `yaml:"key_hash"`
+	Scopes  []string `yaml:"scopes"`
+	// Role on the management routes: admin, operator or reseller. Defaults to admin
+	// for keys with the admin scope and reseller otherwise.
+	Role string `yaml:"role"`
 }
 
 // MonitoringConfig holds monitoring configuration
@@ -89,6 +177,68 @@ type MonitoringConfig struct {
 	HealthCheckInterval  time.Duration `yaml:"health_check_interval"`
 }
 
+// FallbackConfig holds fallback SKU chains for prabayar transactions
+type FallbackConfig struct {
+	Chains []FallbackChainConfig `yaml:"chains"`
+}
+
+// FallbackChainConfig defines the SKUs tried in order when a SKU fails on the seller side
+type FallbackChainConfig struct {
+	SKU       string   `yaml:"sku"`
+	Fallbacks []string `yaml:"fallbacks"`
+	MaxPrice  float64  `yaml:"max_price"`
+}
+
+// ProductConfig holds generic product codes that resolve to operator specific SKUs
+type ProductConfig struct {
+	Generic []GenericProductConfig `yaml:"generic"`
+}
+
+// GenericProductConfig maps a generic product code to a Digiflazz SKU per operator
+type GenericProductConfig struct {
+	Code     string            `yaml:"code"`
+	Category string            `yaml:"category"`
+	SKUs     map[string]string `yaml:"skus"`
+}
+
+// ValidationConfig holds customer number validation rules
+type ValidationConfig struct {
+	SKUPrefixes map[string]string        `yaml:"sku_prefixes"`
+	Lengths     []ValidationLengthConfig `yaml:"lengths"`
+}
+
+// ValidationLengthConfig defines a numeric validator for a category or brand, e.g. a PDAM region
+type ValidationLengthConfig struct {
+	Key       string `yaml:"key"`
+	Prefix    string `yaml:"prefix"`
+	MinLength int    `yaml:"min_length"`
+	MaxLength int    `yaml:"max_length"`
+}
+
+// OtomaxConfig holds Otomax H2H signing settings
+type OtomaxConfig struct {
+	// SecretKey signs messages for resellers without their own secret
+	SecretKey string `yaml:"secret_key"`
+	// MaxClockSkew bounds the timestamp of signed requests; defaults to 5 minutes
+	MaxClockSkew time.Duration `yaml:"max_clock_skew"`
+	// ResponseSigning signs responses and callbacks: md5 (default) or hmac-sha256
+	ResponseSigning string                 `yaml:"response_signing"`
+	Resellers       []OtomaxResellerConfig `yaml:"resellers"`
+}
+
+// OtomaxResellerConfig holds the signing settings of one Otomax reseller
+type OtomaxResellerConfig struct {
+	// ID matches the reseller_id query parameter
+	ID string `yaml:"id"`
+	// Client is the API key name of the reseller; its requests use these settings whatever reseller_id they send
+	Client string `yaml:"client"`
+	Secret string `yaml:"secret"`
+	// RequestSigning is md5 or hmac-sha256; empty accepts unsigned requests
+	RequestSigning string `yaml:"request_signing"`
+	// ResponseSigning overrides the response algorithm for this reseller
+	ResponseSigning string `yaml:"response_signing"`
+}
+
 // Load loads configuration from environment variables and config file
 func Load() (*Config, error) {
 	cfg := &Config{}
@@ -99,11 +249,96 @@ func Load() (*Config, error) {
 	}
 
 	// Override with environment variables
-	loadFromEnv(cfg)
+	secrets, err := loadSecretFiles()
+	if err != nil {
+		return nil, err
+	}
+	if err := loadFromEnv(cfg, secrets); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
 
+// secretEnvVars can also be read from the file named by <NAME>_FILE, e.g. a Docker secret
+var secretEnvVars = []string{
+	"DIGIFLAZZ_API_KEY",
+	"DB_PASSWORD",
+	"REDIS_PASSWORD",
+	"JWT_SECRET",
+	"API_KEY",
+	"API_KEYS",
+	"OTOMAX_SECRET_KEY",
+	"OTOMAX_RESELLERS",
+}
+
+// loadSecretFiles reads the secrets whose <NAME>_FILE variable is set. Surrounding
+// whitespace is trimmed. A file takes precedence over the plain variable so a placeholder
+// in .env cannot shadow a mounted secret.
+func loadSecretFiles() (map[string]string, error) {
+	secrets := make(map[string]string)
+	for _, name := range secretEnvVars {
+		path := os.Getenv(name + "_FILE")
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			return nil, fmt.Errorf("%s_FILE %s is empty", name, path)
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
+
+// maskedSecret replaces a configured secret when displaying configuration
+const maskedSecret = "********"
+
+// mask hides a non-empty secret, leaving unset values visible as empty
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return maskedSecret
+}
+
+// Masked returns a copy of the configuration with every secret and key hash masked,
+// safe to print or log
+func (c *Config) Masked() *Config {
+	masked := *c
+	masked.Digiflazz.APIKey = mask(c.Digiflazz.APIKey)
+	if c.Digiflazz.Accounts != nil {
+		masked.Digiflazz.Accounts = make([]DigiflazzAccountConfig, len(c.Digiflazz.Accounts))
+		for i, account := range c.Digiflazz.Accounts {
+			account.APIKey = mask(account.APIKey)
+			masked.Digiflazz.Accounts[i] = account
+		}
+	}
+	masked.Database.Password = mask(c.Database.Password)
+	masked.Redis.Password = mask(c.Redis.Password)
+	masked.Security.JWTSecret = mask(c.Security.JWTSecret)
+	if c.Security.APIKeys != nil {
+		masked.Security.APIKeys = make([]APIKeyConfig, len(c.Security.APIKeys))
+		for i, key := range c.Security.APIKeys {
+			key.KeyHash = mask(key.KeyHash)
+			masked.Security.APIKeys[i] = key
+		}
+	}
+	masked.Otomax.SecretKey = mask(c.Otomax.SecretKey)
+	if c.Otomax.Resellers != nil {
+		masked.Otomax.Resellers = make([]OtomaxResellerConfig, len(c.Otomax.Resellers))
+		for i, reseller := range c.Otomax.Resellers {
+			reseller.Secret = mask(reseller.Secret)
+			masked.Otomax.Resellers[i] = reseller
+		}
+	}
+	return &masked
+}
+
 // loadFromYAML loads configuration from YAML file
 func loadFromYAML(cfg *Config) error {
 	configFile := "configs/config.yaml"
@@ -120,8 +355,17 @@ func loadFromYAML(cfg *Config) error {
 	return yaml.Unmarshal(data, cfg)
 }
 
-// loadFromEnv loads configuration from environment variables
-func loadFromEnv(cfg *Config) {
+// loadFromEnv loads configuration from environment variables; secrets read from
+// <NAME>_FILE replace the variables of the same name. Malformed credential entries
+// are an error rather than being skipped, so a typo cannot silently drop a key.
+func loadFromEnv(cfg *Config, secrets map[string]string) error {
+	secret := func(name string) string {
+		if value, ok := secrets[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+
 	// Server configuration
 	if host := os.Getenv("SERVER_HOST"); host != "" {
 		cfg.Server.Host = host
@@ -129,6 +373,9 @@ func loadFromEnv(cfg *Config) {
 	if port := os.Getenv("SERVER_PORT"); port != "" {
 		cfg.Server.Port = port
 	}
+	if cfg.Server.Port == "" {
+		cfg.Server.Port = "8080"
+	}
 
 	// Digiflazz configuration
 	if baseURL := os.Getenv("DIGIFLAZZ_BASE_URL"); baseURL != "" {
@@ -137,12 +384,18 @@ func loadFromEnv(cfg *Config) {
 	if username := os.Getenv("DIGIFLAZZ_USERNAME"); username != "" {
 		cfg.Digiflazz.Username = username
 	}
-	if apiKey := os.Getenv("DIGIFLAZZ_API_KEY"); apiKey != "" {
+	if apiKey := secret("DIGIFLAZZ_API_KEY"); apiKey != "" {
 		cfg.Digiflazz.APIKey = apiKey
 	}
 	if ipWhitelist := os.Getenv("DIGIFLAZZ_IP_WHITELIST"); ipWhitelist != "" {
 		cfg.Digiflazz.IPWhitelist = ipWhitelist
 	}
+	if failover := os.Getenv("DIGIFLAZZ_FAILOVER_ACCOUNT"); failover != "" {
+		cfg.Digiflazz.Failover = failover
+	}
+	if bodyLogLevel := os.Getenv("DIGIFLAZZ_BODY_LOG_LEVEL"); bodyLogLevel != "" {
+		cfg.Digiflazz.BodyLogLevel = bodyLogLevel
+	}
 	
 	// Timeout configuration
 	if timeoutStr := os.Getenv("DIGIFLAZZ_TIMEOUT"); timeoutStr != "" {
@@ -158,7 +411,10 @@ func loadFromEnv(cfg *Config) {
 		}
 	}
 	
-	// Set default timeout and retry attempts if not configured
+	// Set default base URL, timeout and retry attempts if not configured
+	if cfg.Digiflazz.BaseURL == "" {
+		cfg.Digiflazz.BaseURL = "https://api.digiflazz.com"
+	}
 	if cfg.Digiflazz.Timeout == 0 {
 		cfg.Digiflazz.Timeout = 30 * time.Second
 	}
@@ -181,7 +437,7 @@ func loadFromEnv(cfg *Config) {
 	if user := os.Getenv("DB_USER"); user != "" {
 		cfg.Database.User = user
 	}
-	if password := os.Getenv("DB_PASSWORD"); password != "" {
+	if password := secret("DB_PASSWORD"); password != "" {
 		cfg.Database.Password = password
 	}
 
@@ -194,9 +450,59 @@ func loadFromEnv(cfg *Config) {
 			cfg.Redis.Port = p
 		}
 	}
-	if password := os.Getenv("REDIS_PASSWORD"); password != "" {
+	if password := secret("REDIS_PASSWORD"); password != "" {
 		cfg.Redis.Password = password
 	}
+	if db := os.Getenv("REDIS_DB"); db != "" {
+		if d, err := strconv.Atoi(db); err == nil {
+			cfg.Redis.DB = d
+		}
+	}
+
+	// Cache configuration
+	if cacheType := os.Getenv("CACHE_TYPE"); cacheType != "" {
+		cfg.Cache.Type = cacheType
+	}
+	if path := os.Getenv("CACHE_DB_PATH"); path != "" {
+		cfg.Cache.SQLitePath = path
+	}
+	if prefix := os.Getenv("CACHE_KEY_PREFIX"); prefix != "" {
+		cfg.Cache.KeyPrefix = prefix
+	}
+	if maxConns := os.Getenv("CACHE_SQLITE_MAX_OPEN_CONNS"); maxConns != "" {
+		if n, err := strconv.Atoi(maxConns); err == nil {
+			cfg.Cache.SQLiteMaxOpenConns = n
+		}
+	}
+	if busyTimeout := os.Getenv("CACHE_SQLITE_BUSY_TIMEOUT"); busyTimeout != "" {
+		if d, err := time.ParseDuration(busyTimeout); err == nil {
+			cfg.Cache.SQLiteBusyTimeout = d
+		}
+	}
+	if memoryTier := os.Getenv("CACHE_MEMORY_TIER"); memoryTier != "" {
+		cfg.Cache.MemoryTier = memoryTier == "true"
+	}
+	if maxEntries := os.Getenv("CACHE_MEMORY_MAX_ENTRIES"); maxEntries != "" {
+		if n, err := strconv.Atoi(maxEntries); err == nil {
+			cfg.Cache.MemoryMaxEntries = n
+		}
+	}
+	if maxBytes := os.Getenv("CACHE_MEMORY_MAX_BYTES"); maxBytes != "" {
+		if n, err := strconv.ParseInt(maxBytes, 10, 64); err == nil {
+			cfg.Cache.MemoryMaxBytes = n
+		}
+	}
+	if memoryTTL := os.Getenv("CACHE_MEMORY_TTL"); memoryTTL != "" {
+		if d, err := time.ParseDuration(memoryTTL); err == nil {
+			cfg.Cache.MemoryTTL = d
+		}
+	}
+	if cfg.Cache.Type == "" {
+		cfg.Cache.Type = "sqlite"
+	}
+	if cfg.Cache.KeyPrefix == "" {
+		cfg.Cache.KeyPrefix = "gateway:"
+	}
 
 	// Logging configuration
 	if level := os.Getenv("LOG_LEVEL"); level != "" {
@@ -204,14 +510,126 @@ func loadFromEnv(cfg *Config) {
 	}
 
 	// Security configuration
-	if jwtSecret := os.Getenv("JWT_SECRET"); jwtSecret != "" {
+	if jwtSecret := secret("JWT_SECRET"); jwtSecret != "" {
 		cfg.Security.JWTSecret = jwtSecret
 	}
+	// API_KEYS holds "name:sha256:scope+scope[:role]" entries separated by commas
+	if apiKeys := secret("API_KEYS"); apiKeys != "" {
+		for i, entry := range splitList(apiKeys) {
+			parts := strings.SplitN(entry, ":", 4)
+			if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+				// The entry itself holds a key hash and is not echoed
+				return fmt.Errorf("API_KEYS entry %d is malformed (expected name:sha256:scope+scope[:role])", i+1)
+			}
+			key := APIKeyConfig{
+				Name:    parts[0],
+				KeyHash: parts[1],
+				Scopes:  strings.Split(parts[2], "+"),
+			}
+			if len(parts) == 4 {
+				key.Role = parts[3]
+			}
+			cfg.Security.APIKeys = append(cfg.Security.APIKeys, key)
+		}
+	}
+	// API_KEY is a single plaintext key with every scope; it is hashed immediately.
+	// The placeholder written by older generated .env files is ignored.
+	if apiKey := secret("API_KEY"); apiKey != "" && apiKey != "your-api-key" {
+		sum := sha256.Sum256([]byte(apiKey))
+		cfg.Security.APIKeys = append(cfg.Security.APIKeys, APIKeyConfig{
+			Name:    "default",
+			KeyHash: hex.EncodeToString(sum[:]),
+			Scopes:  []string{"transact", "inquiry", "admin"},
+		})
+	}
 	if rateLimit := os.Getenv("API_RATE_LIMIT"); rateLimit != "" {
 		if rl, err := strconv.Atoi(rateLimit); err == nil {
 			cfg.Security.APIRateLimit = rl
 		}
 	}
+	// api_rate_limit is the legacy per-minute budget used when rate_limit is not configured
+	if cfg.Security.RateLimit.Requests == 0 {
+		cfg.Security.RateLimit.Requests = cfg.Security.APIRateLimit
+	}
+	if store := os.Getenv("RATE_LIMIT_STORE"); store != "" {
+		cfg.Security.RateLimit.Store = store
+	}
+	if requests := os.Getenv("RATE_LIMIT_REQUESTS"); requests != "" {
+		if n, err := strconv.Atoi(requests); err == nil {
+			cfg.Security.RateLimit.Requests = n
+		}
+	}
+	if window := os.Getenv("RATE_LIMIT_WINDOW"); window != "" {
+		if d, err := time.ParseDuration(window); err == nil {
+			cfg.Security.RateLimit.Window = d
+		}
+	}
+	if burst := os.Getenv("RATE_LIMIT_BURST"); burst != "" {
+		if n, err := strconv.Atoi(burst); err == nil {
+			cfg.Security.RateLimit.Burst = n
+		}
+	}
+	// RATE_LIMIT_TRANSACT, RATE_LIMIT_INQUIRY and RATE_LIMIT_ADMIN hold "requests" or "requests/window"
+	for _, group := range []string{"transact", "inquiry", "admin"} {
+		value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(group))
+		if value == "" {
+			continue
+		}
+		rule, ok := parseRateLimitRule(value)
+		if !ok {
+			continue
+		}
+		if cfg.Security.RateLimit.Groups == nil {
+			cfg.Security.RateLimit.Groups = make(map[string]RateLimitRuleConfig)
+		}
+		cfg.Security.RateLimit.Groups[group] = rule
+	}
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		cfg.Security.TrustedProxies = splitList(proxies)
+	}
+	// IP_ALLOWLIST_OTOMAX and IP_ALLOWLIST_ADMIN hold comma separated CIDRs or addresses
+	for _, group := range []string{"otomax", "admin"} {
+		if allowlist := os.Getenv("IP_ALLOWLIST_" + strings.ToUpper(group)); allowlist != "" {
+			if cfg.Security.IPAllowlists == nil {
+				cfg.Security.IPAllowlists = make(map[string][]string)
+			}
+			cfg.Security.IPAllowlists[group] = splitList(allowlist)
+		}
+	}
+	if auditLogPath := os.Getenv("AUDIT_LOG_PATH"); auditLogPath != "" {
+		cfg.Security.AuditLogPath = auditLogPath
+	}
+
+	// Otomax configuration
+	if secretKey := secret("OTOMAX_SECRET_KEY"); secretKey != "" {
+		cfg.Otomax.SecretKey = secretKey
+	}
+	if skew := os.Getenv("OTOMAX_MAX_CLOCK_SKEW"); skew != "" {
+		if d, err := time.ParseDuration(skew); err == nil {
+			cfg.Otomax.MaxClockSkew = d
+		}
+	}
+	if responseSigning := os.Getenv("OTOMAX_RESPONSE_SIGNING"); responseSigning != "" {
+		cfg.Otomax.ResponseSigning = responseSigning
+	}
+	// OTOMAX_RESELLERS holds "id[@client]:algorithm:secret" entries separated by commas;
+	// client binds the reseller to an API key name
+	if resellers := secret("OTOMAX_RESELLERS"); resellers != "" {
+		for i, entry := range splitList(resellers) {
+			parts := strings.SplitN(entry, ":", 3)
+			id, client, _ := strings.Cut(parts[0], "@")
+			if len(parts) != 3 || id == "" {
+				// The entry itself holds the reseller secret and is not echoed
+				return fmt.Errorf("OTOMAX_RESELLERS entry %d is malformed (expected id[@client]:algorithm:secret)", i+1)
+			}
+			cfg.Otomax.Resellers = append(cfg.Otomax.Resellers, OtomaxResellerConfig{
+				ID:             id,
+				Client:         client,
+				RequestSigning: parts[1],
+				Secret:         parts[2],
+			})
+		}
+	}
 
 	// Monitoring configuration
 	if enableMetrics := os.Getenv("ENABLE_METRICS"); enableMetrics != "" {
@@ -222,4 +640,35 @@ func loadFromEnv(cfg *Config) {
 			cfg.Monitoring.MetricsPort = mp
 		}
 	}
+
+	return nil
+}
+
+// parseRateLimitRule parses "requests" or "requests/window", e.g. "30/1m"
+func parseRateLimitRule(value string) (RateLimitRuleConfig, bool) {
+	parts := strings.SplitN(strings.TrimSpace(value), "/", 2)
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests <= 0 {
+		return RateLimitRuleConfig{}, false
+	}
+	rule := RateLimitRuleConfig{Requests: requests}
+	if len(parts) == 2 {
+		window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || window <= 0 {
+			return RateLimitRuleConfig{}, false
+		}
+		rule.Window = window
+	}
+	return rule, true
+}
+
+// splitList splits a comma separated value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gateway-digiflazz/internal/middleware"
+	"gateway-digiflazz/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuthHandler handles API key administration requests
+type AuthHandler struct {
+	apiKeys  *middleware.APIKeyStore
+	auditLog repositories.AuditLogRepository
+	logger   *logrus.Logger
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(apiKeys *middleware.APIKeyStore, logger *logrus.Logger) *AuthHandler {
+	return &AuthHandler{
+		apiKeys: apiKeys,
+		logger:  logger,
+	}
+}
+
+// ListAPIKeys lists the configured API keys with their scopes and last use
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	middleware.JSON(c, http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.apiKeys.Usage(),
+	})
+}
+
+// SetAuditLog sets the audit log served by ListAuditLog
+func (h *AuthHandler) SetAuditLog(auditLog repositories.AuditLogRepository) {
+	h.auditLog = auditLog
+}
+
+// maxAuditLimit bounds the entries returned by one ListAuditLog request
+const maxAuditLimit = 1000
+
+// ListAuditLog lists the latest audited management actions, newest first
+func (h *AuthHandler) ListAuditLog(c *gin.Context) {
+	if h.auditLog == nil {
+		middleware.ErrorResponse(c, http.StatusServiceUnavailable, "AUDIT_LOG_UNAVAILABLE", "Audit log is not configured", "")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid limit", "limit must be a positive number")
+		return
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	entries, err := h.auditLog.Recent(limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to read audit log")
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "AUDIT_LOG_ERROR", "Failed to read audit log", err.Error())
+		return
+	}
+
+	middleware.JSON(c, http.StatusOK, gin.H{
+		"success": true,
+		"data":    entries,
+	})
+}
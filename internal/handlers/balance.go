@@ -3,6 +3,8 @@ package handlers
 import (
 	"net/http"
 
+	"gateway-digiflazz/internal/middleware"
+	"gateway-digiflazz/internal/models"
 	"gateway-digiflazz/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -25,11 +27,17 @@ func NewBalanceHandler(balanceService *services.BalanceService, logger *logrus.L
 
 // GetBalance handles balance check requests
 func (h *BalanceHandler) GetBalance(c *gin.Context) {
-	// Get balance
-	resp, err := h.balanceService.GetBalance()
+	// Get balance, optionally for a specific account
+	var resp *models.BalanceResponse
+	var err error
+	if account := c.Query("account"); account != "" {
+		resp, err = h.balanceService.GetAccountBalance(c.Request.Context(), account)
+	} else {
+		resp, err = h.balanceService.GetBalance(c.Request.Context())
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("Balance retrieval failed")
-		c.JSON(http.StatusInternalServerError, gin.H{
+		middleware.JSON(c, http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error": gin.H{
 				"code":    "BALANCE_FAILED",
@@ -41,8 +49,18 @@ func (h *BalanceHandler) GetBalance(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    resp,
 	})
 }
+
+// GetAccountBalances handles balance requests for every Digiflazz account
+func (h *BalanceHandler) GetAccountBalances(c *gin.Context) {
+	balances := h.balanceService.GetAccountBalances(c.Request.Context())
+
+	middleware.JSON(c, http.StatusOK, gin.H{
+		"success": true,
+		"data":    balances,
+	})
+}
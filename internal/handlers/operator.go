@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gateway-digiflazz/internal/middleware"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/pkg/operator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// OperatorHandler handles operator detection HTTP requests
+type OperatorHandler struct {
+	productResolver *operator.Resolver
+	logger          *logrus.Logger
+}
+
+// NewOperatorHandler creates a new operator handler
+func NewOperatorHandler(productResolver *operator.Resolver, logger *logrus.Logger) *OperatorHandler {
+	return &OperatorHandler{
+		productResolver: productResolver,
+		logger:          logger,
+	}
+}
+
+// ListOperators handles requests for the supported operators and their prefixes
+func (h *OperatorHandler) ListOperators(c *gin.Context) {
+	middleware.JSON(c, http.StatusOK, gin.H{
+		"success": true,
+		"data":    operator.Operators,
+	})
+}
+
+// Detect handles operator detection requests, optionally resolving a generic product code
+func (h *OperatorHandler) Detect(c *gin.Context) {
+	customerNo := c.Query("customer_no")
+	if customerNo == "" {
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
+			Code:    "MISSING_CUSTOMER_NO",
+			Message: "customer_no parameter is required",
+		})
+		return
+	}
+
+	op, normalized, err := operator.Detect(customerNo)
+	if err != nil {
+		h.logger.WithError(err).WithField("customer_no", customerNo).Warn("Operator detection failed")
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
+			Code:    "INVALID_CUSTOMER_NO",
+			Message: "Customer number does not match a supported operator",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{
+		"customer_no": normalized,
+		"operator":    op,
+	}
+
+	// Resolve generic product code when given
+	if productCode := c.Query("product_code"); productCode != "" {
+		resolution, err := h.productResolver.Resolve(productCode, customerNo)
+		if err != nil {
+			middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
+				Code:    "PRODUCT_NOT_RESOLVED",
+				Message: "Failed to resolve product code",
+				Details: err.Error(),
+			})
+			return
+		}
+		data["buyer_sku"] = resolution.BuyerSKU
+		data["category"] = resolution.Category
+	}
+
+	middleware.JSON(c, http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
 	"gateway-digiflazz/internal/middleware"
 	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
 	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/internal/validation"
+	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/operator"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -52,11 +56,43 @@ func (h *OtomaxHandler) ProcessTransaction(c *gin.Context) {
 			"")
 		return
 	}
+	req.ClientID = middleware.ClientName(c)
+
+	if !h.verifySignature(c, models.OtomaxRequestSignature{
+		ResellerID: req.ResellerID,
+		ClientID:   req.ClientID,
+		RefID:      req.RefID,
+		CustomerNo: req.CustomerNo,
+		BuyerSKU:   req.BuyerSKU,
+		Timestamp:  req.Timestamp,
+		Nonce:      req.Nonce,
+		Sign:       req.Sign,
+	}) {
+		return
+	}
 
 	// Process transaction
-	resp, err := h.otomaxService.ProcessTransaction(req)
+	resp, err := h.otomaxService.ProcessTransaction(c.Request.Context(), req)
 	if err != nil {
 		h.logger.WithError(err).Error("Otomax transaction processing failed")
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		if operator.IsNumberError(err) {
+			middleware.ErrorResponse(c, http.StatusBadRequest, 
+				"INVALID_CUSTOMER_NO", 
+				"Customer number does not match a supported operator", 
+				err.Error())
+			return
+		}
+		if operator.IsProductError(err) {
+			middleware.ErrorResponse(c, http.StatusBadRequest,
+				"PRODUCT_NOT_AVAILABLE",
+				"Product is not available for this customer number",
+				err.Error())
+			return
+		}
 		middleware.ErrorResponse(c, http.StatusInternalServerError, 
 			"TRANSACTION_FAILED", 
 			"Failed to process transaction", 
@@ -75,7 +111,7 @@ func (h *OtomaxHandler) CheckStatus(c *gin.Context) {
 	// Bind query parameters to struct
 	if err := c.ShouldBindQuery(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind Otomax status request")
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request parameters",
 			Details: err.Error(),
@@ -85,18 +121,29 @@ func (h *OtomaxHandler) CheckStatus(c *gin.Context) {
 
 	// Validate required fields
 	if req.RefID == "" {
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "MISSING_PARAMETERS",
 			Message: "Missing required parameter: ref_id",
 		})
 		return
 	}
 
+	req.ClientID = middleware.ClientName(c)
+
 	// Check status
-	resp, err := h.otomaxService.CheckStatus(req)
+	resp, err := h.otomaxService.CheckStatus(c.Request.Context(), req)
+	if errors.Is(err, repositories.ErrTransactionNotFound) {
+		// Unknown transactions are answered unsigned so they cannot pass as a gateway status
+		middleware.JSON(c, http.StatusNotFound, &models.OtomaxError{
+			Code:    "TRANSACTION_NOT_FOUND",
+			Message: "Transaction not found",
+			Details: "no transaction with ref_id " + req.RefID + " is known to the gateway",
+		})
+		return
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("Otomax status check failed")
-		c.JSON(http.StatusInternalServerError, models.OtomaxError{
+		middleware.JSON(c, http.StatusInternalServerError, &models.OtomaxError{
 			Code:    "STATUS_CHECK_FAILED",
 			Message: "Failed to check transaction status",
 			Details: err.Error(),
@@ -105,7 +152,7 @@ func (h *OtomaxHandler) CheckStatus(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, resp)
+	middleware.JSON(c, http.StatusOK, resp)
 }
 
 // ProcessCallback handles callback requests from Digiflazz for Otomax transactions
@@ -115,7 +162,7 @@ func (h *OtomaxHandler) ProcessCallback(c *gin.Context) {
 	// Bind JSON body to struct
 	if err := c.ShouldBindJSON(&callback); err != nil {
 		h.logger.WithError(err).Error("Failed to bind Otomax callback")
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "INVALID_CALLBACK",
 			Message: "Invalid callback format",
 			Details: err.Error(),
@@ -124,9 +171,9 @@ func (h *OtomaxHandler) ProcessCallback(c *gin.Context) {
 	}
 
 	// Process callback
-	if err := h.otomaxService.ProcessCallback(callback); err != nil {
+	if err := h.otomaxService.ProcessCallback(c.Request.Context(), callback); err != nil {
 		h.logger.WithError(err).Error("Otomax callback processing failed")
-		c.JSON(http.StatusInternalServerError, models.OtomaxError{
+		middleware.JSON(c, http.StatusInternalServerError, &models.OtomaxError{
 			Code:    "CALLBACK_FAILED",
 			Message: "Failed to process callback",
 			Details: err.Error(),
@@ -135,7 +182,7 @@ func (h *OtomaxHandler) ProcessCallback(c *gin.Context) {
 	}
 
 	// Return success response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "Callback processed successfully",
 	})
@@ -144,7 +191,7 @@ func (h *OtomaxHandler) ProcessCallback(c *gin.Context) {
 // GetTransactionHistory handles transaction history requests
 func (h *OtomaxHandler) GetTransactionHistory(c *gin.Context) {
 	// TODO: Implement transaction history retrieval
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "Transaction history endpoint - to be implemented",
 		"data":    []interface{}{},
@@ -154,7 +201,7 @@ func (h *OtomaxHandler) GetTransactionHistory(c *gin.Context) {
 // GetProductList handles product list requests
 func (h *OtomaxHandler) GetProductList(c *gin.Context) {
 	// TODO: Implement product list retrieval
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "Product list endpoint - to be implemented",
 		"data":    []interface{}{},
@@ -168,7 +215,7 @@ func (h *OtomaxHandler) CheckPascabayarBill(c *gin.Context) {
 	// Bind query parameters to struct
 	if err := c.ShouldBindQuery(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind Otomax Pascabayar check request")
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request parameters",
 			Details: err.Error(),
@@ -178,39 +225,22 @@ func (h *OtomaxHandler) CheckPascabayarBill(c *gin.Context) {
 
 	// Validate required fields
 	if req.RefID == "" || req.CustomerNo == "" || req.BuyerSKU == "" {
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "MISSING_PARAMETERS",
 			Message: "Missing required parameters: ref_id, customer_no, buyer_sku",
 		})
 		return
 	}
 
-	// TODO: Implement Pascabayar bill check logic
-	// For now, return a mock response
-	resp := models.OtomaxPascabayarCheckResponse{
-		RefID:      req.RefID,
-		CustomerNo: req.CustomerNo,
-		BuyerSKU:   req.BuyerSKU,
-		Amount:     50000,
-		AdminFee:   2500,
-		Total:      52500,
-		Status:     "success",
-		Message:    "Bill check successful",
-		RC:         "00",
-		BillDetails: models.BillDetails{
-			CustomerName: "John Doe",
-			BillPeriod:   "2023-12",
-			DueDate:      "2023-12-31",
-			BillAmount:   50000,
-			AdminFee:     2500,
-			TotalAmount:  52500,
-		},
-		Timestamp: time.Now().Format(time.RFC3339),
-		Sign:      h.generateResponseSignature(req.RefID, "success"),
+	// Validate customer number for the product
+	customerNo, err := h.otomaxService.ValidateCustomerNo(req.BuyerSKU, "", req.CustomerNo)
+	if err != nil {
+		h.customerNoErrorResponse(c, err)
+		return
 	}
+	req.CustomerNo = customerNo
 
-	// Return response
-	c.JSON(http.StatusOK, resp)
+	h.pascabayarNotImplemented(c)
 }
 
 // PayPascabayarBill handles Pascabayar bill payment requests from Otomax
@@ -220,7 +250,7 @@ func (h *OtomaxHandler) PayPascabayarBill(c *gin.Context) {
 	// Bind query parameters to struct
 	if err := c.ShouldBindQuery(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind Otomax Pascabayar pay request")
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request parameters",
 			Details: err.Error(),
@@ -230,40 +260,46 @@ func (h *OtomaxHandler) PayPascabayarBill(c *gin.Context) {
 
 	// Validate required fields
 	if req.RefID == "" || req.CustomerNo == "" || req.BuyerSKU == "" {
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "MISSING_PARAMETERS",
 			Message: "Missing required parameters: ref_id, customer_no, buyer_sku",
 		})
 		return
 	}
 
-	// TODO: Implement Pascabayar bill payment logic
-	// For now, return a mock response
-	resp := models.OtomaxPascabayarPayResponse{
+	if !h.verifySignature(c, models.OtomaxRequestSignature{
+		ResellerID: req.ResellerID,
+		ClientID:   middleware.ClientName(c),
 		RefID:      req.RefID,
 		CustomerNo: req.CustomerNo,
 		BuyerSKU:   req.BuyerSKU,
-		Amount:     req.Amount,
-		AdminFee:   2500,
-		Total:      req.Amount + 2500,
-		Status:     "success",
-		Message:    "Bill payment successful",
-		RC:         "00",
-		SN:         "SN123456789",
-		BillDetails: models.BillDetails{
-			CustomerName: "John Doe",
-			BillPeriod:   "2023-12",
-			DueDate:      "2023-12-31",
-			BillAmount:   req.Amount,
-			AdminFee:     2500,
-			TotalAmount:  req.Amount + 2500,
-		},
-		Timestamp: time.Now().Format(time.RFC3339),
-		Sign:      h.generateResponseSignature(req.RefID, "success"),
+		Timestamp:  req.Timestamp,
+		Nonce:      req.Nonce,
+		Sign:       req.Sign,
+	}) {
+		return
 	}
 
-	// Return response
-	c.JSON(http.StatusOK, resp)
+	// Validate customer number for the product
+	customerNo, err := h.otomaxService.ValidateCustomerNo(req.BuyerSKU, "", req.CustomerNo)
+	if err != nil {
+		h.customerNoErrorResponse(c, err)
+		return
+	}
+	req.CustomerNo = customerNo
+
+	h.pascabayarNotImplemented(c)
+}
+
+// pascabayarNotImplemented answers the standalone Otomax bill check and payment, which
+// are not wired to Digiflazz yet. The answer is unsigned so it cannot pass as a bill
+// the gateway checked or paid.
+func (h *OtomaxHandler) pascabayarNotImplemented(c *gin.Context) {
+	middleware.JSON(c, http.StatusNotImplemented, &models.OtomaxError{
+		Code:    "NOT_IMPLEMENTED",
+		Message: "Pascabayar bills are not available on this endpoint",
+		Details: "use /otomax/transaction with type=pascabayar",
+	})
 }
 
 // InquiryPLN handles PLN inquiry requests from Otomax
@@ -273,7 +309,7 @@ func (h *OtomaxHandler) InquiryPLN(c *gin.Context) {
 	// Bind query parameters to struct
 	if err := c.ShouldBindQuery(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind PLN inquiry request")
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request parameters",
 			Details: err.Error(),
@@ -283,22 +319,13 @@ func (h *OtomaxHandler) InquiryPLN(c *gin.Context) {
 
 	// Validate required fields
 	if req.RefID == "" || req.CustomerNo == "" {
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "MISSING_PARAMETERS",
 			Message: "Missing required parameters: ref_id, customer_no",
 		})
 		return
 	}
 
-	// Validate customer number format (PLN customer numbers are typically 11-12 digits)
-	if len(req.CustomerNo) < 10 || len(req.CustomerNo) > 15 {
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
-			Code:    "INVALID_CUSTOMER_NO",
-			Message: "Invalid customer number format. PLN customer numbers should be 10-15 digits",
-		})
-		return
-	}
-
 	// Log request details for debugging
 	h.logger.WithFields(logrus.Fields{
 		"ref_id":      req.RefID,
@@ -310,7 +337,7 @@ func (h *OtomaxHandler) InquiryPLN(c *gin.Context) {
 		CustomerNo: req.CustomerNo,
 	}
 	
-	resp, err := h.plnInquiryService.InquiryPLN(plnReq, req.RefID)
+	resp, err := h.plnInquiryService.InquiryPLN(c.Request.Context(), plnReq, req.RefID)
 	if err != nil {
 		h.logger.WithError(err).WithFields(logrus.Fields{
 			"ref_id":      req.RefID,
@@ -318,7 +345,9 @@ func (h *OtomaxHandler) InquiryPLN(c *gin.Context) {
 		}).Error("PLN inquiry failed")
 		
 		// Check for specific error types
-		if strings.Contains(err.Error(), "customer may not exist") {
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+		} else if isCustomerNotFound(err) {
 			middleware.ErrorResponse(c, http.StatusNotFound, 
 				"CUSTOMER_NOT_FOUND", 
 				"Customer number not found in PLN system", 
@@ -351,11 +380,66 @@ func (h *OtomaxHandler) InquiryPLN(c *gin.Context) {
 	middleware.SuccessResponse(c, resp, "PLN inquiry completed successfully")
 }
 
+// PurchasePLNToken handles PLN prepaid token purchase requests from Otomax
+func (h *OtomaxHandler) PurchasePLNToken(c *gin.Context) {
+	var req models.OtomaxPLNTokenRequest
+
+	// Bind query parameters to struct
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind PLN token request")
+		middleware.ErrorResponse(c, http.StatusBadRequest,
+			"INVALID_REQUEST",
+			"Invalid request parameters",
+			err.Error())
+		return
+	}
+
+	req.ClientID = middleware.ClientName(c)
+
+	if !h.verifySignature(c, models.OtomaxRequestSignature{
+		ResellerID: req.ResellerID,
+		ClientID:   req.ClientID,
+		RefID:      req.RefID,
+		CustomerNo: req.CustomerNo,
+		BuyerSKU:   req.BuyerSKU,
+		Timestamp:  req.Timestamp,
+		Nonce:      req.Nonce,
+		Sign:       req.Sign,
+	}) {
+		return
+	}
+
+	resp, err := h.otomaxService.PurchasePLNToken(c.Request.Context(), req)
+	if err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"ref_id":      req.RefID,
+			"customer_no": req.CustomerNo,
+		}).Error("PLN token purchase failed")
+
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+		} else if isCustomerNotFound(err) {
+			middleware.ErrorResponse(c, http.StatusNotFound,
+				"CUSTOMER_NOT_FOUND",
+				"Customer number not found in PLN system",
+				fmt.Sprintf("Customer number %s does not exist or is invalid", req.CustomerNo))
+		} else {
+			middleware.ErrorResponse(c, http.StatusInternalServerError,
+				"PLN_TOKEN_FAILED",
+				"Failed to purchase PLN token",
+				err.Error())
+		}
+		return
+	}
+
+	middleware.SuccessResponse(c, resp, "PLN token purchase processed successfully")
+}
+
 // GetPLNStats handles PLN inquiry statistics requests from Otomax
 func (h *OtomaxHandler) GetPLNStats(c *gin.Context) {
 	stats := h.plnInquiryService.GetStats()
 
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "PLN inquiry statistics",
 		"data":    stats,
@@ -366,7 +450,7 @@ func (h *OtomaxHandler) GetPLNStats(c *gin.Context) {
 func (h *OtomaxHandler) ClearPLNCache(c *gin.Context) {
 	customerNo := c.Param("customer_no")
 	if customerNo == "" {
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "MISSING_PARAMETERS",
 			Message: "Missing required parameter: customer_no",
 		})
@@ -376,7 +460,7 @@ func (h *OtomaxHandler) ClearPLNCache(c *gin.Context) {
 	err := h.plnInquiryService.ClearCache(customerNo)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to clear PLN cache")
-		c.JSON(http.StatusInternalServerError, models.OtomaxError{
+		middleware.JSON(c, http.StatusInternalServerError, &models.OtomaxError{
 			Code:    "CACHE_CLEAR_FAILED",
 			Message: "Failed to clear PLN cache",
 			Details: err.Error(),
@@ -386,7 +470,7 @@ func (h *OtomaxHandler) ClearPLNCache(c *gin.Context) {
 
 	h.logger.WithField("customer_no", customerNo).Info("PLN cache cleared for customer")
 
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "PLN cache cleared for customer: " + customerNo,
 	})
@@ -397,7 +481,7 @@ func (h *OtomaxHandler) ClearAllPLNCache(c *gin.Context) {
 	err := h.plnInquiryService.ClearAllCache()
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to clear all PLN cache")
-		c.JSON(http.StatusInternalServerError, models.OtomaxError{
+		middleware.JSON(c, http.StatusInternalServerError, &models.OtomaxError{
 			Code:    "CACHE_CLEAR_ALL_FAILED",
 			Message: "Failed to clear all PLN cache",
 			Details: err.Error(),
@@ -407,20 +491,22 @@ func (h *OtomaxHandler) ClearAllPLNCache(c *gin.Context) {
 
 	h.logger.Info("All PLN cache cleared")
 
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "All PLN cache cleared",
 	})
 }
 
-// UpdatePLNCacheConfig handles PLN cache configuration updates from Otomax
+// UpdatePLNCacheConfig handles PLN cache configuration updates from Otomax.
+// Fields omitted from the body keep their current values.
 func (h *OtomaxHandler) UpdatePLNCacheConfig(c *gin.Context) {
-	var config models.PLNInquiryConfig
-	
-	// Bind JSON body to struct
+	previous := h.plnInquiryService.GetCacheConfig()
+	config := previous
+
+	// Bind JSON body onto the active configuration
 	if err := c.ShouldBindJSON(&config); err != nil {
 		h.logger.WithError(err).Error("Failed to bind PLN cache config")
-		c.JSON(http.StatusBadRequest, models.OtomaxError{
+		middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid configuration format",
 			Details: err.Error(),
@@ -428,21 +514,92 @@ func (h *OtomaxHandler) UpdatePLNCacheConfig(c *gin.Context) {
 		return
 	}
 
-	// TODO: Implement PLN cache configuration update logic
-	h.logger.WithFields(map[string]interface{}{
-		"cache_enabled": config.CacheEnabled,
-		"cache_ttl":     config.CacheTTL,
-	}).Info("Updating PLN cache configuration")
+	if err := h.plnInquiryService.SetCacheConfig(config); err != nil {
+		h.logger.WithError(err).Error("Failed to update PLN cache config")
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.OtomaxError{
+			Code:    "CACHE_CONFIG_FAILED",
+			Message: "Failed to update PLN cache configuration",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	updated := h.plnInquiryService.GetCacheConfig()
+	middleware.SetAuditDetails(c, map[string]interface{}{"previous": previous, "config": updated})
 
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "PLN cache configuration updated",
-		"data":    config,
+		"data":    updated,
 	})
 }
 
-// generateResponseSignature generates signature for response
-func (h *OtomaxHandler) generateResponseSignature(refID, status string) string {
-	// TODO: Implement proper signature generation
-	return "mock_signature_" + refID + "_" + status
+// CheckSignature lets resellers test their request signing or response verification
+// against the gateway's computation. The expected signature is never returned.
+func (h *OtomaxHandler) CheckSignature(c *gin.Context) {
+	var req models.OtomaxSignatureCheckRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest,
+			"INVALID_REQUEST",
+			"Invalid request parameters",
+			err.Error())
+		return
+	}
+	if req.Type == "" {
+		req.Type = "response"
+	}
+	if req.Type != "request" && req.Type != "response" {
+		middleware.ErrorResponse(c, http.StatusBadRequest,
+			"INVALID_REQUEST",
+			"Invalid signature type",
+			"type must be request or response")
+		return
+	}
+
+	check := h.otomaxService.CheckSignature(req, middleware.ClientName(c))
+	middleware.SuccessResponse(c, check, "Signature checked")
+}
+
+// verifySignature verifies the request signature of resellers that opted in to request
+// signing and writes the rejection; it reports whether the request may proceed
+func (h *OtomaxHandler) verifySignature(c *gin.Context, req models.OtomaxRequestSignature) bool {
+	err := h.otomaxService.VerifyRequestSignature(c.Request.Context(), req)
+	if err == nil {
+		return true
+	}
+	if sigErr, ok := services.AsSignatureError(err); ok {
+		middleware.ErrorResponse(c, http.StatusUnauthorized,
+			sigErr.Code,
+			"Request signature rejected",
+			sigErr.Message)
+		return false
+	}
+	middleware.ErrorResponse(c, http.StatusInternalServerError,
+		"SIGNATURE_CHECK_FAILED",
+		"Failed to verify request signature",
+		err.Error())
+	return false
+}
+
+// isCustomerNotFound reports whether a PLN inquiry error means the customer number does not exist
+func isCustomerNotFound(err error) bool {
+	return digiflazz.IsCustomerNotFound(digiflazz.RCFromError(err)) ||
+		strings.Contains(err.Error(), "customer may not exist")
+}
+
+// customerNoErrorResponse answers a request whose customer number failed validation
+func (h *OtomaxHandler) customerNoErrorResponse(c *gin.Context, err error) {
+	if fieldErrors, ok := validation.AsErrors(err); ok {
+		middleware.ValidationErrorResponse(c, fieldErrors)
+		return
+	}
+	middleware.JSON(c, http.StatusBadRequest, &models.OtomaxError{
+		Code:    "INVALID_CUSTOMER_NO",
+		Message: "Invalid customer number",
+		Details: err.Error(),
+	})
 }
@@ -3,8 +3,10 @@ package handlers
 import (
 	"net/http"
 
+	"gateway-digiflazz/internal/middleware"
 	"gateway-digiflazz/internal/models"
 	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/internal/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -29,7 +31,7 @@ func (h *PascabayarHandler) CheckBill(c *gin.Context) {
 	var req models.PascabayarCheckRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind Pascabayar check request")
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request format",
 			Details: err.Error(),
@@ -38,10 +40,14 @@ func (h *PascabayarHandler) CheckBill(c *gin.Context) {
 	}
 
 	// Check bill
-	resp, err := h.pascabayarService.CheckBill(req)
+	resp, err := h.pascabayarService.CheckBill(c.Request.Context(), req)
 	if err != nil {
 		h.logger.WithError(err).Error("Pascabayar bill check failed")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.ErrorResponse{
 			Code:    "BILL_CHECK_FAILED",
 			Message: "Failed to check bill",
 			Details: err.Error(),
@@ -50,7 +56,7 @@ func (h *PascabayarHandler) CheckBill(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    resp,
 	})
@@ -61,7 +67,7 @@ func (h *PascabayarHandler) PayBill(c *gin.Context) {
 	var req models.PascabayarPayRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind Pascabayar pay request")
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request format",
 			Details: err.Error(),
@@ -70,10 +76,14 @@ func (h *PascabayarHandler) PayBill(c *gin.Context) {
 	}
 
 	// Pay bill
-	resp, err := h.pascabayarService.PayBill(req)
+	resp, err := h.pascabayarService.PayBill(c.Request.Context(), req)
 	if err != nil {
 		h.logger.WithError(err).Error("Pascabayar bill payment failed")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.ErrorResponse{
 			Code:    "BILL_PAYMENT_FAILED",
 			Message: "Failed to pay bill",
 			Details: err.Error(),
@@ -82,7 +92,7 @@ func (h *PascabayarHandler) PayBill(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    resp,
 	})
@@ -92,7 +102,7 @@ func (h *PascabayarHandler) PayBill(c *gin.Context) {
 func (h *PascabayarHandler) GetTransaction(c *gin.Context) {
 	refID := c.Param("ref_id")
 	if refID == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
 			Code:    "MISSING_REF_ID",
 			Message: "ref_id parameter is required",
 		})
@@ -103,7 +113,7 @@ func (h *PascabayarHandler) GetTransaction(c *gin.Context) {
 	tx, err := h.pascabayarService.GetPascabayarTransaction(refID)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get Pascabayar transaction")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		middleware.JSON(c, http.StatusInternalServerError, &models.ErrorResponse{
 			Code:    "TRANSACTION_NOT_FOUND",
 			Message: "Failed to get transaction",
 			Details: err.Error(),
@@ -112,7 +122,7 @@ func (h *PascabayarHandler) GetTransaction(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    tx,
 	})
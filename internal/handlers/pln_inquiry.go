@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"gateway-digiflazz/internal/middleware"
 	"gateway-digiflazz/internal/models"
 	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/internal/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -29,7 +37,7 @@ func (h *PLNInquiryHandler) InquiryPLN(c *gin.Context) {
 	var req models.PLNInquiryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind PLN inquiry request")
-		c.JSON(http.StatusBadRequest, models.PLNInquiryError{
+		middleware.JSON(c, http.StatusBadRequest, &models.PLNInquiryError{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request format",
 			Details: err.Error(),
@@ -39,7 +47,7 @@ func (h *PLNInquiryHandler) InquiryPLN(c *gin.Context) {
 
 	// Validate required fields
 	if req.CustomerNo == "" {
-		c.JSON(http.StatusBadRequest, models.PLNInquiryError{
+		middleware.JSON(c, http.StatusBadRequest, &models.PLNInquiryError{
 			Code:    "MISSING_CUSTOMER_NO",
 			Message: "customer_no is required",
 		})
@@ -50,10 +58,22 @@ func (h *PLNInquiryHandler) InquiryPLN(c *gin.Context) {
 	refID := req.RefID
 	
 	// Perform PLN inquiry
-	resp, err := h.plnInquiryService.InquiryPLN(req, refID)
+	resp, err := h.plnInquiryService.InquiryPLN(c.Request.Context(), req, refID)
 	if err != nil {
 		h.logger.WithError(err).Error("PLN inquiry failed")
-		c.JSON(http.StatusInternalServerError, models.PLNInquiryError{
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		if isCustomerNotFound(err) {
+			middleware.JSON(c, http.StatusNotFound, &models.PLNInquiryError{
+				Code:    "CUSTOMER_NOT_FOUND",
+				Message: "Customer number not found in PLN system",
+				Details: err.Error(),
+			})
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.PLNInquiryError{
 			Code:    "INQUIRY_FAILED",
 			Message: "Failed to perform PLN inquiry",
 			Details: err.Error(),
@@ -62,7 +82,7 @@ func (h *PLNInquiryHandler) InquiryPLN(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    resp,
 	})
@@ -72,7 +92,7 @@ func (h *PLNInquiryHandler) InquiryPLN(c *gin.Context) {
 func (h *PLNInquiryHandler) GetStats(c *gin.Context) {
 	stats := h.plnInquiryService.GetStats()
 	
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    stats,
 	})
@@ -82,7 +102,7 @@ func (h *PLNInquiryHandler) GetStats(c *gin.Context) {
 func (h *PLNInquiryHandler) ClearCache(c *gin.Context) {
 	customerNo := c.Param("customer_no")
 	if customerNo == "" {
-		c.JSON(http.StatusBadRequest, models.PLNInquiryError{
+		middleware.JSON(c, http.StatusBadRequest, &models.PLNInquiryError{
 			Code:    "MISSING_CUSTOMER_NO",
 			Message: "customer_no parameter is required",
 		})
@@ -92,7 +112,7 @@ func (h *PLNInquiryHandler) ClearCache(c *gin.Context) {
 	// Clear cache for specific customer
 	if err := h.plnInquiryService.ClearCache(customerNo); err != nil {
 		h.logger.WithError(err).Error("Failed to clear PLN inquiry cache")
-		c.JSON(http.StatusInternalServerError, models.PLNInquiryError{
+		middleware.JSON(c, http.StatusInternalServerError, &models.PLNInquiryError{
 			Code:    "CACHE_CLEAR_FAILED",
 			Message: "Failed to clear cache",
 			Details: err.Error(),
@@ -100,7 +120,7 @@ func (h *PLNInquiryHandler) ClearCache(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "Cache cleared successfully",
 	})
@@ -110,7 +130,7 @@ func (h *PLNInquiryHandler) ClearCache(c *gin.Context) {
 func (h *PLNInquiryHandler) ClearAllCache(c *gin.Context) {
 	if err := h.plnInquiryService.ClearAllCache(); err != nil {
 		h.logger.WithError(err).Error("Failed to clear all PLN inquiry cache")
-		c.JSON(http.StatusInternalServerError, models.PLNInquiryError{
+		middleware.JSON(c, http.StatusInternalServerError, &models.PLNInquiryError{
 			Code:    "CACHE_CLEAR_FAILED",
 			Message: "Failed to clear all cache",
 			Details: err.Error(),
@@ -118,18 +138,20 @@ func (h *PLNInquiryHandler) ClearAllCache(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "All cache cleared successfully",
 	})
 }
 
-// UpdateCacheConfig handles cache configuration updates
+// UpdateCacheConfig handles cache configuration updates.
+// Fields omitted from the body keep their current values.
 func (h *PLNInquiryHandler) UpdateCacheConfig(c *gin.Context) {
-	var config models.PLNInquiryConfig
+	previous := h.plnInquiryService.GetCacheConfig()
+	config := previous
 	if err := c.ShouldBindJSON(&config); err != nil {
 		h.logger.WithError(err).Error("Failed to bind cache config request")
-		c.JSON(http.StatusBadRequest, models.PLNInquiryError{
+		middleware.JSON(c, http.StatusBadRequest, &models.PLNInquiryError{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request format",
 			Details: err.Error(),
@@ -137,11 +159,249 @@ func (h *PLNInquiryHandler) UpdateCacheConfig(c *gin.Context) {
 		return
 	}
 
-	h.plnInquiryService.SetCacheConfig(config)
-	
-	c.JSON(http.StatusOK, gin.H{
+	if err := h.plnInquiryService.SetCacheConfig(config); err != nil {
+		h.logger.WithError(err).Error("Failed to update cache config")
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.PLNInquiryError{
+			Code:    "CACHE_CONFIG_FAILED",
+			Message: "Failed to update cache configuration",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	updated := h.plnInquiryService.GetCacheConfig()
+	middleware.SetAuditDetails(c, map[string]interface{}{"previous": previous, "config": updated})
+
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "Cache configuration updated successfully",
-		"data":    config,
+		"data":    updated,
+	})
+}
+
+const (
+	// maxWarmBatch bounds the customer numbers accepted by one warm-up request
+	maxWarmBatch = 1000
+	// maxImportBodyBytes bounds the size of an uploaded cache import
+	maxImportBodyBytes = 64 << 20
+)
+
+// ExportCache sends every cached PLN inquiry as JSONL (default) or CSV. The export is
+// spooled to a temporary file first, so a failure is reported instead of a truncated download.
+func (h *PLNInquiryHandler) ExportCache(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", services.CacheFormatJSONL))
+	if format != services.CacheFormatJSONL && format != services.CacheFormatCSV {
+		middleware.ValidationErrorResponse(c, map[string]string{"format": "format must be jsonl or csv"})
+		return
+	}
+
+	exportFailed := func(err error) {
+		h.logger.WithError(err).Error("Failed to export PLN inquiry cache")
+		middleware.JSON(c, http.StatusInternalServerError, &models.PLNInquiryError{
+			Code:    "CACHE_EXPORT_FAILED",
+			Message: "Failed to export cache",
+			Details: err.Error(),
+		})
+	}
+
+	file, err := os.CreateTemp("", "pln_cache_export_*")
+	if err != nil {
+		exportFailed(err)
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := h.plnInquiryService.ExportCache(c.Request.Context(), file, format); err != nil {
+		exportFailed(err)
+		return
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		exportFailed(err)
+		return
+	}
+
+	contentType := "application/x-ndjson"
+	if format == services.CacheFormatCSV {
+		contentType = "text/csv"
+	}
+	filename := fmt.Sprintf("pln_cache_%s.%s", time.Now().Format("20060102-150405"), format)
+	c.DataFromReader(http.StatusOK, size, contentType, file, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", filename),
+	})
+}
+
+// ImportCache imports cached PLN inquiries from an uploaded "file" or the raw request body.
+// The format defaults to the uploaded file's extension and the policy to skip.
+func (h *PLNInquiryHandler) ImportCache(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+
+	var body io.Reader = c.Request.Body
+	format := services.CacheFormatJSONL
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			middleware.JSON(c, http.StatusBadRequest, &models.PLNInquiryError{
+				Code:    "INVALID_REQUEST",
+				Message: "file is required",
+				Details: err.Error(),
+			})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			middleware.JSON(c, http.StatusBadRequest, &models.PLNInquiryError{
+				Code:    "INVALID_REQUEST",
+				Message: "Failed to read uploaded file",
+				Details: err.Error(),
+			})
+			return
+		}
+		defer file.Close()
+		body = file
+		format = services.CacheFormatForFile(fileHeader.Filename)
+	} else if c.ContentType() == "text/csv" {
+		format = services.CacheFormatCSV
+	}
+	format = strings.ToLower(c.DefaultQuery("format", format))
+	policy := strings.ToLower(c.DefaultQuery("policy", services.ImportPolicySkip))
+
+	result, err := h.plnInquiryService.ImportCache(c.Request.Context(), body, format, policy)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to import PLN inquiry cache")
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.PLNInquiryError{
+			Code:    "CACHE_IMPORT_FAILED",
+			Message: "Failed to import cache",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	middleware.SetAuditDetails(c, map[string]interface{}{
+		"format":   format,
+		"policy":   policy,
+		"total":    result.Total,
+		"imported": result.Imported,
+		"skipped":  result.Skipped,
+		"expired":  result.Expired,
+		"failed":   result.Failed,
+	})
+
+	middleware.JSON(c, http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cache imported successfully",
+		"data":    result,
+	})
+}
+
+// WarmCache starts looking up a list of customer numbers against Digiflazz to fill the
+// cache. The warm-up runs in the background; its job is polled with GetWarmJob.
+func (h *PLNInquiryHandler) WarmCache(c *gin.Context) {
+	var req models.PLNCacheWarmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind cache warm request")
+		middleware.JSON(c, http.StatusBadRequest, &models.PLNInquiryError{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+	if len(req.CustomerNos) > maxWarmBatch {
+		middleware.ValidationErrorResponse(c, map[string]string{
+			"customer_nos": fmt.Sprintf("at most %d customer numbers per request", maxWarmBatch),
+		})
+		return
+	}
+
+	job, err := h.plnInquiryService.StartWarmCache(c.Request.Context(), req.CustomerNos, req.Concurrency)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to start PLN inquiry cache warm-up")
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		if errors.Is(err, services.ErrWarmJobsBusy) {
+			middleware.JSON(c, http.StatusTooManyRequests, &models.PLNInquiryError{
+				Code:    "CACHE_WARM_BUSY",
+				Message: "Too many cache warm-ups running",
+				Details: err.Error(),
+			})
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.PLNInquiryError{
+			Code:    "CACHE_WARM_FAILED",
+			Message: "Failed to warm cache",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	middleware.JSON(c, http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Cache warm-up started",
+		"data":    job,
+	})
+}
+
+// GetWarmJob reports the progress of a cache warm-up started by WarmCache
+func (h *PLNInquiryHandler) GetWarmJob(c *gin.Context) {
+	job, ok := h.plnInquiryService.GetWarmJob(c.Param("job_id"))
+	if !ok {
+		middleware.JSON(c, http.StatusNotFound, &models.PLNInquiryError{
+			Code:    "WARM_JOB_NOT_FOUND",
+			Message: "Cache warm-up job not found",
+		})
+		return
+	}
+
+	middleware.JSON(c, http.StatusOK, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// SearchCache lists cached PLN inquiries, filtered by customer_no, meter_no, name or segment_power
+func (h *PLNInquiryHandler) SearchCache(c *gin.Context) {
+	var query models.PLNCacheSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		middleware.JSON(c, http.StatusBadRequest, &models.PLNInquiryError{
+			Code:    "INVALID_REQUEST",
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.plnInquiryService.SearchCache(c.Request.Context(), query)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to search PLN inquiry cache")
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.PLNInquiryError{
+			Code:    "CACHE_SEARCH_FAILED",
+			Message: "Failed to search cache",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	middleware.JSON(c, http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
 	})
 }
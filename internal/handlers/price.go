@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"gateway-digiflazz/internal/middleware"
 	"gateway-digiflazz/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -29,10 +30,10 @@ func (h *PriceHandler) GetPrices(c *gin.Context) {
 	priceType := c.Query("type")
 
 	// Get prices
-	resp, err := h.priceService.GetPrices(priceType)
+	resp, err := h.priceService.GetPrices(c.Request.Context(), priceType)
 	if err != nil {
 		h.logger.WithError(err).Error("Price list retrieval failed")
-		c.JSON(http.StatusInternalServerError, gin.H{
+		middleware.JSON(c, http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error": gin.H{
 				"code":    "PRICES_FAILED",
@@ -44,7 +45,7 @@ func (h *PriceHandler) GetPrices(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    resp,
 	})
@@ -54,7 +55,7 @@ func (h *PriceHandler) GetPrices(c *gin.Context) {
 func (h *PriceHandler) GetProductByCode(c *gin.Context) {
 	code := c.Param("code")
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
+		middleware.JSON(c, http.StatusBadRequest, gin.H{
 			"success": false,
 			"error": gin.H{
 				"code":    "MISSING_CODE",
@@ -65,10 +66,10 @@ func (h *PriceHandler) GetProductByCode(c *gin.Context) {
 	}
 
 	// Get product
-	product, err := h.priceService.GetProductByCode(code)
+	product, err := h.priceService.GetProductByCode(c.Request.Context(), code)
 	if err != nil {
 		h.logger.WithError(err).Error("Product lookup failed")
-		c.JSON(http.StatusNotFound, gin.H{
+		middleware.JSON(c, http.StatusNotFound, gin.H{
 			"success": false,
 			"error": gin.H{
 				"code":    "PRODUCT_NOT_FOUND",
@@ -80,7 +81,7 @@ func (h *PriceHandler) GetProductByCode(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    product,
 	})
@@ -90,7 +91,7 @@ func (h *PriceHandler) GetProductByCode(c *gin.Context) {
 func (h *PriceHandler) GetProductsByCategory(c *gin.Context) {
 	category := c.Param("category")
 	if category == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
+		middleware.JSON(c, http.StatusBadRequest, gin.H{
 			"success": false,
 			"error": gin.H{
 				"code":    "MISSING_CATEGORY",
@@ -101,10 +102,10 @@ func (h *PriceHandler) GetProductsByCategory(c *gin.Context) {
 	}
 
 	// Get products
-	products, err := h.priceService.GetProductsByCategory(category)
+	products, err := h.priceService.GetProductsByCategory(c.Request.Context(), category)
 	if err != nil {
 		h.logger.WithError(err).Error("Products lookup failed")
-		c.JSON(http.StatusInternalServerError, gin.H{
+		middleware.JSON(c, http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error": gin.H{
 				"code":    "PRODUCTS_FAILED",
@@ -116,7 +117,7 @@ func (h *PriceHandler) GetProductsByCategory(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    products,
 	})
@@ -3,8 +3,11 @@ package handlers
 import (
 	"net/http"
 
+	"gateway-digiflazz/internal/middleware"
 	"gateway-digiflazz/internal/models"
 	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/internal/validation"
+	"gateway-digiflazz/pkg/operator"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -29,7 +32,7 @@ func (h *TransactionHandler) Topup(c *gin.Context) {
 	var req models.TopupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind topup request")
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request format",
 			Details: err.Error(),
@@ -38,10 +41,30 @@ func (h *TransactionHandler) Topup(c *gin.Context) {
 	}
 
 	// Process topup
-	resp, err := h.transactionService.Topup(req)
+	resp, err := h.transactionService.Topup(c.Request.Context(), req)
 	if err != nil {
 		h.logger.WithError(err).Error("Topup processing failed")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		if operator.IsNumberError(err) {
+			middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
+				Code:    "INVALID_CUSTOMER_NO",
+				Message: "Customer number does not match a supported operator",
+				Details: err.Error(),
+			})
+			return
+		}
+		if operator.IsProductError(err) {
+			middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
+				Code:    "PRODUCT_NOT_AVAILABLE",
+				Message: "Product is not available for this customer number",
+				Details: err.Error(),
+			})
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.ErrorResponse{
 			Code:    "TOPUP_FAILED",
 			Message: "Failed to process topup",
 			Details: err.Error(),
@@ -50,7 +73,7 @@ func (h *TransactionHandler) Topup(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    resp,
 	})
@@ -61,7 +84,7 @@ func (h *TransactionHandler) Pay(c *gin.Context) {
 	var req models.PayRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.WithError(err).Error("Failed to bind payment request")
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
 			Code:    "INVALID_REQUEST",
 			Message: "Invalid request format",
 			Details: err.Error(),
@@ -70,10 +93,14 @@ func (h *TransactionHandler) Pay(c *gin.Context) {
 	}
 
 	// Process payment
-	resp, err := h.transactionService.Pay(req)
+	resp, err := h.transactionService.Pay(c.Request.Context(), req)
 	if err != nil {
 		h.logger.WithError(err).Error("Payment processing failed")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		if fieldErrors, ok := validation.AsErrors(err); ok {
+			middleware.ValidationErrorResponse(c, fieldErrors)
+			return
+		}
+		middleware.JSON(c, http.StatusInternalServerError, &models.ErrorResponse{
 			Code:    "PAYMENT_FAILED",
 			Message: "Failed to process payment",
 			Details: err.Error(),
@@ -82,7 +109,7 @@ func (h *TransactionHandler) Pay(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    resp,
 	})
@@ -92,7 +119,7 @@ func (h *TransactionHandler) Pay(c *gin.Context) {
 func (h *TransactionHandler) GetStatus(c *gin.Context) {
 	refID := c.Param("ref_id")
 	if refID == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
 			Code:    "MISSING_REF_ID",
 			Message: "ref_id parameter is required",
 		})
@@ -100,10 +127,10 @@ func (h *TransactionHandler) GetStatus(c *gin.Context) {
 	}
 
 	// Get transaction status
-	resp, err := h.transactionService.GetStatus(refID)
+	resp, err := h.transactionService.GetStatus(c.Request.Context(), refID)
 	if err != nil {
 		h.logger.WithError(err).Error("Status check failed")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		middleware.JSON(c, http.StatusInternalServerError, &models.ErrorResponse{
 			Code:    "STATUS_CHECK_FAILED",
 			Message: "Failed to check transaction status",
 			Details: err.Error(),
@@ -112,7 +139,7 @@ func (h *TransactionHandler) GetStatus(c *gin.Context) {
 	}
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"data":    resp,
 	})
@@ -123,7 +150,7 @@ func (h *TransactionHandler) Webhook(c *gin.Context) {
 	var webhook models.WebhookRequest
 	if err := c.ShouldBindJSON(&webhook); err != nil {
 		h.logger.WithError(err).Error("Failed to bind webhook request")
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		middleware.JSON(c, http.StatusBadRequest, &models.ErrorResponse{
 			Code:    "INVALID_WEBHOOK",
 			Message: "Invalid webhook format",
 			Details: err.Error(),
@@ -132,9 +159,9 @@ func (h *TransactionHandler) Webhook(c *gin.Context) {
 	}
 
 	// Process webhook
-	if err := h.transactionService.ProcessWebhook(webhook); err != nil {
+	if err := h.transactionService.ProcessWebhook(c.Request.Context(), webhook); err != nil {
 		h.logger.WithError(err).Error("Webhook processing failed")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		middleware.JSON(c, http.StatusInternalServerError, &models.ErrorResponse{
 			Code:    "WEBHOOK_FAILED",
 			Message: "Failed to process webhook",
 			Details: err.Error(),
@@ -143,7 +170,7 @@ func (h *TransactionHandler) Webhook(c *gin.Context) {
 	}
 
 	// Return success response
-	c.JSON(http.StatusOK, gin.H{
+	middleware.JSON(c, http.StatusOK, gin.H{
 		"success": true,
 		"message": "Webhook processed successfully",
 	})
@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// API key scopes
+const (
+	ScopeTransact = "transact"
+	ScopeInquiry  = "inquiry"
+	ScopeAdmin    = "admin"
+)
+
+// Where clients send their API key; Otomax uses GET URLs, hence the query parameter
+const (
+	APIKeyHeader     = "X-API-Key"
+	APIKeyQueryParam = "api_key"
+)
+
+// Context keys set for authenticated requests
+const (
+	ContextClientKey     = "client"
+	ContextClientNameKey = "client_name"
+)
+
+// Client is the identity of an authenticated API key
+type Client struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	Role   string   `json:"role"`
+}
+
+// HasScope reports whether the client was granted scope
+func (c *Client) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyUsage reports when an API key was last used; the key itself is never exposed
+type APIKeyUsage struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Role       string     `json:"role"`
+	Requests   int64      `json:"requests"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+}
+
+// apiKey is a configured key and its usage
+type apiKey struct {
+	client     Client
+	requests   int64
+	lastUsedAt time.Time
+	lastUsedIP string
+}
+
+// APIKeyStore holds the accepted API keys by their SHA-256 hash
+type APIKeyStore struct {
+	mu     sync.Mutex
+	byHash map[string]*apiKey
+	names  []string
+}
+
+// NewAPIKeyStore validates the configured keys
+func NewAPIKeyStore(keys []config.APIKeyConfig) (*APIKeyStore, error) {
+	store := &APIKeyStore{byHash: make(map[string]*apiKey)}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("api key name is required")
+		}
+		if seen[key.Name] {
+			return nil, fmt.Errorf("duplicate api key name %q", key.Name)
+		}
+		hash := strings.ToLower(strings.TrimSpace(key.KeyHash))
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("api key %q: key_hash must be a hex SHA-256 digest", key.Name)
+		}
+		if _, ok := store.byHash[hash]; ok {
+			return nil, fmt.Errorf("api key %q reuses the key of another api key", key.Name)
+		}
+		if len(key.Scopes) == 0 {
+			return nil, fmt.Errorf("api key %q has no scopes", key.Name)
+		}
+		for _, scope := range key.Scopes {
+			switch scope {
+			case ScopeTransact, ScopeInquiry, ScopeAdmin:
+			default:
+				return nil, fmt.Errorf("api key %q: unknown scope %q", key.Name, scope)
+			}
+		}
+
+		role := key.Role
+		if role == "" {
+			role = defaultRole(key.Scopes)
+		} else if !ValidRole(role) {
+			return nil, fmt.Errorf("api key %q: unknown role %q", key.Name, role)
+		}
+
+		seen[key.Name] = true
+		store.names = append(store.names, key.Name)
+		store.byHash[hash] = &apiKey{client: Client{Name: key.Name, Scopes: key.Scopes, Role: role}}
+	}
+	sort.Strings(store.names)
+	return store, nil
+}
+
+// HashAPIKey returns the hex SHA-256 digest stored in place of key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Enabled reports whether any API key is configured
+func (s *APIKeyStore) Enabled() bool {
+	return s != nil && len(s.byHash) > 0
+}
+
+// Authenticate returns the client owning key and records its use from ip
+func (s *APIKeyStore) Authenticate(key, ip string) (*Client, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.byHash[HashAPIKey(key)]
+	if !ok {
+		return nil, false
+	}
+	entry.requests++
+	entry.lastUsedAt = time.Now()
+	entry.lastUsedIP = ip
+	client := entry.client
+	return &client, true
+}
+
+// Usage returns the usage of every key ordered by name
+func (s *APIKeyStore) Usage() []APIKeyUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byName := make(map[string]*apiKey, len(s.byHash))
+	for _, entry := range s.byHash {
+		byName[entry.client.Name] = entry
+	}
+	usage := make([]APIKeyUsage, 0, len(s.names))
+	for _, name := range s.names {
+		entry := byName[name]
+		item := APIKeyUsage{
+			Name:       name,
+			Scopes:     entry.client.Scopes,
+			Role:       entry.client.Role,
+			Requests:   entry.requests,
+			LastUsedIP: entry.lastUsedIP,
+		}
+		if !entry.lastUsedAt.IsZero() {
+			lastUsedAt := entry.lastUsedAt
+			item.LastUsedAt = &lastUsedAt
+		}
+		usage = append(usage, item)
+	}
+	return usage
+}
+
+// APIKeyAuth requires an API key granted scope, sent in the X-API-Key header, as a
+// bearer token or in the api_key query parameter. The client is stored in the context.
+// Requests pass through unauthenticated while no key is configured.
+func APIKeyAuth(store *APIKeyStore, scope string, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !store.Enabled() {
+			c.Next()
+			return
+		}
+
+		key := apiKeyFromRequest(c)
+		if key == "" {
+			ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "API key is required",
+				fmt.Sprintf("Send the key in the %s header or the %s query parameter", APIKeyHeader, APIKeyQueryParam))
+			c.Abort()
+			return
+		}
+
+		client, ok := store.Authenticate(key, c.ClientIP())
+		if !ok {
+			logger.WithFields(logrus.Fields{
+				"path": c.Request.URL.Path,
+				"ip":   c.ClientIP(),
+			}).Warn("Rejected request with invalid API key")
+			ErrorResponse(c, http.StatusUnauthorized, "INVALID_API_KEY", "Invalid API key", "")
+			c.Abort()
+			return
+		}
+
+		c.Set(ContextClientKey, client)
+		c.Set(ContextClientNameKey, client.Name)
+
+		if !client.HasScope(scope) {
+			logger.WithFields(logrus.Fields{
+				"client": client.Name,
+				"scope":  scope,
+				"path":   c.Request.URL.Path,
+			}).Warn("Rejected request lacking API key scope")
+			ErrorResponse(c, http.StatusForbidden, "INSUFFICIENT_SCOPE", "API key is not allowed to use this endpoint",
+				fmt.Sprintf("Requires the %s scope", scope))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// apiKeyFromRequest extracts the API key and removes it from the query so handlers never see it
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+
+	query := c.Request.URL.Query()
+	key := query.Get(APIKeyQueryParam)
+	if key != "" {
+		query.Del(APIKeyQueryParam)
+		c.Request.URL.RawQuery = query.Encode()
+	}
+	return key
+}
+
+// ClientFromContext returns the authenticated client of the request, if any
+func ClientFromContext(c *gin.Context) (*Client, bool) {
+	value, ok := c.Get(ContextClientKey)
+	if !ok {
+		return nil, false
+	}
+	client, ok := value.(*Client)
+	return client, ok
+}
+
+// ClientName returns the name of the authenticated client, or "" for anonymous requests
+func ClientName(c *gin.Context) string {
+	return c.GetString(ContextClientNameKey)
+}
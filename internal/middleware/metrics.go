@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"time"
+
+	"gateway-digiflazz/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths cannot grow
+// the metric label set
+const unmatchedRoute = "unmatched"
+
+// Metrics records the count and latency of every request by its route pattern.
+// A nil m records nothing.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"gateway-digiflazz/pkg/requestid"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -11,19 +15,40 @@ import (
 // Logger middleware for request logging
 func Logger(logger *logrus.Logger) gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		logger.WithFields(logrus.Fields{
+		fields := logrus.Fields{
 			"status":     param.StatusCode,
 			"method":     param.Method,
-			"path":       param.Path,
+			"path":       redactAPIKey(param.Path),
 			"ip":         param.ClientIP,
 			"user_agent": param.Request.UserAgent(),
 			"latency":    param.Latency,
 			"time":       param.TimeStamp.Format(time.RFC3339),
-		}).Info("HTTP Request")
+		}
+		if client, ok := param.Keys[ContextClientNameKey].(string); ok {
+			fields["client"] = client
+		}
+		if id, ok := param.Keys[requestid.Field].(string); ok {
+			fields[requestid.Field] = id
+		}
+		logger.WithFields(fields).Info("HTTP Request")
 		return ""
 	})
 }
 
+// redactAPIKey masks the api_key query parameter of a logged path
+func redactAPIKey(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found || !strings.Contains(rawQuery, APIKeyQueryParam+"=") {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?[redacted]"
+	}
+	query.Set(APIKeyQueryParam, "[redacted]")
+	return base + "?" + query.Encode()
+}
+
 // Recovery middleware for panic recovery
 func Recovery(logger *logrus.Logger) gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
@@ -31,9 +56,10 @@ func Recovery(logger *logrus.Logger) gin.HandlerFunc {
 			"error": recovered,
 			"path":  c.Request.URL.Path,
 			"method": c.Request.Method,
+			"request_id": RequestIDFromContext(c),
 		}).Error("Panic recovered")
 		
-		c.JSON(http.StatusInternalServerError, gin.H{
+		JSON(c, http.StatusInternalServerError, gin.H{
 			"error": "Internal server error",
 		})
 	})
@@ -44,8 +70,9 @@ func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-API-Key, X-Request-ID")
 		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -56,16 +83,6 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
-// RateLimit middleware for API rate limiting
-func RateLimit() gin.HandlerFunc {
-	// Simple in-memory rate limiter
-	// In production, use Redis or other distributed rate limiter
-	return func(c *gin.Context) {
-		// TODO: Implement proper rate limiting
-		c.Next()
-	}
-}
-
 // SecurityHeaders middleware for security headers
 func SecurityHeaders() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -77,31 +94,45 @@ func SecurityHeaders() gin.HandlerFunc {
 	}
 }
 
-// RequestID middleware for request tracking
+// RequestID tags every request with an ID: the client's X-Request-ID when it is a safe
+// token, otherwise a new random one. The ID is returned in the X-Request-ID header and,
+// for responses written with JSON, as request_id in the body. It is stored in the gin
+// context and carried by the request context into service and Digiflazz client logs.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
-			requestID = generateRequestID()
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
 		}
-		
-		c.Header("X-Request-ID", requestID)
-		c.Set("request_id", requestID)
+
+		c.Header(requestid.Header, id)
+		c.Set(requestid.Field, id)
+		c.Request = c.Request.WithContext(requestid.WithContext(c.Request.Context(), id))
 		c.Next()
 	}
 }
 
-// generateRequestID generates a unique request ID
-func generateRequestID() string {
-	return time.Now().Format("20060102150405") + "-" + randomString(8)
+// RequestIDFromContext returns the request ID of the request, or "" outside RequestID
+func RequestIDFromContext(c *gin.Context) string {
+	return c.GetString(requestid.Field)
+}
+
+// requestIDSetter is implemented by response models embedding models.RequestIDField
+type requestIDSetter interface {
+	SetRequestID(id string)
 }
 
-// randomString generates a random string of specified length
-func randomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
+// JSON writes body as the JSON response with the request ID added as request_id.
+// gin.H bodies get a request_id key and models embedding models.RequestIDField (passed
+// by pointer) get the field set; other bodies are written unchanged.
+func JSON(c *gin.Context, status int, body interface{}) {
+	if id := RequestIDFromContext(c); id != "" {
+		switch b := body.(type) {
+		case gin.H:
+			b[requestid.Field] = id
+		case requestIDSetter:
+			b.SetRequestID(id)
+		}
 	}
-	return string(b)
+	c.JSON(status, body)
 }
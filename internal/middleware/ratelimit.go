@@ -0,0 +1,305 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// Rate limit stores
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+)
+
+// Defaults used when no budget is configured
+const (
+	DefaultRateLimitRequests = 100
+	DefaultRateLimitWindow   = time.Minute
+)
+
+// RateLimitRule is a token bucket holding Burst tokens, refilled at Requests per Window
+type RateLimitRule struct {
+	Requests int
+	Window   time.Duration
+	Burst    int
+}
+
+// rate returns the refill rate in tokens per second
+func (r RateLimitRule) rate() float64 {
+	return float64(r.Requests) / r.Window.Seconds()
+}
+
+// RateLimitResult is the outcome of taking a token
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available when the request was denied
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error)
+}
+
+// RateLimiter applies a token bucket per client and route group
+type RateLimiter struct {
+	store  RateLimitStore
+	rules  map[string]RateLimitRule
+	logger *logrus.Logger
+}
+
+// NewRateLimiter builds the per-group rules from cfg. Groups without their own budget
+// use cfg.Requests per cfg.Window.
+func NewRateLimiter(cfg config.RateLimitConfig, store RateLimitStore, logger *logrus.Logger) *RateLimiter {
+	base := RateLimitRule{Requests: cfg.Requests, Window: cfg.Window, Burst: cfg.Burst}
+	if base.Requests <= 0 {
+		base.Requests = DefaultRateLimitRequests
+	}
+	if base.Window <= 0 {
+		base.Window = DefaultRateLimitWindow
+	}
+
+	rules := make(map[string]RateLimitRule)
+	for _, group := range []string{ScopeTransact, ScopeInquiry, ScopeAdmin} {
+		rule := base
+		if override, ok := cfg.Groups[group]; ok {
+			if override.Requests > 0 {
+				rule.Requests = override.Requests
+			}
+			if override.Window > 0 {
+				rule.Window = override.Window
+			}
+			if override.Burst > 0 {
+				rule.Burst = override.Burst
+			}
+		}
+		if rule.Burst <= 0 {
+			rule.Burst = rule.Requests
+		}
+		rules[group] = rule
+	}
+
+	return &RateLimiter{store: store, rules: rules, logger: logger}
+}
+
+// Rule returns the budget of a route group
+func (l *RateLimiter) Rule(group string) RateLimitRule {
+	return l.rules[group]
+}
+
+// RateLimit limits requests of a route group per API client, or per client IP for
+// anonymous requests. It must run after APIKeyAuth. A nil limiter disables limiting.
+func RateLimit(limiter *RateLimiter, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		rule, ok := limiter.rules[group]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		key := group + ":ip:" + c.ClientIP()
+		if client := ClientName(c); client != "" {
+			key = group + ":client:" + client
+		}
+
+		result, err := limiter.store.Take(c.Request.Context(), key, rule)
+		if err != nil {
+			// Fail open so a store outage does not take the gateway down
+			limiter.logger.WithError(err).WithField("key", key).Warn("Rate limit store unavailable, allowing request")
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			limiter.logger.WithFields(logrus.Fields{
+				"key":         key,
+				"path":        c.Request.URL.Path,
+				"retry_after": retryAfter,
+			}).Warn("Rate limit exceeded")
+			ErrorResponse(c, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests",
+				fmt.Sprintf("Limit is %d requests per %s; retry after %d seconds", rule.Requests, rule.Window, retryAfter))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// takeToken refills a bucket holding tokens since elapsed and takes one token
+func takeToken(tokens float64, elapsed time.Duration, rule RateLimitRule) (float64, RateLimitResult) {
+	rate := rule.rate()
+	capacity := float64(rule.Burst)
+	tokens = math.Min(capacity, tokens+elapsed.Seconds()*rate)
+
+	result := RateLimitResult{Limit: rule.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = time.Duration((capacity - tokens) / rate * float64(time.Second))
+	return tokens, result
+}
+
+// memoryBucket is a token bucket of MemoryRateLimitStore
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	// idle is how long the bucket takes to refill, after which it can be dropped
+	idle time.Duration
+}
+
+// MemoryRateLimitStore keeps token buckets in process memory
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimitStore creates an in-memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take takes a token from the bucket of key
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(rule.Burst), updated: now}
+		s.buckets[key] = bucket
+	}
+
+	tokens, result := takeToken(bucket.tokens, now.Sub(bucket.updated), rule)
+	bucket.tokens = tokens
+	bucket.updated = now
+	bucket.idle = time.Duration(float64(rule.Burst) / rule.rate() * float64(time.Second))
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) > bucket.idle {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// redisTakeScript atomically refills and takes a token from a bucket stored as a hash.
+// KEYS[1] bucket; ARGV: now (ms), rate (tokens/ms), capacity, ttl (ms).
+// Returns {allowed, tokens*1000}.
+var redisTakeScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil then
+	tokens = capacity
+	updated = now
+end
+
+local elapsed = math.max(0, now - updated)
+tokens = math.min(capacity, tokens + elapsed * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], ttl)
+return {allowed, math.floor(tokens * 1000)}
+`)
+
+// RedisRateLimitStore keeps token buckets in Redis so every instance shares the budget
+type RedisRateLimitStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisRateLimitStore creates a Redis rate limit store; bucket keys are prefixed with keyPrefix
+func NewRedisRateLimitStore(client *redis.Client, keyPrefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, keyPrefix: keyPrefix}
+}
+
+// Take takes a token from the bucket of key
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	ratePerMs := rule.rate() / 1000
+	refill := time.Duration(float64(rule.Burst) / rule.rate() * float64(time.Second))
+	ttl := refill + time.Second
+
+	values, err := redisTakeScript.Run(ctx, s.client, []string{s.keyPrefix + key},
+		time.Now().UnixMilli(), ratePerMs, rule.Burst, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	// Rebuild the result from the stored tokens without refilling again
+	tokens := float64(values[1]) / 1000
+	result := RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      rule.Burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(rule.Burst) - tokens) / rule.rate() * float64(time.Second)),
+	}
+	if !result.Allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rule.rate() * float64(time.Second))
+	}
+	return result, nil
+}
@@ -35,6 +35,7 @@ func ResponseInterceptor(logger *logrus.Logger) gin.HandlerFunc {
 			"duration":   duration,
 			"client_ip":  c.ClientIP(),
 			"user_agent": c.Request.UserAgent(),
+			"request_id": RequestIDFromContext(c),
 		}).Info("HTTP Request")
 		
 		// Format response based on status code
@@ -148,7 +149,7 @@ func formatErrorResponse(c *gin.Context, statusCode int) {
 		}
 	}
 	
-	c.JSON(statusCode, response)
+	JSON(c, statusCode, response)
 }
 
 // SuccessResponse memformat success response secara konsisten
@@ -160,7 +161,7 @@ func SuccessResponse(c *gin.Context, data interface{}, message string) {
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
 	
-	c.JSON(http.StatusOK, response)
+	JSON(c, http.StatusOK, response)
 }
 
 // ErrorResponse memformat error response secara konsisten
@@ -175,7 +176,7 @@ func ErrorResponse(c *gin.Context, statusCode int, code, message, details string
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
 	
-	c.JSON(statusCode, response)
+	JSON(c, statusCode, response)
 }
 
 // ValidationErrorResponse memformat validation error response
@@ -191,5 +192,5 @@ func ValidationErrorResponse(c *gin.Context, errors map[string]string) {
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
 	
-	c.JSON(http.StatusBadRequest, response)
+	JSON(c, http.StatusBadRequest, response)
 }
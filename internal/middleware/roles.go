@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Roles of API clients on the management routes, from most to least privileged
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleReseller = "reseller"
+)
+
+// roleRank orders the roles; a role is granted everything the roles below it are
+var roleRank = map[string]int{
+	RoleReseller: 1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// defaultRole is the role of keys configured without one: admin for keys holding the
+// admin scope, which already had full management access, and reseller otherwise
+func defaultRole(scopes []string) string {
+	for _, scope := range scopes {
+		if scope == ScopeAdmin {
+			return RoleAdmin
+		}
+	}
+	return RoleReseller
+}
+
+// HasRole reports whether the client holds role or a more privileged one
+func (c *Client) HasRole(role string) bool {
+	return roleRank[c.Role] >= roleRank[role]
+}
+
+// contextAuditDetailsKey holds the details a handler adds to its audit entry
+const contextAuditDetailsKey = "audit_details"
+
+// RequireRole rejects authenticated clients below role. It must follow APIKeyAuth.
+// Unlike APIKeyAuth it fails closed: while no API key is configured the management
+// routes it guards are refused.
+func RequireRole(store *APIKeyStore, role string, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !store.Enabled() {
+			logger.WithFields(logrus.Fields{
+				"role": role,
+				"path": c.Request.URL.Path,
+				"ip":   c.ClientIP(),
+			}).Warn("Rejected management request, no API key configured")
+			ErrorResponse(c, http.StatusForbidden, "MANAGEMENT_DISABLED", "Management endpoints are disabled",
+				fmt.Sprintf("Configure an API key with the %s role", role))
+			c.Abort()
+			return
+		}
+
+		client, ok := ClientFromContext(c)
+		if !ok || !client.HasRole(role) {
+			fields := logrus.Fields{
+				"role": role,
+				"path": c.Request.URL.Path,
+				"ip":   c.ClientIP(),
+			}
+			if ok {
+				fields["client"] = client.Name
+				fields["client_role"] = client.Role
+			}
+			logger.WithFields(fields).Warn("Rejected request lacking role")
+			ErrorResponse(c, http.StatusForbidden, "INSUFFICIENT_ROLE", "API key role is not allowed to use this endpoint",
+				fmt.Sprintf("Requires the %s role", role))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SetAuditDetails records the values changed by the request in its audit entry
+func SetAuditDetails(c *gin.Context, details map[string]interface{}) {
+	c.Set(contextAuditDetailsKey, details)
+}
+
+// Audit records action in the audit log once the request completes, whether it was
+// allowed or rejected. Place it after authentication and rate limiting, so only
+// authenticated clients within their budget can add entries, and before RequireRole,
+// so attempts denied by role are kept too. The target is the customer_no path
+// parameter when the route has one.
+func Audit(auditLog repositories.AuditLogRepository, action string, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		entry := models.AuditEntry{
+			Time:   time.Now().UTC(),
+			Actor:  "anonymous",
+			IP:     c.ClientIP(),
+			Action: action,
+			Target: c.Param("customer_no"),
+			Method: c.Request.Method,
+			Path:   c.Request.URL.Path,
+			Status: c.Writer.Status(),
+		}
+		if client, ok := ClientFromContext(c); ok {
+			entry.Actor = client.Name
+			entry.Role = client.Role
+		}
+		if details, ok := c.Get(contextAuditDetailsKey); ok {
+			entry.Details, _ = details.(map[string]interface{})
+		}
+
+		logger.WithFields(logrus.Fields{
+			"audit":  true,
+			"actor":  entry.Actor,
+			"role":   entry.Role,
+			"ip":     entry.IP,
+			"action": entry.Action,
+			"target": entry.Target,
+			"status": entry.Status,
+		}).Info("Audited management action")
+
+		if auditLog == nil {
+			return
+		}
+		if err := auditLog.Append(entry); err != nil {
+			logger.WithError(err).WithField("action", action).Error("Failed to write audit log entry")
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// AuditEntry records a destructive management action
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Role   string    `json:"role,omitempty"`
+	IP     string    `json:"ip"`
+	Action string    `json:"action"`
+	Target string    `json:"target,omitempty"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+	// Details holds the values the action changed, e.g. the new configuration or import counts
+	Details map[string]interface{} `json:"details,omitempty"`
+}
@@ -62,6 +62,7 @@ type TopupRequest struct {
 	CustomerNo string `json:"customer_no"`
 	RefID     string `json:"ref_id"`
 	Sign      string `json:"sign"`
+	MaxPrice  float64 `json:"max_price,omitempty"`
 }
 
 // TopupResponse represents the response for topup transaction
@@ -156,6 +157,7 @@ type ErrorResponse struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+	RequestIDField
 }
 
 // Transaction represents a transaction record
@@ -180,3 +182,11 @@ type APIError struct {
 	Message string `json:"message"`
 	Status  int    `json:"status"`
 }
+
+// AccountBalance represents the last known deposit balance of a Digiflazz account
+type AccountBalance struct {
+	Account   string    `json:"account"`
+	Deposit   float64   `json:"deposit"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Error     string    `json:"error,omitempty"`
+}
@@ -10,6 +10,59 @@ type OtomaxTransactionRequest struct {
 	Amount     string `form:"amount" json:"amount"`
 	Type       string `form:"type" json:"type"` // prabayar or pascabayar
 	Timestamp  string `form:"timestamp" json:"timestamp"`
+	ResellerID string `form:"reseller_id" json:"reseller_id"`
+	Category   string `form:"category" json:"category"`
+	// Nonce and Sign are required from resellers that opted in to request signing
+	Nonce string `form:"nonce" json:"nonce"`
+	Sign  string `form:"sign" json:"sign"`
+	// ClientID is the authenticated API client, set by the handler
+	ClientID string `form:"-" json:"-"`
+}
+
+// OtomaxRequestSignature holds the signed fields of an Otomax request
+type OtomaxRequestSignature struct {
+	ResellerID string
+	ClientID   string
+	RefID      string
+	CustomerNo string
+	BuyerSKU   string
+	Timestamp  string
+	Nonce      string
+	Sign       string
+}
+
+// OtomaxResponseSignature holds the signed fields of an Otomax response or callback
+type OtomaxResponseSignature struct {
+	RefID      string
+	CustomerNo string
+	BuyerSKU   string
+	Status     string
+	RC         string
+	SN         string
+	Timestamp  string
+}
+
+// OtomaxSignatureCheck reports whether a signature matches the gateway's computation
+type OtomaxSignatureCheck struct {
+	Valid     bool   `json:"valid"`
+	Algorithm string `json:"algorithm"`
+	// Canonical is the signed string; {secret} marks where MD5 appends the secret
+	Canonical string `json:"canonical"`
+}
+
+// OtomaxSignatureCheckRequest asks the gateway to check a request or response signature
+type OtomaxSignatureCheckRequest struct {
+	Type       string `form:"type" json:"type"` // request or response
+	ResellerID string `form:"reseller_id" json:"reseller_id"`
+	RefID      string `form:"ref_id" json:"ref_id" binding:"required"`
+	CustomerNo string `form:"customer_no" json:"customer_no"`
+	BuyerSKU   string `form:"buyer_sku" json:"buyer_sku"`
+	Status     string `form:"status" json:"status"`
+	RC         string `form:"rc" json:"rc"`
+	SN         string `form:"sn" json:"sn"`
+	Timestamp  string `form:"timestamp" json:"timestamp"`
+	Nonce      string `form:"nonce" json:"nonce"`
+	Sign       string `form:"sign" json:"sign" binding:"required"`
 }
 
 // OtomaxTransactionResponse represents the response to Otomax
@@ -22,14 +75,18 @@ type OtomaxTransactionResponse struct {
 	Message    string  `json:"message"`
 	RC         string  `json:"rc"`
 	SN         string  `json:"sn,omitempty"`
+	PLNToken   *PLNTokenDetails `json:"pln_token,omitempty"`
 	Timestamp  string  `json:"timestamp"`
 	Sign       string  `json:"sign"`
 }
 
 // OtomaxStatusRequest represents status check request from Otomax
 type OtomaxStatusRequest struct {
-	RefID     string `form:"ref_id" json:"ref_id" binding:"required"`
-	Timestamp string `form:"timestamp" json:"timestamp"`
+	RefID      string `form:"ref_id" json:"ref_id" binding:"required"`
+	Timestamp  string `form:"timestamp" json:"timestamp"`
+	ResellerID string `form:"reseller_id" json:"reseller_id"`
+	// ClientID is the authenticated API client, set by the handler
+	ClientID string `form:"-" json:"-"`
 }
 
 // OtomaxStatusResponse represents status check response to Otomax
@@ -42,8 +99,12 @@ type OtomaxStatusResponse struct {
 	Message    string  `json:"message"`
 	RC         string  `json:"rc"`
 	SN         string  `json:"sn,omitempty"`
+	PLNToken   *PLNTokenDetails `json:"pln_token,omitempty"`
+	// TransactionRequestID is the request ID of the purchase, for support lookups
+	TransactionRequestID string `json:"transaction_request_id,omitempty"`
 	Timestamp  string  `json:"timestamp"`
 	Sign       string  `json:"sign"`
+	RequestIDField
 }
 
 // OtomaxCallback represents the callback from Digiflazz for Otomax transactions
@@ -66,6 +127,7 @@ type OtomaxTransaction struct {
 	RefID       string    `json:"ref_id"`
 	CustomerNo  string    `json:"customer_no"`
 	BuyerSKU    string    `json:"buyer_sku"`
+	ProductCode string    `json:"product_code,omitempty"`
 	Amount      float64   `json:"amount"`
 	Type        string    `json:"type"`
 	Status      string    `json:"status"`
@@ -73,13 +135,35 @@ type OtomaxTransaction struct {
 	RC          string    `json:"rc"`
 	SN          string    `json:"sn"`
 	DigiflazzRefID string `json:"digiflazz_ref_id"`
+	ResellerID  string    `json:"reseller_id,omitempty"`
+	ClientID    string    `json:"client_id,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	Account     string    `json:"account,omitempty"`
+	// RequestID is the gateway request ID of the purchase
+	RequestID   string    `json:"request_id,omitempty"`
+	Attempts    []OtomaxTransactionAttempt `json:"attempts,omitempty"`
+	PLNToken    *PLNTokenDetails `json:"pln_token,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// OtomaxTransactionAttempt represents a single Digiflazz attempt made for an Otomax transaction
+type OtomaxTransactionAttempt struct {
+	RefID       string    `json:"ref_id"`
+	BuyerSKU    string    `json:"buyer_sku"`
+	Account     string    `json:"account"`
+	Status      string    `json:"status"`
+	RC          string    `json:"rc"`
+	Message     string    `json:"message"`
+	SN          string    `json:"sn,omitempty"`
+	Price       float64   `json:"price"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
 // OtomaxError represents an Otomax error response
 type OtomaxError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+	RequestIDField
 }
@@ -80,6 +80,7 @@ type OtomaxPascabayarCheckRequest struct {
 	CustomerNo string `form:"customer_no" json:"customer_no" binding:"required"`
 	BuyerSKU   string `form:"buyer_sku" json:"buyer_sku" binding:"required"`
 	Timestamp  string `form:"timestamp" json:"timestamp"`
+	ResellerID string `form:"reseller_id" json:"reseller_id"`
 }
 
 // OtomaxPascabayarCheckResponse represents Otomax response for Pascabayar check
@@ -96,6 +97,7 @@ type OtomaxPascabayarCheckResponse struct {
 	BillDetails  BillDetails `json:"bill_details"`
 	Timestamp    string  `json:"timestamp"`
 	Sign         string  `json:"sign"`
+	RequestIDField
 }
 
 // OtomaxPascabayarPayRequest represents Otomax request for Pascabayar payment
@@ -105,6 +107,9 @@ type OtomaxPascabayarPayRequest struct {
 	BuyerSKU   string  `form:"buyer_sku" json:"buyer_sku" binding:"required"`
 	Amount     float64 `form:"amount" json:"amount" binding:"required"`
 	Timestamp  string  `form:"timestamp" json:"timestamp"`
+	ResellerID string  `form:"reseller_id" json:"reseller_id"`
+	Nonce      string  `form:"nonce" json:"nonce"`
+	Sign       string  `form:"sign" json:"sign"`
 }
 
 // OtomaxPascabayarPayResponse represents Otomax response for Pascabayar payment
@@ -122,6 +127,7 @@ type OtomaxPascabayarPayResponse struct {
 	BillDetails  BillDetails `json:"bill_details"`
 	Timestamp    string  `json:"timestamp"`
 	Sign         string  `json:"sign"`
+	RequestIDField
 }
 
 // BillDetails represents bill details for Pascabayar transactions
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // PLNInquiryRequest represents the request for PLN inquiry
 type PLNInquiryRequest struct {
@@ -31,6 +35,9 @@ type PLNInquiryResponse struct {
 	} `json:"data"`
 	Message string `json:"message"`
 	Status  int    `json:"status"`
+	// Set when the response was served from cache
+	CachedAt *time.Time `json:"cached_at,omitempty"`
+	Stale    bool       `json:"stale,omitempty"`
 }
 
 // PLNInquiryCache represents cached PLN inquiry data
@@ -53,13 +60,87 @@ type PLNInquiryError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+	RequestIDField
 }
 
-// PLNInquiryConfig represents configuration for PLN inquiry caching
+// PLNInquiryConfig represents configuration for PLN inquiry caching.
+// CacheTTL is the hard TTL after which entries are no longer served; zero keeps
+// entries until they are cleared. Entries older than CacheSoftTTL are still served
+// but refreshed in the background; zero disables background refresh.
 type PLNInquiryConfig struct {
 	CacheEnabled   bool          `json:"cache_enabled"`
 	CacheTTL       time.Duration `json:"cache_ttl"`
+	CacheSoftTTL   time.Duration `json:"cache_soft_ttl"`
 	CacheKeyPrefix string        `json:"cache_key_prefix"`
+	// Negative caching of definitive "customer not found" responses
+	NegativeCacheEnabled bool          `json:"negative_cache_enabled"`
+	NegativeCacheTTL     time.Duration `json:"negative_cache_ttl"`
+}
+
+// MarshalJSON encodes durations as strings such as "24h0m0s"
+func (c PLNInquiryConfig) MarshalJSON() ([]byte, error) {
+	type alias PLNInquiryConfig
+	return json.Marshal(struct {
+		alias
+		CacheTTL         string `json:"cache_ttl"`
+		CacheSoftTTL     string `json:"cache_soft_ttl"`
+		NegativeCacheTTL string `json:"negative_cache_ttl"`
+	}{
+		alias:            alias(c),
+		CacheTTL:         c.CacheTTL.String(),
+		CacheSoftTTL:     c.CacheSoftTTL.String(),
+		NegativeCacheTTL: c.NegativeCacheTTL.String(),
+	})
+}
+
+// UnmarshalJSON accepts durations as strings ("24h") only. Numbers are rejected:
+// encoding/json read them as nanoseconds before, so a bare number is ambiguous.
+// Fields missing from data keep their current values.
+func (c *PLNInquiryConfig) UnmarshalJSON(data []byte) error {
+	type alias PLNInquiryConfig
+	aux := struct {
+		*alias
+		CacheTTL         json.RawMessage `json:"cache_ttl"`
+		CacheSoftTTL     json.RawMessage `json:"cache_soft_ttl"`
+		NegativeCacheTTL json.RawMessage `json:"negative_cache_ttl"`
+	}{alias: (*alias)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if err := decodeDuration("cache_ttl", aux.CacheTTL, &c.CacheTTL); err != nil {
+		return err
+	}
+	if err := decodeDuration("cache_soft_ttl", aux.CacheSoftTTL, &c.CacheSoftTTL); err != nil {
+		return err
+	}
+	return decodeDuration("negative_cache_ttl", aux.NegativeCacheTTL, &c.NegativeCacheTTL)
+}
+
+// decodeDuration decodes a duration string into dst, leaving it unchanged when raw is empty
+func decodeDuration(field string, raw json.RawMessage, dst *time.Duration) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return fmt.Errorf("%s must be a duration string such as \"24h\" or \"30m\"", field)
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", field, text, err)
+	}
+	*dst = d
+	return nil
+}
+
+// PLNInquiryNegativeCache represents a cached "customer not found" PLN inquiry result
+type PLNInquiryNegativeCache struct {
+	CustomerNo string    `json:"customer_no"`
+	RC         string    `json:"rc"`
+	Message    string    `json:"message"`
+	CachedAt   time.Time `json:"cached_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
 }
 
 // PLNInquiryStats represents statistics for PLN inquiry
@@ -70,4 +151,144 @@ type PLNInquiryStats struct {
 	APIRequests      int64 `json:"api_requests"`
 	ErrorCount       int64 `json:"error_count"`
 	AverageResponseTime time.Duration `json:"average_response_time"`
+	CoalescedRequests int64 `json:"coalesced_requests"`
+	InflightRequests int64 `json:"inflight_requests"`
+	NegativeCacheHits int64 `json:"negative_cache_hits"`
+	NegativeCacheStores int64 `json:"negative_cache_stores"`
+	StaleHits        int64 `json:"stale_hits"`
+	BackgroundRefreshes int64 `json:"background_refreshes"`
+	BackgroundRefreshErrors int64 `json:"background_refresh_errors"`
+	HitRatio         float64 `json:"hit_ratio"`
+	Latency          PLNInquiryLatencyStats `json:"latency"`
+	Rates            map[string]PLNInquiryRateStats `json:"rates"`
+	Config           PLNInquiryConfig `json:"config"`
+	Cache            map[string]interface{} `json:"cache,omitempty"`
+}
+
+// PLNInquiryLatencyStats holds latency percentiles split by cache hits and API calls
+type PLNInquiryLatencyStats struct {
+	Cache LatencySummary `json:"cache"`
+	API   LatencySummary `json:"api"`
+}
+
+// LatencySummary summarises a latency histogram in milliseconds
+type LatencySummary struct {
+	Count  int64   `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+}
+
+// PLNInquiryRateStats holds request counts over a sliding window
+type PLNInquiryRateStats struct {
+	Requests          int64   `json:"requests"`
+	CacheHits         int64   `json:"cache_hits"`
+	APIRequests       int64   `json:"api_requests"`
+	CoalescedRequests int64   `json:"coalesced_requests"`
+	NegativeCacheHits int64   `json:"negative_cache_hits"`
+	ErrorCount        int64   `json:"error_count"`
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+	HitRatio          float64 `json:"hit_ratio"`
+}
+
+// OtomaxPLNTokenRequest represents a PLN prepaid token purchase request from Otomax
+type OtomaxPLNTokenRequest struct {
+	RefID       string `form:"ref_id" json:"ref_id" binding:"required"`
+	CustomerNo  string `form:"customer_no" json:"customer_no" binding:"required"`
+	BuyerSKU    string `form:"buyer_sku" json:"buyer_sku" binding:"required"`
+	Amount      string `form:"amount" json:"amount"`
+	ResellerID  string `form:"reseller_id" json:"reseller_id"`
+	SkipInquiry bool   `form:"skip_inquiry" json:"skip_inquiry"`
+	Timestamp   string `form:"timestamp" json:"timestamp"`
+	Nonce       string `form:"nonce" json:"nonce"`
+	Sign        string `form:"sign" json:"sign"`
+	// ClientID is the authenticated API client, set by the handler
+	ClientID string `form:"-" json:"-"`
+}
+
+// PLNTokenDetails represents the structured PLN token parsed from the Digiflazz SN
+type PLNTokenDetails struct {
+	Token          string  `json:"token"`
+	TokenFormatted string  `json:"token_formatted"`
+	CustomerName   string  `json:"customer_name,omitempty"`
+	Tariff         string  `json:"tariff,omitempty"`
+	Power          string  `json:"power,omitempty"`
+	KWh            float64 `json:"kwh,omitempty"`
+}
+
+// PLNCacheImportResult summarises an import of PLN cache entries
+type PLNCacheImportResult struct {
+	Total    int      `json:"total"`
+	Imported int      `json:"imported"`
+	Skipped  int      `json:"skipped"`
+	Expired  int      `json:"expired"`
+	Failed   int      `json:"failed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// PLNCacheWarmRequest represents a request to warm the PLN cache from Digiflazz
+type PLNCacheWarmRequest struct {
+	CustomerNos []string `json:"customer_nos" binding:"required"`
+	Concurrency int      `json:"concurrency"`
+}
+
+// PLNCacheWarmResult summarises a PLN cache warm-up
+type PLNCacheWarmResult struct {
+	Requested     int               `json:"requested"`
+	Warmed        int               `json:"warmed"`
+	AlreadyCached int               `json:"already_cached"`
+	NotFound      int               `json:"not_found"`
+	Failed        int               `json:"failed"`
+	Errors        map[string]string `json:"errors,omitempty"`
+	Duration      string            `json:"duration"`
+}
+
+// PLNCacheWarmJob tracks a PLN cache warm-up running in the background. Result is set
+// once the warm-up has finished.
+type PLNCacheWarmJob struct {
+	JobID      string              `json:"job_id"`
+	Status     string              `json:"status"`
+	Requested  int                 `json:"requested"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+	Result     *PLNCacheWarmResult `json:"result,omitempty"`
+}
+
+// PLNCacheSearchQuery filters and paginates cached PLN inquiries. CustomerNo and
+// MeterNo match by prefix, Name by case-insensitive substring and SegmentPower by
+// tariff and/or power ("R1", "1300" or "R1/1300").
+type PLNCacheSearchQuery struct {
+	CustomerNo   string `form:"customer_no"`
+	MeterNo      string `form:"meter_no"`
+	Name         string `form:"name"`
+	SegmentPower string `form:"segment_power"`
+	Page         int    `form:"page"`
+	PerPage      int    `form:"per_page"`
+}
+
+// PLNCacheEntry is a cached PLN inquiry as listed by the cache browser.
+// ExpiresAt is omitted for entries that never expire.
+type PLNCacheEntry struct {
+	CustomerNo   string     `json:"customer_no"`
+	MeterNo      string     `json:"meter_no,omitempty"`
+	SubscriberID string     `json:"subscriber_id,omitempty"`
+	Name         string     `json:"name,omitempty"`
+	SegmentPower string     `json:"segment_power,omitempty"`
+	RC           string     `json:"rc"`
+	RefID        string     `json:"ref_id,omitempty"`
+	CachedAt     time.Time  `json:"cached_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Stale        bool       `json:"stale"`
+}
+
+// PLNCacheSearchResult is a page of cached PLN inquiries. Truncated is set when the
+// search stopped at its scan limit, so Total only counts the entries it read.
+type PLNCacheSearchResult struct {
+	Entries    []PLNCacheEntry `json:"entries"`
+	Page       int             `json:"page"`
+	PerPage    int             `json:"per_page"`
+	Total      int             `json:"total"`
+	TotalPages int             `json:"total_pages"`
+	Truncated  bool            `json:"truncated,omitempty"`
 }
@@ -0,0 +1,12 @@
+package models
+
+// RequestIDField adds the gateway request ID to a JSON response model as request_id.
+// middleware.JSON fills it in; embed it last so the ID follows the model's own fields.
+type RequestIDField struct {
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// SetRequestID sets the request ID of the response
+func (f *RequestIDField) SetRequestID(id string) {
+	f.RequestID = id
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gateway-digiflazz/internal/models"
+)
+
+// AuditLogRepository stores the audit trail of destructive management actions
+type AuditLogRepository interface {
+	Append(entry models.AuditEntry) error
+	Recent(limit int) ([]models.AuditEntry, error)
+}
+
+// FileAuditLogRepository appends audit entries to a JSON lines file
+type FileAuditLogRepository struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileAuditLogRepository creates a repository backed by the JSON lines file at path
+func NewFileAuditLogRepository(path string) *FileAuditLogRepository {
+	return &FileAuditLogRepository{path: path}
+}
+
+// Append writes entry as a single line at the end of the file
+func (r *FileAuditLogRepository) Append(entry models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// auditReadChunk is the block size Recent reads from the end of the file
+const auditReadChunk = 64 << 10
+
+// Recent returns up to limit of the latest entries, newest first. The file is read
+// backwards from its end, so the cost follows limit rather than the size of the log.
+// A limit of zero or less returns every entry.
+func (r *FileAuditLogRepository) Recent(limit int) ([]models.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []models.AuditEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	entries := []models.AuditEntry{}
+	full := func() bool { return limit > 0 && len(entries) >= limit }
+	add := func(line []byte) {
+		var entry models.AuditEntry
+		if len(bytes.TrimSpace(line)) == 0 || json.Unmarshal(line, &entry) != nil {
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	// partial holds the start of a line whose beginning lies in an earlier chunk
+	var partial []byte
+	offset := info.Size()
+	for offset > 0 && !full() {
+		size := int64(auditReadChunk)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+
+		chunk := make([]byte, size, size+int64(len(partial)))
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		lines := bytes.Split(append(chunk, partial...), []byte{'\n'})
+		partial = lines[0]
+		for i := len(lines) - 1; i > 0 && !full(); i-- {
+			add(lines[i])
+		}
+	}
+	if offset == 0 && !full() {
+		add(partial)
+	}
+	return entries, nil
+}
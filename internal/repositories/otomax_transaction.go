@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"errors"
+	"sync"
+
+	"gateway-digiflazz/internal/models"
+)
+
+// ErrTransactionNotFound is returned when no transaction exists for a ref_id
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// OtomaxTransactionRepository defines storage operations for Otomax transactions
+type OtomaxTransactionRepository interface {
+	Save(tx *models.OtomaxTransaction) error
+	GetByRefID(refID string) (*models.OtomaxTransaction, error)
+	CountByStatus(status string) (int, error)
+}
+
+// MemoryOtomaxTransactionRepository stores Otomax transactions in memory
+type MemoryOtomaxTransactionRepository struct {
+	mu           sync.RWMutex
+	transactions map[string]models.OtomaxTransaction
+}
+
+// NewMemoryOtomaxTransactionRepository creates a new in-memory Otomax transaction repository
+func NewMemoryOtomaxTransactionRepository() *MemoryOtomaxTransactionRepository {
+	return &MemoryOtomaxTransactionRepository{
+		transactions: make(map[string]models.OtomaxTransaction),
+	}
+}
+
+// Save creates or replaces a transaction keyed by its ref_id
+func (r *MemoryOtomaxTransactionRepository) Save(tx *models.OtomaxTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *tx
+	stored.Attempts = append([]models.OtomaxTransactionAttempt(nil), tx.Attempts...)
+	r.transactions[tx.RefID] = stored
+	return nil
+}
+
+// GetByRefID retrieves a transaction by its ref_id
+func (r *MemoryOtomaxTransactionRepository) GetByRefID(refID string) (*models.OtomaxTransaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.transactions[refID]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	tx := stored
+	tx.Attempts = append([]models.OtomaxTransactionAttempt(nil), stored.Attempts...)
+	return &tx, nil
+}
+
+// CountByStatus returns the number of transactions with the given status
+func (r *MemoryOtomaxTransactionRepository) CountByStatus(status string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, tx := range r.transactions {
+		if tx.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gateway-digiflazz/internal/models"
+)
+
+// ErrConfigNotFound is returned when no PLN cache configuration has been persisted yet
+var ErrConfigNotFound = errors.New("pln cache config not found")
+
+// PLNCacheConfigRepository persists the runtime PLN inquiry cache configuration
+type PLNCacheConfigRepository interface {
+	Load(config *models.PLNInquiryConfig) error
+	Save(config models.PLNInquiryConfig) error
+}
+
+// FilePLNCacheConfigRepository stores the PLN cache configuration as a JSON file
+type FilePLNCacheConfigRepository struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFilePLNCacheConfigRepository creates a repository backed by the JSON file at path
+func NewFilePLNCacheConfigRepository(path string) *FilePLNCacheConfigRepository {
+	return &FilePLNCacheConfigRepository{path: path}
+}
+
+// Load decodes the persisted configuration onto config; settings missing from the file keep their values
+func (r *FilePLNCacheConfigRepository) Load(config *models.PLNInquiryConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrConfigNotFound
+		}
+		return fmt.Errorf("failed to read pln cache config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("failed to parse pln cache config: %w", err)
+	}
+	return nil
+}
+
+// Save writes the configuration, replacing the file atomically
+func (r *FilePLNCacheConfigRepository) Save(config models.PLNInquiryConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pln cache config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create pln cache config directory: %w", err)
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pln cache config: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to replace pln cache config: %w", err)
+	}
+	return nil
+}
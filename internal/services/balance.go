@@ -1,10 +1,12 @@
 package services
 
 import (
+	"context"
 	"fmt"
 
 	"gateway-digiflazz/internal/models"
 	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/requestid"
 
 	"github.com/sirupsen/logrus"
 )
@@ -12,28 +14,52 @@ import (
 // BalanceService handles balance operations
 type BalanceService struct {
 	digiflazzClient *digiflazz.Client
+	digiflazzPool   *digiflazz.Pool
 	logger          *logrus.Logger
 }
 
 // NewBalanceService creates a new balance service
-func NewBalanceService(client *digiflazz.Client, logger *logrus.Logger) *BalanceService {
+func NewBalanceService(pool *digiflazz.Pool, logger *logrus.Logger) *BalanceService {
 	return &BalanceService{
-		digiflazzClient: client,
+		digiflazzClient: pool.Default(),
+		digiflazzPool:   pool,
 		logger:          logger,
 	}
 }
 
 // GetBalance retrieves the current balance
-func (s *BalanceService) GetBalance() (*models.BalanceResponse, error) {
-	s.logger.Info("Retrieving account balance")
+func (s *BalanceService) GetBalance(ctx context.Context) (*models.BalanceResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.Info("Retrieving account balance")
 
 	// Call Digiflazz API
-	resp, err := s.digiflazzClient.CheckBalance()
+	resp, err := s.digiflazzClient.CheckBalance(ctx)
 	if err != nil {
-		s.logger.WithError(err).Error("Digiflazz balance API call failed")
+		log.WithError(err).Error("Digiflazz balance API call failed")
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
 
-	s.logger.WithField("balance", resp.Data.Deposit).Info("Balance retrieved successfully")
+	log.WithField("balance", resp.Data.Deposit).Info("Balance retrieved successfully")
 	return resp, nil
 }
+
+// GetAccountBalance retrieves the current balance of a named Digiflazz account
+func (s *BalanceService) GetAccountBalance(ctx context.Context, account string) (*models.BalanceResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithField("account", account).Info("Retrieving account balance")
+
+	resp, err := s.digiflazzPool.CheckBalance(ctx, account)
+	if err != nil {
+		log.WithError(err).WithField("account", account).Error("Digiflazz balance API call failed")
+		return nil, fmt.Errorf("failed to get balance for account %s: %w", account, err)
+	}
+
+	return resp, nil
+}
+
+// GetAccountBalances refreshes and returns the balance of every Digiflazz account
+func (s *BalanceService) GetAccountBalances(ctx context.Context) []models.AccountBalance {
+	log := requestid.Logger(ctx, s.logger)
+	log.Info("Refreshing balances for all Digiflazz accounts")
+	return s.digiflazzPool.RefreshBalances(ctx)
+}
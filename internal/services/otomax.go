@@ -1,48 +1,164 @@
 package services
 
 import (
-	"crypto/md5"
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"gateway-digiflazz/internal/config"
 	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+	"gateway-digiflazz/internal/validation"
 	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/metrics"
+	"gateway-digiflazz/pkg/operator"
+	"gateway-digiflazz/pkg/requestid"
 
 	"github.com/sirupsen/logrus"
 )
 
 // OtomaxService handles Otomax transaction operations
 type OtomaxService struct {
-	digiflazzClient *digiflazz.Client
+	digiflazzPool   *digiflazz.Pool
+	repository      repositories.OtomaxTransactionRepository
 	logger          *logrus.Logger
-	secretKey       string
+	fallbackChains  map[string]config.FallbackChainConfig
+	productResolver *operator.Resolver
+	validators      *validation.Registry
+	plnInquiryService *PLNInquiryService
+	signer          *OtomaxSigner
+	metrics         *metrics.Metrics
 }
 
 // NewOtomaxService creates a new Otomax service
-func NewOtomaxService(client *digiflazz.Client, logger *logrus.Logger, secretKey string) *OtomaxService {
+func NewOtomaxService(pool *digiflazz.Pool, repository repositories.OtomaxTransactionRepository, logger *logrus.Logger, secretKey string) *OtomaxService {
 	return &OtomaxService{
-		digiflazzClient: client,
+		digiflazzPool:   pool,
+		repository:      repository,
 		logger:          logger,
-		secretKey:       secretKey,
+		fallbackChains:  make(map[string]config.FallbackChainConfig),
+		productResolver: operator.NewResolver(nil),
+		validators:      validation.NewRegistry(config.ValidationConfig{}),
+		// Without resellers the config cannot be invalid
+		signer: mustOtomaxSigner(config.OtomaxConfig{SecretKey: secretKey}),
 	}
 }
 
+// SetSigner configures the per-reseller signing settings
+func (s *OtomaxService) SetSigner(signer *OtomaxSigner) {
+	s.signer = signer
+}
+
+// VerifyRequestSignature verifies the signature of a request from a reseller that
+// opted in to request signing; a *SignatureError describes a rejection
+func (s *OtomaxService) VerifyRequestSignature(ctx context.Context, req models.OtomaxRequestSignature) error {
+	log := requestid.Logger(ctx, s.logger)
+	if err := s.signer.VerifyRequest(req); err != nil {
+		log.WithFields(logrus.Fields{
+			"ref_id":      req.RefID,
+			"reseller_id": req.ResellerID,
+			"client":      req.ClientID,
+		}).WithError(err).Warn("Rejected Otomax request signature")
+		return err
+	}
+	return nil
+}
+
+// SetMetrics configures where transaction outcomes are recorded
+func (s *OtomaxService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// PendingTransactions returns the number of stored transactions still pending
+func (s *OtomaxService) PendingTransactions() int {
+	count, err := s.repository.CountByStatus("pending")
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to count pending Otomax transactions")
+		return 0
+	}
+	return count
+}
+
+// SetValidators configures the customer number validator registry
+func (s *OtomaxService) SetValidators(validators *validation.Registry) {
+	s.validators = validators
+}
+
+// SetPLNInquiryService configures the PLN inquiry service used to validate token purchases
+func (s *OtomaxService) SetPLNInquiryService(plnInquiryService *PLNInquiryService) {
+	s.plnInquiryService = plnInquiryService
+}
+
+// ValidateCustomerNo validates and normalises a customer number for the SKU and category
+func (s *OtomaxService) ValidateCustomerNo(buyerSKU, category, customerNo string) (string, error) {
+	return s.validators.ValidateCustomerNo(buyerSKU, category, customerNo)
+}
+
+// SetProductResolver configures the resolver for generic product codes
+func (s *OtomaxService) SetProductResolver(resolver *operator.Resolver) {
+	s.productResolver = resolver
+}
+
+// SetFallbackChains configures the fallback SKU chains used for prabayar transactions
+func (s *OtomaxService) SetFallbackChains(chains []config.FallbackChainConfig) {
+	s.fallbackChains = make(map[string]config.FallbackChainConfig, len(chains))
+	for _, chain := range chains {
+		s.fallbackChains[chain.SKU] = chain
+	}
+	s.logger.WithField("chains", len(chains)).Info("Fallback SKU chains configured")
+}
+
 // ProcessTransaction processes a transaction from Otomax
-func (s *OtomaxService) ProcessTransaction(req models.OtomaxTransactionRequest) (*models.OtomaxTransactionResponse, error) {
-	s.logger.WithFields(logrus.Fields{
+func (s *OtomaxService) ProcessTransaction(ctx context.Context, req models.OtomaxTransactionRequest) (*models.OtomaxTransactionResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithFields(logrus.Fields{
 		"ref_id":      req.RefID,
 		"customer_no": req.CustomerNo,
 		"buyer_sku":   req.BuyerSKU,
 		"type":        req.Type,
+		"client":      req.ClientID,
 	}).Info("Processing Otomax transaction")
 
-	// Note: Signature validation removed - Otomax requests do not require signature validation
+	// Request signatures are verified by the handler before the request reaches the service
+
+	// Resolve generic product codes (e.g. PULSA10) to the operator specific SKU
+	productCode := ""
+	if s.productResolver.IsGeneric(req.BuyerSKU) {
+		resolution, err := s.productResolver.Resolve(req.BuyerSKU, req.CustomerNo)
+		if err != nil {
+			log.WithError(err).WithField("buyer_sku", req.BuyerSKU).Error("Failed to resolve generic product code")
+			return nil, fmt.Errorf("failed to resolve product %s: %w", req.BuyerSKU, err)
+		}
+
+		log.WithFields(logrus.Fields{
+			"product_code": req.BuyerSKU,
+			"buyer_sku":    resolution.BuyerSKU,
+			"operator":     resolution.Operator.Code,
+		}).Info("Generic product code resolved")
+
+		productCode = req.BuyerSKU
+		req.BuyerSKU = resolution.BuyerSKU
+		req.CustomerNo = resolution.CustomerNo
+		if req.Category == "" {
+			req.Category = resolution.Category
+		}
+	}
+
+	// Validate customer number for the product category or brand
+	customerNo, err := s.validators.ValidateCustomerNo(req.BuyerSKU, req.Category, req.CustomerNo)
+	if err != nil {
+		log.WithError(err).WithField("ref_id", req.RefID).Warn("Otomax transaction validation failed")
+		return nil, err
+	}
+	req.CustomerNo = customerNo
 
 	// Parse amount
 	amount, err := strconv.ParseFloat(req.Amount, 64)
 	if err != nil {
-		s.logger.WithError(err).Error("Invalid amount format")
+		log.WithError(err).Error("Invalid amount format")
 		return nil, fmt.Errorf("invalid amount format")
 	}
 
@@ -51,8 +167,14 @@ func (s *OtomaxService) ProcessTransaction(req models.OtomaxTransactionRequest)
 		RefID:      req.RefID,
 		CustomerNo: req.CustomerNo,
 		BuyerSKU:   req.BuyerSKU,
+		ProductCode: productCode,
 		Amount:     amount,
 		Type:       req.Type,
+		ResellerID: req.ResellerID,
+		ClientID:   req.ClientID,
+		Category:   req.Category,
+		Account:    s.digiflazzPool.Route(req.ResellerID, req.Category),
+		RequestID:  requestid.FromContext(ctx),
 		Status:     "pending",
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
@@ -61,82 +183,214 @@ func (s *OtomaxService) ProcessTransaction(req models.OtomaxTransactionRequest)
 	// Process based on transaction type
 	var response *models.OtomaxTransactionResponse
 	if req.Type == "prabayar" {
-		response, err = s.processPrabayarTransaction(transaction)
+		response, err = s.processPrabayarTransaction(ctx, transaction)
 	} else if req.Type == "pascabayar" {
-		response, err = s.processPascabayarTransaction(transaction)
+		response, err = s.processPascabayarTransaction(ctx, transaction)
 	} else {
 		return nil, fmt.Errorf("invalid transaction type: %s", req.Type)
 	}
 
 	if err != nil {
-		s.logger.WithError(err).Error("Transaction processing failed")
+		log.WithError(err).Error("Transaction processing failed")
 		transaction.Status = "failed"
 		transaction.Message = err.Error()
-		// TODO: Update transaction in database
+		s.saveTransaction(ctx, transaction)
+		s.metrics.ObserveTransaction(transaction.BuyerSKU, transaction.Category, transaction.Status)
 		return nil, err
 	}
 
-	// TODO: Save transaction to database
-	s.logger.WithField("ref_id", req.RefID).Info("Otomax transaction processed successfully")
+	s.signTransactionResponse(transaction, response)
+	s.saveTransaction(ctx, transaction)
+	s.metrics.ObserveTransaction(transaction.BuyerSKU, transaction.Category, response.Status)
+	log.WithField("ref_id", req.RefID).Info("Otomax transaction processed successfully")
 	return response, nil
 }
 
 // CheckStatus checks the status of an Otomax transaction
-func (s *OtomaxService) CheckStatus(req models.OtomaxStatusRequest) (*models.OtomaxStatusResponse, error) {
-	s.logger.WithField("ref_id", req.RefID).Info("Checking Otomax transaction status")
+func (s *OtomaxService) CheckStatus(ctx context.Context, req models.OtomaxStatusRequest) (*models.OtomaxStatusResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithField("ref_id", req.RefID).Info("Checking Otomax transaction status")
 
-	// Note: Signature validation removed - Otomax requests do not require signature validation
+	// Status checks only read data, so they are not covered by request signing
+
+	// Only statuses the gateway knows are returned and signed. An unknown ref_id is not
+	// reported as success: resellers verifying the signature would trust it.
+	transaction, err := s.repository.GetByRefID(req.RefID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTransactionNotFound) {
+			log.WithField("ref_id", req.RefID).Warn("Otomax status requested for unknown transaction")
+		}
+		return nil, err
+	}
+	if transaction.Status == "pending" {
+		s.refreshPendingStatus(ctx, transaction)
+	}
 
-	// TODO: Get transaction from database
-	// For now, return a mock response
 	response := &models.OtomaxStatusResponse{
-		RefID:     req.RefID,
-		Status:    "success",
-		Message:   "Transaction completed",
-		RC:        "00",
-		Timestamp: time.Now().Format(time.RFC3339),
-		Sign:      s.generateResponseSignature(req.RefID, "success"),
+		RefID:      transaction.RefID,
+		CustomerNo: transaction.CustomerNo,
+		BuyerSKU:   transaction.BuyerSKU,
+		Amount:     transaction.Amount,
+		Status:     transaction.Status,
+		Message:    transaction.Message,
+		RC:         transaction.RC,
+		SN:         transaction.SN,
+		PLNToken:   transaction.PLNToken,
+		TransactionRequestID: transaction.RequestID,
+		Timestamp:  time.Now().Format(time.RFC3339),
 	}
+	response.Sign = s.signStatusResponse(transaction.ResellerID, transaction.ClientID, response)
 
-	s.logger.WithField("ref_id", req.RefID).Info("Otomax transaction status retrieved")
+	log.WithField("ref_id", req.RefID).Info("Otomax transaction status retrieved")
 	return response, nil
 }
 
 // ProcessCallback processes callback from Digiflazz for Otomax transactions
-func (s *OtomaxService) ProcessCallback(callback models.OtomaxCallback) error {
-	s.logger.WithFields(logrus.Fields{
+func (s *OtomaxService) ProcessCallback(ctx context.Context, callback models.OtomaxCallback) error {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithFields(logrus.Fields{
 		"ref_id": callback.RefID,
 		"status": callback.Status,
 		"rc":     callback.RC,
 	}).Info("Processing Otomax callback")
 
 	// Validate callback signature
-	if !s.validateCallbackSignature(callback) {
-		s.logger.Error("Invalid callback signature")
+	if !s.signer.VerifyCallback(callback) {
+		log.Error("Invalid callback signature")
 		return fmt.Errorf("invalid callback signature")
 	}
 
-	// TODO: Update transaction status in database
+	transaction, err := s.repository.GetByRefID(callback.RefID)
+	if err != nil {
+		// Acknowledge callbacks for transactions this instance does not know so they are not retried forever
+		log.WithError(err).WithField("ref_id", callback.RefID).Warn("Callback for unknown Otomax transaction")
+		return nil
+	}
+	s.applyStatusUpdate(ctx, transaction, callback.Status, callback.RC, callback.Message, callback.SN)
+
 	// TODO: Notify Otomax about status update
-	// TODO: Send notification to user
 
-	s.logger.WithField("ref_id", callback.RefID).Info("Otomax callback processed successfully")
+	log.WithField("ref_id", callback.RefID).Info("Otomax callback processed successfully")
 	return nil
 }
 
-// processPrabayarTransaction processes a prabayar transaction
-func (s *OtomaxService) processPrabayarTransaction(transaction *models.OtomaxTransaction) (*models.OtomaxTransactionResponse, error) {
-	// Create Digiflazz topup request
-	digiflazzReq := models.TopupRequest{
-		RefID:      transaction.RefID,
-		CustomerNo: transaction.CustomerNo,
-		BuyerSKU:   transaction.BuyerSKU,
+// refreshPendingStatus asks Digiflazz for the current status of a pending transaction.
+// Failures keep the stored status.
+func (s *OtomaxService) refreshPendingStatus(ctx context.Context, transaction *models.OtomaxTransaction) {
+	log := requestid.Logger(ctx, s.logger)
+	client, ok := s.digiflazzPool.Client(transaction.Account)
+	if !ok {
+		return
+	}
+	// Fallback attempts run under sub-ref_ids; Digiflazz knows the one that completed
+	refID := transaction.DigiflazzRefID
+	if refID == "" {
+		refID = transaction.RefID
 	}
 
-	// Call Digiflazz API
-	digiflazzResp, err := s.digiflazzClient.Topup(digiflazzReq)
+	resp, err := client.CheckStatus(ctx, refID)
 	if err != nil {
-		return nil, fmt.Errorf("digiflazz topup failed: %w", err)
+		log.WithError(err).WithField("ref_id", transaction.RefID).Warn("Failed to refresh pending Otomax transaction")
+		return
+	}
+	if resp.Data.Status == "" {
+		return
+	}
+	s.applyStatusUpdate(ctx, transaction, resp.Data.Status, resp.Data.RC, resp.Data.Message, resp.Data.SN)
+}
+
+// applyStatusUpdate records a later Digiflazz status of a transaction, from a callback
+// or a status check, and parses the PLN token once the purchase succeeds. Only pending
+// transactions move; a final success or failure is never overwritten.
+func (s *OtomaxService) applyStatusUpdate(ctx context.Context, transaction *models.OtomaxTransaction, status, rc, message, sn string) {
+	if transaction.Status != "pending" {
+		requestid.Logger(ctx, s.logger).WithFields(logrus.Fields{
+			"ref_id":     transaction.RefID,
+			"status":     transaction.Status,
+			"new_status": status,
+		}).Warn("Ignoring status update for a final Otomax transaction")
+		return
+	}
+
+	transaction.Status = mapDigiflazzStatus(status)
+	transaction.RC = rc
+	transaction.Message = message
+	if sn != "" {
+		transaction.SN = sn
+	}
+	s.attachPLNToken(ctx, transaction, "")
+	s.saveTransaction(ctx, transaction)
+
+	requestid.Logger(ctx, s.logger).WithFields(logrus.Fields{
+		"ref_id": transaction.RefID,
+		"status": transaction.Status,
+		"rc":     transaction.RC,
+	}).Info("Otomax transaction status updated")
+}
+
+// processPrabayarTransaction processes a prabayar transaction, walking the
+// fallback SKU chain when the seller side fails
+func (s *OtomaxService) processPrabayarTransaction(ctx context.Context, transaction *models.OtomaxTransaction) (*models.OtomaxTransactionResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	chain := s.fallbackChain(transaction.BuyerSKU)
+
+	var digiflazzResp *models.TopupResponse
+	for i, buyerSKU := range chain.skus {
+		// The first attempt keeps the Otomax ref_id, fallbacks get fresh sub-ref_ids
+		refID := transaction.RefID
+		if i > 0 {
+			refID = fmt.Sprintf("%s-%d", transaction.RefID, i)
+		}
+
+		digiflazzReq := models.TopupRequest{
+			RefID:      refID,
+			CustomerNo: transaction.CustomerNo,
+			BuyerSKU:   buyerSKU,
+			MaxPrice:   chain.maxPrice,
+		}
+
+		// Call Digiflazz API on the routed account, failing over on insufficient balance
+		resp, account, err := s.digiflazzPool.Topup(ctx, transaction.Account, digiflazzReq)
+		attempt := models.OtomaxTransactionAttempt{
+			RefID:       refID,
+			BuyerSKU:    buyerSKU,
+			Account:     account,
+			AttemptedAt: time.Now(),
+		}
+		if err != nil {
+			attempt.Status = "failed"
+			attempt.RC = digiflazz.RCFromError(err)
+			attempt.Message = err.Error()
+		} else {
+			attempt.Status = mapDigiflazzStatus(resp.Data.Status)
+			attempt.RC = resp.Data.RC
+			attempt.Message = resp.Data.Message
+			attempt.SN = resp.Data.SN
+			attempt.Price = resp.Data.Price
+		}
+		transaction.Attempts = append(transaction.Attempts, attempt)
+		transaction.Account = account
+
+		log.WithFields(logrus.Fields{
+			"ref_id":     transaction.RefID,
+			"attempt":    i + 1,
+			"sub_ref_id": refID,
+			"buyer_sku":  buyerSKU,
+			"account":    account,
+			"status":     attempt.Status,
+			"rc":         attempt.RC,
+		}).Info("Prabayar attempt completed")
+
+		hasNext := i < len(chain.skus)-1
+		if !hasNext || !s.shouldFallback(attempt) {
+			if err != nil {
+				return nil, fmt.Errorf("digiflazz topup failed: %w", err)
+			}
+			digiflazzResp = resp
+			// Report the SKU that was bought; the requested one stays on the first attempt
+			transaction.BuyerSKU = buyerSKU
+			break
+		}
 	}
 
 	// Create response
@@ -145,12 +399,11 @@ func (s *OtomaxService) processPrabayarTransaction(transaction *models.OtomaxTra
 		CustomerNo: transaction.CustomerNo,
 		BuyerSKU:   transaction.BuyerSKU,
 		Amount:     transaction.Amount,
-		Status:     s.mapDigiflazzStatus(digiflazzResp.Data.Status),
+		Status:     mapDigiflazzStatus(digiflazzResp.Data.Status),
 		Message:    digiflazzResp.Data.Message,
 		RC:         digiflazzResp.Data.RC,
 		SN:         digiflazzResp.Data.SN,
 		Timestamp:  time.Now().Format(time.RFC3339),
-		Sign:       s.generateResponseSignature(transaction.RefID, s.mapDigiflazzStatus(digiflazzResp.Data.Status)),
 	}
 
 	// Update transaction status
@@ -163,10 +416,54 @@ func (s *OtomaxService) processPrabayarTransaction(transaction *models.OtomaxTra
 	return response, nil
 }
 
+// fallbackChainSKUs holds the resolved SKUs and price ceiling for a transaction
+type fallbackChainSKUs struct {
+	skus     []string
+	maxPrice float64
+}
+
+// fallbackChain returns the SKUs to try for buyerSKU, starting with buyerSKU itself
+func (s *OtomaxService) fallbackChain(buyerSKU string) fallbackChainSKUs {
+	chain, ok := s.fallbackChains[buyerSKU]
+	if !ok {
+		return fallbackChainSKUs{skus: []string{buyerSKU}}
+	}
+
+	skus := append([]string{buyerSKU}, chain.Fallbacks...)
+	return fallbackChainSKUs{skus: skus, maxPrice: chain.MaxPrice}
+}
+
+// shouldFallback reports whether a failed attempt may be retried with the next SKU.
+// Only definitive seller-side failures fall back; successes, pending transactions,
+// customer errors and price ceiling rejections stop the chain.
+func (s *OtomaxService) shouldFallback(attempt models.OtomaxTransactionAttempt) bool {
+	if attempt.Status != "failed" {
+		return false
+	}
+	if digiflazz.IsCustomerError(attempt.RC) || attempt.RC == digiflazz.RCSellerPriceTooHigh {
+		return false
+	}
+	return digiflazz.IsSellerError(attempt.RC)
+}
+
+// saveTransaction persists the transaction, logging failures
+func (s *OtomaxService) saveTransaction(ctx context.Context, transaction *models.OtomaxTransaction) {
+	log := requestid.Logger(ctx, s.logger)
+	transaction.UpdatedAt = time.Now()
+	if err := s.repository.Save(transaction); err != nil {
+		log.WithError(err).WithField("ref_id", transaction.RefID).Error("Failed to save Otomax transaction")
+	}
+}
+
 // processPascabayarTransaction processes a pascabayar transaction
-func (s *OtomaxService) processPascabayarTransaction(transaction *models.OtomaxTransaction) (*models.OtomaxTransactionResponse, error) {
+func (s *OtomaxService) processPascabayarTransaction(ctx context.Context, transaction *models.OtomaxTransaction) (*models.OtomaxTransactionResponse, error) {
 	// For Pascabayar, we need to check the bill first
 	// This is a two-step process: Check -> Pay
+	// Both steps must use the same account, so no failover is applied
+	client, ok := s.digiflazzPool.Client(transaction.Account)
+	if !ok {
+		return nil, fmt.Errorf("unknown digiflazz account: %s", transaction.Account)
+	}
 	
 	// Step 1: Check the bill
 	checkReq := models.PascabayarCheckRequest{
@@ -175,13 +472,17 @@ func (s *OtomaxService) processPascabayarTransaction(transaction *models.OtomaxT
 		BuyerSKU:   transaction.BuyerSKU,
 	}
 
-	checkResp, err := s.digiflazzClient.CheckPascabayarBill(checkReq)
+	checkResp, err := client.CheckPascabayarBill(ctx, checkReq)
 	if err != nil {
 		return nil, fmt.Errorf("digiflazz bill check failed: %w", err)
 	}
 
 	// If check failed, return error
 	if checkResp.Data.RC != "00" {
+		// Record the outcome so the stored transaction is not left pending
+		transaction.Status = "failed"
+		transaction.Message = checkResp.Data.Message
+		transaction.RC = checkResp.Data.RC
 		return &models.OtomaxTransactionResponse{
 			RefID:      transaction.RefID,
 			CustomerNo: transaction.CustomerNo,
@@ -191,7 +492,6 @@ func (s *OtomaxService) processPascabayarTransaction(transaction *models.OtomaxT
 			Message:    checkResp.Data.Message,
 			RC:         checkResp.Data.RC,
 			Timestamp:  time.Now().Format(time.RFC3339),
-			Sign:       s.generateResponseSignature(transaction.RefID, "failed"),
 		}, nil
 	}
 
@@ -203,7 +503,7 @@ func (s *OtomaxService) processPascabayarTransaction(transaction *models.OtomaxT
 		Amount:     checkResp.Data.Amount, // Use amount from check response
 	}
 
-	payResp, err := s.digiflazzClient.PayPascabayarBill(payReq)
+	payResp, err := client.PayPascabayarBill(ctx, payReq)
 	if err != nil {
 		return nil, fmt.Errorf("digiflazz bill payment failed: %w", err)
 	}
@@ -214,11 +514,10 @@ func (s *OtomaxService) processPascabayarTransaction(transaction *models.OtomaxT
 		CustomerNo: transaction.CustomerNo,
 		BuyerSKU:   transaction.BuyerSKU,
 		Amount:     payResp.Data.Amount,
-		Status:     s.mapDigiflazzStatus(payResp.Data.Status),
+		Status:     mapDigiflazzStatus(payResp.Data.Status),
 		Message:    payResp.Data.Message,
 		RC:         payResp.Data.RC,
 		Timestamp:  time.Now().Format(time.RFC3339),
-		Sign:       s.generateResponseSignature(transaction.RefID, s.mapDigiflazzStatus(payResp.Data.Status)),
 	}
 
 	// Update transaction status
@@ -230,47 +529,70 @@ func (s *OtomaxService) processPascabayarTransaction(transaction *models.OtomaxT
 	return response, nil
 }
 
-// Note: Signature validation removed - Otomax requests do not require signature validation
-// The gateway handles all Digiflazz API signatures internally
-
-// validateCallbackSignature validates the callback signature
-func (s *OtomaxService) validateCallbackSignature(callback models.OtomaxCallback) bool {
-	expectedSign := s.generateCallbackSignature(callback.RefID, callback.Status)
-	return callback.Sign == expectedSign
-}
-
-// generateRequestSignature generates signature for transaction request
-func (s *OtomaxService) generateRequestSignature(refID, customerNo, buyerSKU string) string {
-	data := fmt.Sprintf("%s%s%s%s", refID, customerNo, buyerSKU, s.secretKey)
-	return fmt.Sprintf("%x", md5.Sum([]byte(data)))
+// SignResponse signs a response built outside the service for a reseller
+func (s *OtomaxService) SignResponse(resellerID, clientID string, resp models.OtomaxResponseSignature) string {
+	return s.signer.SignResponse(resellerID, clientID, resp)
 }
 
-// generateStatusSignature generates signature for status request
-func (s *OtomaxService) generateStatusSignature(refID string) string {
-	data := fmt.Sprintf("%s%s", refID, s.secretKey)
-	return fmt.Sprintf("%x", md5.Sum([]byte(data)))
+// CheckSignature checks a reseller's request or response signature against the gateway's
+func (s *OtomaxService) CheckSignature(req models.OtomaxSignatureCheckRequest, clientID string) models.OtomaxSignatureCheck {
+	if req.Type == "request" {
+		return s.signer.CheckRequest(models.OtomaxRequestSignature{
+			ResellerID: req.ResellerID,
+			ClientID:   clientID,
+			RefID:      req.RefID,
+			CustomerNo: req.CustomerNo,
+			BuyerSKU:   req.BuyerSKU,
+			Timestamp:  req.Timestamp,
+			Nonce:      req.Nonce,
+			Sign:       req.Sign,
+		})
+	}
+	return s.signer.CheckResponse(req.ResellerID, clientID, req.Sign, models.OtomaxResponseSignature{
+		RefID:      req.RefID,
+		CustomerNo: req.CustomerNo,
+		BuyerSKU:   req.BuyerSKU,
+		Status:     req.Status,
+		RC:         req.RC,
+		SN:         req.SN,
+		Timestamp:  req.Timestamp,
+	})
 }
 
-// generateResponseSignature generates signature for response
-func (s *OtomaxService) generateResponseSignature(refID, status string) string {
-	data := fmt.Sprintf("%s%s%s", refID, status, s.secretKey)
-	return fmt.Sprintf("%x", md5.Sum([]byte(data)))
+// signTransactionResponse signs a transaction response for the reseller of transaction
+func (s *OtomaxService) signTransactionResponse(transaction *models.OtomaxTransaction, resp *models.OtomaxTransactionResponse) {
+	resp.Sign = s.signer.SignResponse(transaction.ResellerID, transaction.ClientID, models.OtomaxResponseSignature{
+		RefID:      resp.RefID,
+		CustomerNo: resp.CustomerNo,
+		BuyerSKU:   resp.BuyerSKU,
+		Status:     resp.Status,
+		RC:         resp.RC,
+		SN:         resp.SN,
+		Timestamp:  resp.Timestamp,
+	})
 }
 
-// generateCallbackSignature generates signature for callback
-func (s *OtomaxService) generateCallbackSignature(refID, status string) string {
-	data := fmt.Sprintf("%s%s%s", refID, status, s.secretKey)
-	return fmt.Sprintf("%x", md5.Sum([]byte(data)))
+// signStatusResponse signs a status response for a reseller
+func (s *OtomaxService) signStatusResponse(resellerID, clientID string, resp *models.OtomaxStatusResponse) string {
+	return s.signer.SignResponse(resellerID, clientID, models.OtomaxResponseSignature{
+		RefID:      resp.RefID,
+		CustomerNo: resp.CustomerNo,
+		BuyerSKU:   resp.BuyerSKU,
+		Status:     resp.Status,
+		RC:         resp.RC,
+		SN:         resp.SN,
+		Timestamp:  resp.Timestamp,
+	})
 }
 
 // mapDigiflazzStatus maps Digiflazz status to Otomax status
-func (s *OtomaxService) mapDigiflazzStatus(digiflazzStatus string) string {
-	switch digiflazzStatus {
-	case "success":
+func mapDigiflazzStatus(digiflazzStatus string) string {
+	switch strings.ToLower(digiflazzStatus) {
+	case "success", "sukses":
 		return "success"
 	case "pending":
 		return "pending"
-	case "failed":
+	case "failed", "gagal":
 		return "failed"
 	default:
 		return "failed"
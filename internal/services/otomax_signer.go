@@ -0,0 +1,405 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/models"
+)
+
+// Otomax signature algorithms
+const (
+	SignatureMD5        = "md5"
+	SignatureHMACSHA256 = "hmac-sha256"
+)
+
+// DefaultOtomaxSecretKey is the well-known gateway secret used when none is configured
+const DefaultOtomaxSecretKey = "default-secret-key"
+
+// placeholderSecretKeys are published gateway secrets from the defaults and samples;
+// signatures made with them can be forged by anyone
+var placeholderSecretKeys = map[string]bool{
+	DefaultOtomaxSecretKey:   true,
+	"your_otomax_secret_key": true,
+	"your_secret_key_here":   true,
+	"your_secret_key":        true,
+}
+
+// IsPlaceholderSecretKey reports whether key is unset or a published placeholder
+func IsPlaceholderSecretKey(key string) bool {
+	return key == "" || placeholderSecretKeys[key]
+}
+
+// DefaultOtomaxMaxClockSkew bounds the timestamp of signed requests when none is configured
+const DefaultOtomaxMaxClockSkew = 5 * time.Minute
+
+// Signature error codes returned to the reseller
+const (
+	SignatureErrMissing  = "MISSING_SIGNATURE"
+	SignatureErrInvalid  = "INVALID_SIGNATURE"
+	SignatureErrExpired  = "TIMESTAMP_EXPIRED"
+	SignatureErrReplayed = "REPLAYED_REQUEST"
+	// SignatureErrUnknownReseller rejects a reseller_id that is not configured, or that
+	// belongs to another API key, while request signing is in use
+	SignatureErrUnknownReseller = "UNKNOWN_RESELLER"
+)
+
+// SignatureError reports why a signed request was rejected
+type SignatureError struct {
+	Code    string
+	Message string
+}
+
+func (e *SignatureError) Error() string {
+	return e.Message
+}
+
+// AsSignatureError returns the SignatureError wrapped in err, if any
+func AsSignatureError(err error) (*SignatureError, bool) {
+	var sigErr *SignatureError
+	if errors.As(err, &sigErr) {
+		return sigErr, true
+	}
+	return nil, false
+}
+
+// OtomaxSigner signs and verifies Otomax messages with per-reseller secrets
+type OtomaxSigner struct {
+	secretKey string
+	// responseSigning is the default response and callback algorithm
+	responseSigning string
+	resellers       map[string]config.OtomaxResellerConfig
+	clients         map[string]string
+	// signingEnabled is set when any reseller opted in to request signing
+	signingEnabled bool
+	maxSkew        time.Duration
+	nonces         *nonceCache
+	now            func() time.Time
+}
+
+// NewOtomaxSigner validates the reseller signing settings
+func NewOtomaxSigner(cfg config.OtomaxConfig) (*OtomaxSigner, error) {
+	signer := &OtomaxSigner{
+		secretKey:       cfg.SecretKey,
+		responseSigning: strings.ToLower(strings.TrimSpace(cfg.ResponseSigning)),
+		resellers:       make(map[string]config.OtomaxResellerConfig),
+		clients:         make(map[string]string),
+		maxSkew:         cfg.MaxClockSkew,
+		nonces:          newNonceCache(),
+		now:             time.Now,
+	}
+	if signer.maxSkew <= 0 {
+		signer.maxSkew = DefaultOtomaxMaxClockSkew
+	}
+	// MD5 over ref_id and status stays the default so existing resellers keep verifying
+	if signer.responseSigning == "" {
+		signer.responseSigning = SignatureMD5
+	}
+	if !validSignatureAlgorithm(signer.responseSigning) {
+		return nil, fmt.Errorf("unknown otomax response signing %q (expected md5 or hmac-sha256)", cfg.ResponseSigning)
+	}
+
+	for _, reseller := range cfg.Resellers {
+		if reseller.ID == "" {
+			return nil, fmt.Errorf("otomax reseller id is required")
+		}
+		if _, ok := signer.resellers[reseller.ID]; ok {
+			return nil, fmt.Errorf("duplicate otomax reseller %q", reseller.ID)
+		}
+		reseller.RequestSigning = strings.ToLower(strings.TrimSpace(reseller.RequestSigning))
+		if reseller.RequestSigning != "" && !validSignatureAlgorithm(reseller.RequestSigning) {
+			return nil, fmt.Errorf("otomax reseller %q: unknown request signing %q (expected md5 or hmac-sha256)", reseller.ID, reseller.RequestSigning)
+		}
+		reseller.ResponseSigning = strings.ToLower(strings.TrimSpace(reseller.ResponseSigning))
+		if reseller.ResponseSigning != "" && !validSignatureAlgorithm(reseller.ResponseSigning) {
+			return nil, fmt.Errorf("otomax reseller %q: unknown response signing %q (expected md5 or hmac-sha256)", reseller.ID, reseller.ResponseSigning)
+		}
+		if reseller.RequestSigning != "" && reseller.Secret == "" && IsPlaceholderSecretKey(cfg.SecretKey) {
+			return nil, fmt.Errorf("otomax reseller %q: request signing requires its own secret or a non-default OTOMAX_SECRET_KEY", reseller.ID)
+		}
+		if reseller.RequestSigning != "" {
+			signer.signingEnabled = true
+		}
+		if reseller.Client != "" {
+			if other, ok := signer.clients[reseller.Client]; ok {
+				return nil, fmt.Errorf("otomax resellers %q and %q share client %q", other, reseller.ID, reseller.Client)
+			}
+			signer.clients[reseller.Client] = reseller.ID
+		}
+		signer.resellers[reseller.ID] = reseller
+	}
+	return signer, nil
+}
+
+// validSignatureAlgorithm reports whether algorithm is supported
+func validSignatureAlgorithm(algorithm string) bool {
+	return algorithm == SignatureMD5 || algorithm == SignatureHMACSHA256
+}
+
+// mustOtomaxSigner creates a signer from a config known to be valid
+func mustOtomaxSigner(cfg config.OtomaxConfig) *OtomaxSigner {
+	signer, err := NewOtomaxSigner(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return signer
+}
+
+// reseller returns the settings of the reseller sending a request. The authenticated
+// API client takes precedence over the reseller_id it sends.
+func (s *OtomaxSigner) reseller(resellerID, clientID string) (config.OtomaxResellerConfig, bool) {
+	if id, ok := s.clients[clientID]; ok && clientID != "" {
+		return s.resellers[id], true
+	}
+	reseller, ok := s.resellers[resellerID]
+	return reseller, ok
+}
+
+// secret returns the secret of a reseller, falling back to the gateway secret key
+func (s *OtomaxSigner) secret(reseller config.OtomaxResellerConfig) string {
+	if reseller.Secret != "" {
+		return reseller.Secret
+	}
+	return s.secretKey
+}
+
+// canonicalRequest returns the signed string of a request, without the secret:
+//
+//	md5:         ref_id + customer_no + buyer_sku + timestamp + nonce
+//	hmac-sha256: ref_id|customer_no|buyer_sku|timestamp|nonce
+func canonicalRequest(algorithm string, req models.OtomaxRequestSignature) string {
+	fields := []string{req.RefID, req.CustomerNo, req.BuyerSKU, req.Timestamp, req.Nonce}
+	if algorithm == SignatureMD5 {
+		return strings.Join(fields, "")
+	}
+	return strings.Join(fields, "|")
+}
+
+// canonicalResponse returns the signed string of a response or callback, without the secret.
+// The legacy MD5 scheme only covers the ref_id and status:
+//
+//	md5:         ref_id + status
+//	hmac-sha256: ref_id|customer_no|buyer_sku|status|rc|sn|timestamp
+func canonicalResponse(algorithm string, resp models.OtomaxResponseSignature) string {
+	if algorithm == SignatureMD5 {
+		return resp.RefID + resp.Status
+	}
+	return strings.Join([]string{resp.RefID, resp.CustomerNo, resp.BuyerSKU, resp.Status, resp.RC, resp.SN, resp.Timestamp}, "|")
+}
+
+// sign signs a canonical string: MD5 appends the secret, HMAC-SHA256 keys the MAC with it
+func sign(algorithm, secret, canonical string) (string, error) {
+	switch algorithm {
+	case SignatureMD5:
+		return fmt.Sprintf("%x", md5.Sum([]byte(canonical+secret))), nil
+	case SignatureHMACSHA256:
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(canonical))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	default:
+		return "", fmt.Errorf("unknown signature algorithm %q", algorithm)
+	}
+}
+
+// RequestSignature computes the signature of a request:
+//
+//	md5:         hex(MD5(ref_id + customer_no + buyer_sku + timestamp + nonce + secret))
+//	hmac-sha256: hex(HMAC-SHA256(secret, ref_id|customer_no|buyer_sku|timestamp|nonce))
+func RequestSignature(algorithm, secret string, req models.OtomaxRequestSignature) (string, error) {
+	return sign(algorithm, secret, canonicalRequest(algorithm, req))
+}
+
+// ResponseSignature computes the signature of a response or callback:
+//
+//	md5:         hex(MD5(ref_id + status + secret))
+//	hmac-sha256: hex(HMAC-SHA256(secret, ref_id|customer_no|buyer_sku|status|rc|sn|timestamp))
+func ResponseSignature(algorithm, secret string, resp models.OtomaxResponseSignature) (string, error) {
+	return sign(algorithm, secret, canonicalResponse(algorithm, resp))
+}
+
+// VerifyResponseSignature reports whether signature is the signature of resp. Resellers
+// can run it against gateway responses to check their own verification.
+func VerifyResponseSignature(algorithm, secret, signature string, resp models.OtomaxResponseSignature) bool {
+	expected, err := ResponseSignature(algorithm, secret, resp)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// responseAlgorithm returns the response signing algorithm of a reseller
+func (s *OtomaxSigner) responseAlgorithm(reseller config.OtomaxResellerConfig) string {
+	if reseller.ResponseSigning != "" {
+		return reseller.ResponseSigning
+	}
+	return s.responseSigning
+}
+
+// SignResponse signs a response to a reseller with its secret and response algorithm
+func (s *OtomaxSigner) SignResponse(resellerID, clientID string, resp models.OtomaxResponseSignature) string {
+	reseller, _ := s.reseller(resellerID, clientID)
+	// The algorithm was validated by NewOtomaxSigner
+	signature, _ := ResponseSignature(s.responseAlgorithm(reseller), s.secret(reseller), resp)
+	return signature
+}
+
+// VerifyCallback checks the signature of a callback, signed with the gateway secret key.
+// Callbacks are always rejected while the secret key is a published placeholder, since
+// anyone could sign them.
+func (s *OtomaxSigner) VerifyCallback(callback models.OtomaxCallback) bool {
+	if IsPlaceholderSecretKey(s.secretKey) {
+		return false
+	}
+	return VerifyResponseSignature(s.responseSigning, s.secretKey, callback.Sign, models.OtomaxResponseSignature{
+		RefID:      callback.RefID,
+		CustomerNo: callback.CustomerNo,
+		BuyerSKU:   callback.BuyerSKU,
+		Status:     callback.Status,
+		RC:         callback.RC,
+		SN:         callback.SN,
+		Timestamp:  callback.Timestamp,
+	})
+}
+
+// CheckRequest compares a request signature with the gateway's without consuming the
+// nonce or checking the timestamp, so resellers can test their signing. Resellers that
+// have not opted in are checked against hmac-sha256.
+func (s *OtomaxSigner) CheckRequest(req models.OtomaxRequestSignature) models.OtomaxSignatureCheck {
+	reseller, _ := s.reseller(req.ResellerID, req.ClientID)
+	algorithm := reseller.RequestSigning
+	if algorithm == "" {
+		algorithm = SignatureHMACSHA256
+	}
+	expected, _ := RequestSignature(algorithm, s.secret(reseller), req)
+	return models.OtomaxSignatureCheck{
+		Valid:     hmac.Equal([]byte(expected), []byte(strings.ToLower(req.Sign))),
+		Algorithm: algorithm,
+		Canonical: describeCanonical(algorithm, canonicalRequest(algorithm, req)),
+	}
+}
+
+// CheckResponse compares a response signature with the gateway's for a reseller
+func (s *OtomaxSigner) CheckResponse(resellerID, clientID, signature string, resp models.OtomaxResponseSignature) models.OtomaxSignatureCheck {
+	reseller, _ := s.reseller(resellerID, clientID)
+	algorithm := s.responseAlgorithm(reseller)
+	return models.OtomaxSignatureCheck{
+		Valid:     VerifyResponseSignature(algorithm, s.secret(reseller), signature, resp),
+		Algorithm: algorithm,
+		Canonical: describeCanonical(algorithm, canonicalResponse(algorithm, resp)),
+	}
+}
+
+// describeCanonical shows where the secret goes without revealing it
+func describeCanonical(algorithm, canonical string) string {
+	if algorithm == SignatureMD5 {
+		return canonical + "{secret}"
+	}
+	return canonical
+}
+
+// VerifyRequest checks the signature, timestamp and nonce of a request from a reseller
+// that opted in to request signing. Requests of other configured resellers pass unchanged.
+// Once any reseller signs, an unknown reseller_id or one bound to another API key is
+// rejected, so dropping sign and swapping reseller_id cannot bypass signing.
+func (s *OtomaxSigner) VerifyRequest(req models.OtomaxRequestSignature) error {
+	reseller, ok := s.reseller(req.ResellerID, req.ClientID)
+	if !ok {
+		if s.signingEnabled {
+			return &SignatureError{Code: SignatureErrUnknownReseller, Message: fmt.Sprintf("reseller %q is not configured", req.ResellerID)}
+		}
+		return nil
+	}
+	if reseller.Client != "" && reseller.Client != req.ClientID {
+		return &SignatureError{Code: SignatureErrUnknownReseller, Message: fmt.Sprintf("reseller %q belongs to another API key", reseller.ID)}
+	}
+	if reseller.RequestSigning == "" {
+		return nil
+	}
+
+	if req.Sign == "" || req.Timestamp == "" {
+		return &SignatureError{Code: SignatureErrMissing, Message: "sign and timestamp are required"}
+	}
+	// Nonces are optional for the legacy MD5 scheme; the signature itself is then the nonce
+	if reseller.RequestSigning == SignatureHMACSHA256 && req.Nonce == "" {
+		return &SignatureError{Code: SignatureErrMissing, Message: "nonce is required"}
+	}
+
+	timestamp, err := parseRequestTimestamp(req.Timestamp)
+	if err != nil {
+		return &SignatureError{Code: SignatureErrExpired, Message: err.Error()}
+	}
+	now := s.now()
+	if skew := now.Sub(timestamp); skew > s.maxSkew || skew < -s.maxSkew {
+		return &SignatureError{Code: SignatureErrExpired, Message: fmt.Sprintf("timestamp is outside the allowed clock skew of %s", s.maxSkew)}
+	}
+
+	expected, err := RequestSignature(reseller.RequestSigning, s.secret(reseller), req)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(req.Sign))) {
+		return &SignatureError{Code: SignatureErrInvalid, Message: "signature does not match"}
+	}
+
+	nonce := req.Nonce
+	if nonce == "" {
+		nonce = expected
+	}
+	// A timestamp within the skew either side of now stays valid for twice the skew
+	if !s.nonces.add(reseller.ID+":"+nonce, now, 2*s.maxSkew) {
+		return &SignatureError{Code: SignatureErrReplayed, Message: "nonce was already used"}
+	}
+	return nil
+}
+
+// parseRequestTimestamp accepts RFC 3339 timestamps and Unix seconds
+func parseRequestTimestamp(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("timestamp must be RFC 3339 or Unix seconds")
+}
+
+// nonceCache remembers used nonces until they expire
+type nonceCache struct {
+	mu        sync.Mutex
+	expiresAt map[string]time.Time
+	lastSweep time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{expiresAt: make(map[string]time.Time)}
+}
+
+// add records nonce and reports whether it was unused
+func (n *nonceCache) add(nonce string, now time.Time, ttl time.Duration) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if now.Sub(n.lastSweep) > time.Minute {
+		n.lastSweep = now
+		for key, expiresAt := range n.expiresAt {
+			if now.After(expiresAt) {
+				delete(n.expiresAt, key)
+			}
+		}
+	}
+
+	if expiresAt, ok := n.expiresAt[nonce]; ok && now.Before(expiresAt) {
+		return false
+	}
+	n.expiresAt[nonce] = now.Add(ttl)
+	return true
+}
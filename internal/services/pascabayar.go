@@ -1,19 +1,29 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"gateway-digiflazz/internal/config"
 	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/validation"
 	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/metrics"
+	"gateway-digiflazz/pkg/requestid"
 
 	"github.com/sirupsen/logrus"
 )
 
+// pascabayarCategory labels bill payments in the transaction metrics
+const pascabayarCategory = "Pascabayar"
+
 // PascabayarService handles Pascabayar transaction operations
 type PascabayarService struct {
 	digiflazzClient *digiflazz.Client
 	logger          *logrus.Logger
+	validators      *validation.Registry
+	metrics         *metrics.Metrics
 }
 
 // NewPascabayarService creates a new Pascabayar service
@@ -21,31 +31,43 @@ func NewPascabayarService(client *digiflazz.Client, logger *logrus.Logger) *Pasc
 	return &PascabayarService{
 		digiflazzClient: client,
 		logger:          logger,
+		validators:      validation.NewRegistry(config.ValidationConfig{}),
 	}
 }
 
+// SetValidators configures the customer number validator registry
+func (s *PascabayarService) SetValidators(validators *validation.Registry) {
+	s.validators = validators
+}
+
+// SetMetrics configures where bill payment outcomes are recorded
+func (s *PascabayarService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
 // CheckBill checks the Pascabayar bill before payment
-func (s *PascabayarService) CheckBill(req models.PascabayarCheckRequest) (*models.PascabayarCheckResponse, error) {
-	s.logger.WithFields(logrus.Fields{
+func (s *PascabayarService) CheckBill(ctx context.Context, req models.PascabayarCheckRequest) (*models.PascabayarCheckResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithFields(logrus.Fields{
 		"ref_id":      req.RefID,
 		"customer_no": req.CustomerNo,
 		"buyer_sku":   req.BuyerSKU,
 	}).Info("Checking Pascabayar bill")
 
 	// Validate request
-	if err := s.validateCheckRequest(req); err != nil {
-		s.logger.WithError(err).Error("Pascabayar check request validation failed")
+	if err := s.validateCheckRequest(&req); err != nil {
+		log.WithError(err).Error("Pascabayar check request validation failed")
 		return nil, err
 	}
 
 	// Call Digiflazz API to check bill
-	resp, err := s.digiflazzClient.CheckPascabayarBill(req)
+	resp, err := s.digiflazzClient.CheckPascabayarBill(ctx, req)
 	if err != nil {
-		s.logger.WithError(err).Error("Digiflazz Pascabayar check API call failed")
+		log.WithError(err).Error("Digiflazz Pascabayar check API call failed")
 		return nil, fmt.Errorf("failed to check bill: %w", err)
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"ref_id": resp.Data.RefID,
 		"amount": resp.Data.Amount,
 		"status": resp.Data.Status,
@@ -56,8 +78,9 @@ func (s *PascabayarService) CheckBill(req models.PascabayarCheckRequest) (*model
 }
 
 // PayBill processes the Pascabayar bill payment
-func (s *PascabayarService) PayBill(req models.PascabayarPayRequest) (*models.PascabayarPayResponse, error) {
-	s.logger.WithFields(logrus.Fields{
+func (s *PascabayarService) PayBill(ctx context.Context, req models.PascabayarPayRequest) (*models.PascabayarPayResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithFields(logrus.Fields{
 		"ref_id":      req.RefID,
 		"customer_no": req.CustomerNo,
 		"buyer_sku":   req.BuyerSKU,
@@ -65,19 +88,21 @@ func (s *PascabayarService) PayBill(req models.PascabayarPayRequest) (*models.Pa
 	}).Info("Processing Pascabayar bill payment")
 
 	// Validate request
-	if err := s.validatePayRequest(req); err != nil {
-		s.logger.WithError(err).Error("Pascabayar pay request validation failed")
+	if err := s.validatePayRequest(&req); err != nil {
+		log.WithError(err).Error("Pascabayar pay request validation failed")
 		return nil, err
 	}
 
 	// Call Digiflazz API to pay bill
-	resp, err := s.digiflazzClient.PayPascabayarBill(req)
+	resp, err := s.digiflazzClient.PayPascabayarBill(ctx, req)
 	if err != nil {
-		s.logger.WithError(err).Error("Digiflazz Pascabayar payment API call failed")
+		log.WithError(err).Error("Digiflazz Pascabayar payment API call failed")
+		s.metrics.ObserveTransaction(req.BuyerSKU, pascabayarCategory, "failed")
 		return nil, fmt.Errorf("failed to pay bill: %w", err)
 	}
+	s.metrics.ObserveTransaction(req.BuyerSKU, pascabayarCategory, mapDigiflazzStatus(resp.Data.Status))
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"ref_id": resp.Data.RefID,
 		"amount": resp.Data.Amount,
 		"status": resp.Data.Status,
@@ -88,34 +113,54 @@ func (s *PascabayarService) PayBill(req models.PascabayarPayRequest) (*models.Pa
 	return resp, nil
 }
 
-// validateCheckRequest validates the check bill request
-func (s *PascabayarService) validateCheckRequest(req models.PascabayarCheckRequest) error {
+// validateCheckRequest validates the check bill request and normalises its customer number
+func (s *PascabayarService) validateCheckRequest(req *models.PascabayarCheckRequest) error {
+	fieldErrors := validation.Errors{}
 	if req.RefID == "" {
-		return fmt.Errorf("ref_id is required")
+		fieldErrors["ref_id"] = "ref_id is required"
 	}
 	if req.CustomerNo == "" {
-		return fmt.Errorf("customer_no is required")
+		fieldErrors["customer_no"] = "customer_no is required"
 	}
 	if req.BuyerSKU == "" {
-		return fmt.Errorf("buyer_sku is required")
+		fieldErrors["buyer_sku"] = "buyer_sku is required"
+	}
+	if len(fieldErrors) > 0 {
+		return fieldErrors
 	}
+
+	customerNo, err := s.validators.ValidateCustomerNo(req.BuyerSKU, "", req.CustomerNo)
+	if err != nil {
+		return err
+	}
+	req.CustomerNo = customerNo
 	return nil
 }
 
-// validatePayRequest validates the pay bill request
-func (s *PascabayarService) validatePayRequest(req models.PascabayarPayRequest) error {
+// validatePayRequest validates the pay bill request and normalises its customer number
+func (s *PascabayarService) validatePayRequest(req *models.PascabayarPayRequest) error {
+	fieldErrors := validation.Errors{}
 	if req.RefID == "" {
-		return fmt.Errorf("ref_id is required")
+		fieldErrors["ref_id"] = "ref_id is required"
 	}
 	if req.CustomerNo == "" {
-		return fmt.Errorf("customer_no is required")
+		fieldErrors["customer_no"] = "customer_no is required"
 	}
 	if req.BuyerSKU == "" {
-		return fmt.Errorf("buyer_sku is required")
+		fieldErrors["buyer_sku"] = "buyer_sku is required"
 	}
 	if req.Amount <= 0 {
-		return fmt.Errorf("amount must be greater than 0")
+		fieldErrors["amount"] = "amount must be greater than 0"
+	}
+	if len(fieldErrors) > 0 {
+		return fieldErrors
+	}
+
+	customerNo, err := s.validators.ValidateCustomerNo(req.BuyerSKU, "", req.CustomerNo)
+	if err != nil {
+		return err
 	}
+	req.CustomerNo = customerNo
 	return nil
 }
 
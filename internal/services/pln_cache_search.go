@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/validation"
+	"gateway-digiflazz/pkg/cache"
+)
+
+const (
+	// DefaultCacheSearchPerPage is the page size of cache searches
+	DefaultCacheSearchPerPage = 20
+	// MaxCacheSearchPerPage bounds the page size of cache searches
+	MaxCacheSearchPerPage = 100
+	// MaxCacheSearchScan bounds the cache entries one search reads; a search that
+	// reaches it reports a truncated result
+	MaxCacheSearchScan = 10000
+)
+
+// errCacheSearchScanLimit stops a search scan at MaxCacheSearchScan entries
+var errCacheSearchScanLimit = errors.New("cache search scan limit reached")
+
+// SearchCache lists cached PLN inquiries matching query, ordered by customer number.
+// Entries past the current hard TTL and negative entries are not listed. A customer_no
+// filter narrows the scan to its key prefix; at most MaxCacheSearchScan entries are read.
+func (s *PLNInquiryService) SearchCache(ctx context.Context, query models.PLNCacheSearchQuery) (*models.PLNCacheSearchResult, error) {
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PerPage == 0 {
+		query.PerPage = DefaultCacheSearchPerPage
+	}
+	fieldErrors := validation.Errors{}
+	if query.Page < 1 {
+		fieldErrors["page"] = "page must be at least 1"
+	}
+	if query.PerPage < 1 || query.PerPage > MaxCacheSearchPerPage {
+		fieldErrors["per_page"] = fmt.Sprintf("per_page must be between 1 and %d", MaxCacheSearchPerPage)
+	}
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors
+	}
+
+	scanner, ok := s.cache.(cache.Scanner)
+	if !ok {
+		return nil, cache.ErrScanUnsupported
+	}
+
+	config := s.GetCacheConfig()
+	negativePrefix := config.CacheKeyPrefix + negativeCacheKeySegment
+	customerNo := strings.TrimSpace(query.CustomerNo)
+	name := strings.ToLower(strings.TrimSpace(query.Name))
+	tariff, power := splitSegmentPower(query.SegmentPower)
+	now := time.Now()
+
+	// Entries are keyed by customer number, so a customer_no filter is a key prefix
+	var matches []models.PLNCacheEntry
+	scanned := 0
+	err := scanner.Scan(ctx, config.CacheKeyPrefix+customerNo, func(item cache.Entry) error {
+		if strings.HasPrefix(item.Key, negativePrefix) {
+			return nil
+		}
+		if scanned == MaxCacheSearchScan {
+			return errCacheSearchScanLimit
+		}
+		scanned++
+
+		var cached models.PLNInquiryCache
+		if err := json.Unmarshal([]byte(item.Value), &cached); err != nil {
+			return nil
+		}
+
+		switch {
+		case query.MeterNo != "" && !strings.HasPrefix(cached.MeterNo, strings.TrimSpace(query.MeterNo)):
+			return nil
+		case name != "" && !strings.Contains(strings.ToLower(cached.Name), name):
+			return nil
+		}
+		if tariff != "" || power != "" {
+			entryTariff, entryPower := splitSegmentPower(cached.SegmentPower)
+			if (tariff != "" && tariff != entryTariff) || (power != "" && power != entryPower) {
+				return nil
+			}
+		}
+
+		// The entry expires at the earlier of its own expiry and the current hard TTL
+		expiresAt := cached.ExpiresAt
+		if config.CacheTTL > 0 {
+			if limit := cached.CachedAt.Add(config.CacheTTL); expiresAt.IsZero() || limit.Before(expiresAt) {
+				expiresAt = limit
+			}
+		}
+		if !expiresAt.IsZero() && !now.Before(expiresAt) {
+			return nil
+		}
+
+		entry := models.PLNCacheEntry{
+			CustomerNo:   cached.CustomerNo,
+			MeterNo:      cached.MeterNo,
+			SubscriberID: cached.SubscriberID,
+			Name:         cached.Name,
+			SegmentPower: cached.SegmentPower,
+			RC:           cached.RC,
+			RefID:        cached.RefID,
+			CachedAt:     cached.CachedAt,
+			Stale:        config.CacheSoftTTL > 0 && now.Sub(cached.CachedAt) > config.CacheSoftTTL,
+		}
+		if !expiresAt.IsZero() {
+			entry.ExpiresAt = &expiresAt
+		}
+		matches = append(matches, entry)
+		return nil
+	})
+	truncated := errors.Is(err, errCacheSearchScanLimit)
+	if err != nil && !truncated {
+		return nil, fmt.Errorf("failed to search pln cache: %w", err)
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].CustomerNo < matches[j].CustomerNo })
+
+	result := &models.PLNCacheSearchResult{
+		Entries:    []models.PLNCacheEntry{},
+		Page:       query.Page,
+		PerPage:    query.PerPage,
+		Total:      len(matches),
+		TotalPages: (len(matches) + query.PerPage - 1) / query.PerPage,
+		Truncated:  truncated,
+	}
+	if start := (query.Page - 1) * query.PerPage; start < len(matches) {
+		end := start + query.PerPage
+		if end > len(matches) {
+			end = len(matches)
+		}
+		result.Entries = matches[start:end]
+	}
+	return result, nil
+}
+
+// splitSegmentPower splits a segment power such as "R1 /000001300" into its
+// tariff ("R1") and power without leading zeros or a "VA" suffix ("1300").
+// A bare number is a power and any other value a tariff.
+func splitSegmentPower(value string) (tariff, power string) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	tariffPart, powerPart, found := strings.Cut(value, "/")
+	if !found {
+		if digits := strings.TrimSuffix(value, "VA"); digits != "" && strings.Trim(digits, "0123456789") == "" {
+			tariffPart, powerPart = "", digits
+		} else {
+			return value, ""
+		}
+	}
+
+	powerPart = strings.TrimSuffix(strings.TrimSpace(powerPart), "VA")
+	power = strings.TrimLeft(powerPart, "0")
+	if power == "" && powerPart != "" {
+		power = "0"
+	}
+	return strings.TrimSpace(tariffPart), power
+}
@@ -0,0 +1,466 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/validation"
+	"gateway-digiflazz/pkg/cache"
+	"gateway-digiflazz/pkg/digiflazz"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PLN cache export formats
+const (
+	CacheFormatJSONL = "jsonl"
+	CacheFormatCSV   = "csv"
+)
+
+// Conflict policies for PLN cache imports
+const (
+	// ImportPolicySkip keeps entries that are already cached
+	ImportPolicySkip = "skip"
+	// ImportPolicyOverwrite replaces entries that are already cached
+	ImportPolicyOverwrite = "overwrite"
+	// ImportPolicyNewer replaces cached entries only with more recently cached data
+	ImportPolicyNewer = "newer"
+)
+
+const (
+	// DefaultWarmConcurrency is the number of parallel Digiflazz inquiries during a warm-up
+	DefaultWarmConcurrency = 4
+	// MaxWarmConcurrency bounds the load a warm-up puts on Digiflazz
+	MaxWarmConcurrency = 32
+	// maxImportErrors bounds the error messages reported by an import
+	maxImportErrors = 50
+)
+
+// plnCacheCSVHeader is the column layout of CSV exports and imports
+var plnCacheCSVHeader = []string{
+	"customer_no", "meter_no", "subscriber_id", "name", "segment_power",
+	"status", "rc", "message", "ref_id", "cached_at", "expires_at",
+}
+
+// ExportCache writes every live PLN cache entry to w as JSONL or CSV and returns the number written.
+// Negative "customer not found" entries are not exported.
+func (s *PLNInquiryService) ExportCache(ctx context.Context, w io.Writer, format string) (int, error) {
+	if err := validateCacheFormat(format); err != nil {
+		return 0, err
+	}
+	scanner, ok := s.cache.(cache.Scanner)
+	if !ok {
+		return 0, cache.ErrScanUnsupported
+	}
+
+	prefix := s.GetCacheConfig().CacheKeyPrefix
+	negativePrefix := prefix + negativeCacheKeySegment
+
+	var writeEntry func(models.PLNInquiryCache) error
+	var flush func() error
+	switch format {
+	case CacheFormatCSV:
+		csvWriter := csv.NewWriter(w)
+		if err := csvWriter.Write(plnCacheCSVHeader); err != nil {
+			return 0, err
+		}
+		writeEntry = func(entry models.PLNInquiryCache) error {
+			return csvWriter.Write(plnCacheCSVRecord(entry))
+		}
+		flush = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	default:
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		writeEntry = func(entry models.PLNInquiryCache) error {
+			return encoder.Encode(entry)
+		}
+		flush = buffered.Flush
+	}
+
+	count := 0
+	err := scanner.Scan(ctx, prefix, func(item cache.Entry) error {
+		if strings.HasPrefix(item.Key, negativePrefix) {
+			return nil
+		}
+		var entry models.PLNInquiryCache
+		if err := json.Unmarshal([]byte(item.Value), &entry); err != nil {
+			s.logger.WithError(err).WithField("cache_key", item.Key).Warn("Skipping unreadable PLN cache entry during export")
+			return nil
+		}
+		if err := writeEntry(entry); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to export pln cache: %w", err)
+	}
+	if err := flush(); err != nil {
+		return count, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"format":  format,
+		"entries": count,
+	}).Info("PLN inquiry cache exported")
+	return count, nil
+}
+
+// ImportCache reads PLN cache entries in JSONL or CSV from r and stores them according to policy.
+// Entries keep their original cached_at, so the current TTL still applies to them.
+func (s *PLNInquiryService) ImportCache(ctx context.Context, r io.Reader, format, policy string) (*models.PLNCacheImportResult, error) {
+	if policy == "" {
+		policy = ImportPolicySkip
+	}
+	fieldErrors := validation.Errors{}
+	if format != CacheFormatJSONL && format != CacheFormatCSV {
+		fieldErrors["format"] = "format must be jsonl or csv"
+	}
+	switch policy {
+	case ImportPolicySkip, ImportPolicyOverwrite, ImportPolicyNewer:
+	default:
+		fieldErrors["policy"] = "policy must be one of skip, overwrite or newer"
+	}
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors
+	}
+
+	result := &models.PLNCacheImportResult{}
+	addError := func(line int, err error) {
+		result.Failed++
+		if len(result.Errors) < maxImportErrors {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: %v", line, err))
+		}
+	}
+	importEntry := func(line int, entry models.PLNInquiryCache) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result.Total++
+		imported, err := s.importCacheEntry(ctx, entry, policy)
+		switch {
+		case errors.Is(err, errCacheEntryExpired):
+			result.Expired++
+		case err != nil:
+			addError(line, err)
+		case imported:
+			result.Imported++
+		default:
+			result.Skipped++
+		}
+		return nil
+	}
+
+	var err error
+	if format == CacheFormatCSV {
+		err = readPLNCacheCSV(r, importEntry, addError)
+	} else {
+		err = readPLNCacheJSONL(r, importEntry, addError)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to import pln cache: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"format":   format,
+		"policy":   policy,
+		"total":    result.Total,
+		"imported": result.Imported,
+		"skipped":  result.Skipped,
+		"expired":  result.Expired,
+		"failed":   result.Failed,
+	}).Info("PLN inquiry cache imported")
+	return result, nil
+}
+
+// errCacheEntryExpired is returned by importCacheEntry for entries past their hard TTL
+var errCacheEntryExpired = errors.New("cache entry expired")
+
+// importCacheEntry stores entry unless policy keeps the cached one; it reports whether entry was stored
+func (s *PLNInquiryService) importCacheEntry(ctx context.Context, entry models.PLNInquiryCache, policy string) (bool, error) {
+	customerNo, err := s.validators.Validate("pln", entry.CustomerNo)
+	if err != nil {
+		return false, err
+	}
+	entry.CustomerNo = customerNo
+	// Like live inquiries, only successful ones are cached; cache hits do not check rc
+	if entry.RC != digiflazz.RCSuccess {
+		return false, fmt.Errorf("rc must be %q, only successful inquiries can be imported", digiflazz.RCSuccess)
+	}
+	if entry.CachedAt.IsZero() {
+		return false, fmt.Errorf("cached_at is required")
+	}
+
+	// The entry expires at the earlier of its own expiry and the current hard TTL
+	now := time.Now()
+	expiresAt := entry.ExpiresAt
+	if ttl := s.GetCacheConfig().CacheTTL; ttl > 0 {
+		if limit := entry.CachedAt.Add(ttl); expiresAt.IsZero() || limit.Before(expiresAt) {
+			expiresAt = limit
+		}
+	}
+	if !expiresAt.IsZero() && !now.Before(expiresAt) {
+		return false, errCacheEntryExpired
+	}
+	entry.ExpiresAt = expiresAt
+
+	if policy != ImportPolicyOverwrite {
+		existing, err := s.getFromCache(ctx, customerNo)
+		if err == nil && existing != nil {
+			if policy == ImportPolicySkip || !entry.CachedAt.After(existing.CachedAt) {
+				return false, nil
+			}
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return false, err
+	}
+	var ttl time.Duration
+	if !expiresAt.IsZero() {
+		ttl = expiresAt.Sub(now)
+	}
+	if err := s.cache.Set(ctx, s.getCacheKey(customerNo), string(data), ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WarmCache looks up customerNos against Digiflazz with at most concurrency parallel
+// inquiries, caching every result. Customers that are already cached are not looked up again.
+func (s *PLNInquiryService) WarmCache(ctx context.Context, customerNos []string, concurrency int) (*models.PLNCacheWarmResult, error) {
+	unique, concurrency, err := s.prepareWarmUp(customerNos, concurrency)
+	if err != nil {
+		return nil, err
+	}
+	return s.warmCache(ctx, unique, concurrency), nil
+}
+
+// prepareWarmUp validates a warm-up, returning the distinct customer numbers and the
+// concurrency to use. Warm-ups are refused while the cache is disabled, since every
+// lookup would reach Digiflazz and store nothing.
+func (s *PLNInquiryService) prepareWarmUp(customerNos []string, concurrency int) ([]string, int, error) {
+	if !s.GetCacheConfig().CacheEnabled {
+		return nil, 0, validation.Errors{"cache_enabled": "the PLN cache is disabled, enable it before warming"}
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultWarmConcurrency
+	}
+	if concurrency > MaxWarmConcurrency {
+		return nil, 0, validation.Errors{"concurrency": fmt.Sprintf("concurrency must be at most %d", MaxWarmConcurrency)}
+	}
+
+	unique := make([]string, 0, len(customerNos))
+	seen := make(map[string]bool, len(customerNos))
+	for _, customerNo := range customerNos {
+		customerNo = strings.TrimSpace(customerNo)
+		if customerNo == "" || seen[customerNo] {
+			continue
+		}
+		seen[customerNo] = true
+		unique = append(unique, customerNo)
+	}
+	if len(unique) == 0 {
+		return nil, 0, validation.Errors{"customer_nos": "at least one customer number is required"}
+	}
+	return unique, concurrency, nil
+}
+
+// warmCache looks up the distinct customer numbers of a validated warm-up
+func (s *PLNInquiryService) warmCache(ctx context.Context, unique []string, concurrency int) *models.PLNCacheWarmResult {
+	startTime := time.Now()
+	result := &models.PLNCacheWarmResult{Requested: len(unique), Errors: make(map[string]string)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+dispatch:
+	for _, customerNo := range unique {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(customerNo string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			resp, err := s.InquiryPLN(ctx, models.PLNInquiryRequest{CustomerNo: customerNo}, "warmup-"+customerNo)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil && digiflazz.IsCustomerNotFound(digiflazz.RCFromError(err)):
+				result.NotFound++
+			case err != nil:
+				result.Failed++
+				result.Errors[customerNo] = err.Error()
+			case resp.CachedAt != nil:
+				result.AlreadyCached++
+			default:
+				result.Warmed++
+			}
+		}(customerNo)
+	}
+	wg.Wait()
+
+	// Customers never dispatched because the warm-up was cancelled
+	if skipped := result.Requested - result.Warmed - result.AlreadyCached - result.NotFound - result.Failed; skipped > 0 {
+		result.Failed += skipped
+		result.Errors["_cancelled"] = fmt.Sprintf("%d customer numbers not looked up: %v", skipped, ctx.Err())
+	}
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	result.Duration = time.Since(startTime).String()
+
+	s.logger.WithFields(logrus.Fields{
+		"requested":      result.Requested,
+		"warmed":         result.Warmed,
+		"already_cached": result.AlreadyCached,
+		"not_found":      result.NotFound,
+		"failed":         result.Failed,
+		"concurrency":    concurrency,
+		"duration":       result.Duration,
+	}).Info("PLN inquiry cache warm-up finished")
+	return result
+}
+
+// CacheFormatForFile infers the export format from a file name, defaulting to JSONL
+func CacheFormatForFile(name string) string {
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return CacheFormatCSV
+	}
+	return CacheFormatJSONL
+}
+
+// validateCacheFormat checks an export or import format
+func validateCacheFormat(format string) error {
+	if format != CacheFormatJSONL && format != CacheFormatCSV {
+		return validation.Errors{"format": "format must be jsonl or csv"}
+	}
+	return nil
+}
+
+// plnCacheCSVRecord converts entry to a CSV row in plnCacheCSVHeader order
+func plnCacheCSVRecord(entry models.PLNInquiryCache) []string {
+	expiresAt := ""
+	if !entry.ExpiresAt.IsZero() {
+		expiresAt = entry.ExpiresAt.Format(time.RFC3339Nano)
+	}
+	return []string{
+		entry.CustomerNo, entry.MeterNo, entry.SubscriberID, entry.Name, entry.SegmentPower,
+		entry.Status, entry.RC, entry.Message, entry.RefID,
+		entry.CachedAt.Format(time.RFC3339Nano), expiresAt,
+	}
+}
+
+// readPLNCacheJSONL decodes one entry per line; malformed lines are reported through addError
+func readPLNCacheJSONL(r io.Reader, fn func(line int, entry models.PLNInquiryCache) error, addError func(line int, err error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry models.PLNInquiryCache
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			addError(line, err)
+			continue
+		}
+		if err := fn(line, entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readPLNCacheCSV decodes rows by header name; malformed rows are reported through addError
+func readPLNCacheCSV(r io.Reader, fn func(line int, entry models.PLNInquiryCache) error, addError func(line int, err error)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	if _, ok := columns["customer_no"]; !ok {
+		return fmt.Errorf("csv header must include customer_no")
+	}
+
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		line++
+		if err != nil {
+			addError(line, err)
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		entry := models.PLNInquiryCache{
+			CustomerNo:   field("customer_no"),
+			MeterNo:      field("meter_no"),
+			SubscriberID: field("subscriber_id"),
+			Name:         field("name"),
+			SegmentPower: field("segment_power"),
+			Status:       field("status"),
+			RC:           field("rc"),
+			Message:      field("message"),
+			RefID:        field("ref_id"),
+		}
+		if entry.CachedAt, err = parseCSVTime(field("cached_at")); err != nil {
+			addError(line, fmt.Errorf("invalid cached_at: %w", err))
+			continue
+		}
+		if entry.ExpiresAt, err = parseCSVTime(field("expires_at")); err != nil {
+			addError(line, fmt.Errorf("invalid expires_at: %w", err))
+			continue
+		}
+		if err := fn(line, entry); err != nil {
+			return err
+		}
+	}
+}
+
+// parseCSVTime parses an RFC 3339 timestamp; an empty value is the zero time
+func parseCSVTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/pkg/requestid"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PLN cache warm-up job states
+const (
+	WarmJobRunning  = "running"
+	WarmJobFinished = "finished"
+)
+
+const (
+	// MaxRunningWarmJobs bounds the warm-ups running in the background at a time
+	MaxRunningWarmJobs = 2
+	// maxWarmJobs bounds the jobs kept for polling; the oldest finished jobs are dropped first
+	maxWarmJobs = 100
+)
+
+// ErrWarmJobsBusy is returned by StartWarmCache while MaxRunningWarmJobs warm-ups are running
+var ErrWarmJobsBusy = errors.New("too many pln cache warm-ups running")
+
+// warmJobStore keeps the background warm-ups of a PLN inquiry service
+type warmJobStore struct {
+	mu    sync.Mutex
+	jobs  map[string]*models.PLNCacheWarmJob
+	order []string
+}
+
+func newWarmJobStore() *warmJobStore {
+	return &warmJobStore{jobs: make(map[string]*models.PLNCacheWarmJob)}
+}
+
+// start registers a running job unless MaxRunningWarmJobs are already running
+func (js *warmJobStore) start(requested int) (*models.PLNCacheWarmJob, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	running := 0
+	for _, job := range js.jobs {
+		if job.Status == WarmJobRunning {
+			running++
+		}
+	}
+	if running >= MaxRunningWarmJobs {
+		return nil, ErrWarmJobsBusy
+	}
+
+	job := &models.PLNCacheWarmJob{
+		JobID:     requestid.New(),
+		Status:    WarmJobRunning,
+		Requested: requested,
+		StartedAt: time.Now(),
+	}
+	js.jobs[job.JobID] = job
+	js.order = append(js.order, job.JobID)
+	js.evict()
+
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// finish records the result of a job
+func (js *warmJobStore) finish(jobID string, result *models.PLNCacheWarmResult) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, ok := js.jobs[jobID]
+	if !ok {
+		return
+	}
+	finishedAt := time.Now()
+	job.Status = WarmJobFinished
+	job.FinishedAt = &finishedAt
+	job.Result = result
+}
+
+// get returns a copy of a job, so callers never see it change under them
+func (js *warmJobStore) get(jobID string) (*models.PLNCacheWarmJob, bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, ok := js.jobs[jobID]
+	if !ok {
+		return nil, false
+	}
+	snapshot := *job
+	return &snapshot, true
+}
+
+// evict drops the oldest finished jobs beyond maxWarmJobs; running jobs are always kept
+func (js *warmJobStore) evict() {
+	kept := js.order[:0]
+	excess := len(js.order) - maxWarmJobs
+	for _, jobID := range js.order {
+		if excess > 0 && js.jobs[jobID].Status == WarmJobFinished {
+			delete(js.jobs, jobID)
+			excess--
+			continue
+		}
+		kept = append(kept, jobID)
+	}
+	js.order = kept
+}
+
+// StartWarmCache validates a warm-up like WarmCache and runs it in the background,
+// returning the job to poll with GetWarmJob. The warm-up outlives ctx but keeps its
+// request ID.
+func (s *PLNInquiryService) StartWarmCache(ctx context.Context, customerNos []string, concurrency int) (*models.PLNCacheWarmJob, error) {
+	unique, concurrency, err := s.prepareWarmUp(customerNos, concurrency)
+	if err != nil {
+		return nil, err
+	}
+	job, err := s.warmJobs.start(len(unique))
+	if err != nil {
+		return nil, err
+	}
+
+	requestid.Logger(ctx, s.logger).WithFields(logrus.Fields{
+		"job_id":      job.JobID,
+		"requested":   job.Requested,
+		"concurrency": concurrency,
+	}).Info("PLN inquiry cache warm-up started")
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		s.warmJobs.finish(job.JobID, s.warmCache(ctx, unique, concurrency))
+	}()
+	return job, nil
+}
+
+// GetWarmJob returns a warm-up started by StartWarmCache
+func (s *PLNInquiryService) GetWarmJob(jobID string) (*models.PLNCacheWarmJob, bool) {
+	return s.warmJobs.get(jobID)
+}
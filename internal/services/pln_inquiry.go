@@ -3,11 +3,19 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"gateway-digiflazz/internal/config"
 	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+	"gateway-digiflazz/internal/validation"
 	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/metrics"
+	"gateway-digiflazz/pkg/requestid"
 
 	"github.com/sirupsen/logrus"
 )
@@ -18,9 +26,27 @@ type PLNInquiryService struct {
 	logger          *logrus.Logger
 	cache           CacheInterface
 	config          models.PLNInquiryConfig
-	stats           *models.PLNInquiryStats
+	configMu        sync.RWMutex
+	configStore     repositories.PLNCacheConfigRepository
+	stats           *plnInquiryMetrics
+	inflight        *inquiryGroup
+	warmJobs        *warmJobStore
+	refreshing      sync.Map
+	validators      *validation.Registry
+	metrics         *metrics.Metrics
 }
 
+const (
+	// maxCacheKeyPrefixLength bounds the configurable PLN cache key prefix
+	maxCacheKeyPrefixLength = 64
+	// defaultNegativeCacheTTL is how long "customer not found" results are cached by default
+	defaultNegativeCacheTTL = 10 * time.Minute
+	// maxNegativeCacheTTL keeps negative entries short-lived so new meters become visible
+	maxNegativeCacheTTL = 24 * time.Hour
+	// negativeCacheKeySegment separates negative entries from positive ones under the key prefix
+	negativeCacheKeySegment = "notfound:"
+)
+
 // CacheInterface defines the interface for caching operations
 type CacheInterface interface {
 	Get(ctx context.Context, key string) (string, error)
@@ -41,70 +67,155 @@ func NewPLNInquiryService(client *digiflazz.Client, logger *logrus.Logger, cache
 			CacheEnabled:   true,
 			CacheTTL:       0, // No expiration for static PLN data
 			CacheKeyPrefix: "pln_inquiry:",
+			NegativeCacheEnabled: true,
+			NegativeCacheTTL:     defaultNegativeCacheTTL,
 		},
-		stats: &models.PLNInquiryStats{},
+		stats:      newPLNInquiryMetrics(),
+		inflight:   newInquiryGroup(),
+		warmJobs:   newWarmJobStore(),
+		validators: validation.NewRegistry(config.ValidationConfig{}),
+	}
+}
+
+// SetMetrics configures where cache hits and misses are recorded
+func (s *PLNInquiryService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// SetValidators configures the customer number validator registry
+func (s *PLNInquiryService) SetValidators(validators *validation.Registry) {
+	s.validators = validators
+}
+
+// SetConfigRepository configures where runtime cache configuration updates are persisted
+func (s *PLNInquiryService) SetConfigRepository(store repositories.PLNCacheConfigRepository) {
+	s.configStore = store
+}
+
+// LoadCacheConfig applies the cache configuration persisted by a previous run, if any
+func (s *PLNInquiryService) LoadCacheConfig() error {
+	if s.configStore == nil {
+		return nil
+	}
+
+	config := s.GetCacheConfig()
+	if err := s.configStore.Load(&config); err != nil {
+		if errors.Is(err, repositories.ErrConfigNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := validateCacheConfig(config); err != nil {
+		return fmt.Errorf("persisted pln cache config is invalid: %w", err)
 	}
+
+	s.applyCacheConfig(config)
+	s.logger.WithFields(logrus.Fields{
+		"cache_enabled":    config.CacheEnabled,
+		"cache_ttl":        config.CacheTTL.String(),
+		"cache_key_prefix": config.CacheKeyPrefix,
+	}).Info("Loaded persisted PLN inquiry cache configuration")
+	return nil
 }
 
 // InquiryPLN performs PLN inquiry with caching strategy
-func (s *PLNInquiryService) InquiryPLN(req models.PLNInquiryRequest, refID string) (*models.PLNInquiryResponse, error) {
+func (s *PLNInquiryService) InquiryPLN(ctx context.Context, req models.PLNInquiryRequest, refID string) (*models.PLNInquiryResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
 	startTime := time.Now()
-	s.stats.TotalRequests++
+	cacheConfig := s.GetCacheConfig()
 
-	s.logger.WithFields(logrus.Fields{
+	// Validate PLN meter number or ID pelanggan
+	customerNo, err := s.validators.Validate("pln", req.CustomerNo)
+	if err != nil {
+		log.WithError(err).WithField("customer_no", req.CustomerNo).Warn("PLN inquiry validation failed")
+		return nil, err
+	}
+	req.CustomerNo = customerNo
+
+	s.stats.recordRequest()
+
+	log.WithFields(logrus.Fields{
 		"customer_no": req.CustomerNo,
-		"cached":      s.config.CacheEnabled,
+		"cached":      cacheConfig.CacheEnabled,
 	}).Info("Processing PLN inquiry")
 
 	// Check cache first if enabled
-	if s.config.CacheEnabled {
-		cached, err := s.getFromCache(req.CustomerNo)
+	if cacheConfig.CacheEnabled {
+		cached, err := s.getFromCache(ctx, req.CustomerNo)
 		if err == nil && cached != nil {
-			s.stats.CacheHits++
-			s.logger.WithFields(logrus.Fields{
+			log.WithFields(logrus.Fields{
 				"customer_no": req.CustomerNo,
 				"ref_id":      refID,
 			}).Info("PLN inquiry served from cache")
 			
-			// Update response time
-			s.updateResponseTime(time.Since(startTime))
-			
+			s.stats.recordCacheHit(time.Since(startTime))
+			s.metrics.ObservePLNCache(metrics.PLNCacheHit)
+
+			// Serve stale entries immediately and refresh them in the background
+			stale := cacheConfig.CacheSoftTTL > 0 && time.Since(cached.CachedAt) > cacheConfig.CacheSoftTTL
+			if stale {
+				s.stats.recordStaleHit()
+				s.refreshInBackground(ctx, req.CustomerNo, refID, cacheConfig)
+			}
+
 			// Build response with current ref_id
 			response := s.buildResponseFromCache(cached)
 			response.Data.RefID = refID // Use current ref_id
 			response.Data.Message = cached.Message // Ensure message is included
 			response.Message = "PLN inquiry completed successfully (cached)"
 			response.Status = 1
+			cachedAt := cached.CachedAt
+			response.CachedAt = &cachedAt
+			response.Stale = stale
 			return response, nil
 		}
-		s.stats.CacheMisses++
-		s.logger.WithFields(logrus.Fields{
+		s.stats.recordCacheMiss()
+		s.metrics.ObservePLNCache(metrics.PLNCacheMiss)
+		log.WithFields(logrus.Fields{
 			"customer_no": req.CustomerNo,
 			"ref_id":      refID,
 		}).Info("PLN inquiry cache miss")
+
+		// Answer known non-existent customers without calling Digiflazz
+		if cacheConfig.NegativeCacheEnabled {
+			if notFound, err := s.getNegativeFromCache(ctx, req.CustomerNo); err == nil && notFound != nil {
+				s.stats.recordNegativeHit(time.Since(startTime))
+				s.metrics.ObservePLNCache(metrics.PLNCacheNegativeHit)
+				log.WithFields(logrus.Fields{
+					"customer_no": req.CustomerNo,
+					"ref_id":      refID,
+					"rc":          notFound.RC,
+				}).Info("PLN inquiry served from negative cache")
+				return nil, fmt.Errorf("failed to inquiry PLN: customer not found (cached): %w",
+					&digiflazz.RCError{RC: notFound.RC, Message: notFound.Message})
+			}
+		}
 	}
 
-	// Call Digiflazz API
-	s.stats.APIRequests++
-	resp, err := s.digiflazzClient.InquiryPLN(req)
+	// Call Digiflazz API, sharing the call with concurrent requests for the same customer
+	shared, err, leader := s.inflight.do(req.CustomerNo, func() (*models.PLNInquiryResponse, error) {
+		return s.fetchFromAPI(ctx, req, refID, cacheConfig, startTime)
+	})
+	if !leader {
+		s.stats.recordCoalesced(time.Since(startTime))
+		log.WithFields(logrus.Fields{
+			"customer_no": req.CustomerNo,
+			"ref_id":      refID,
+		}).Info("PLN inquiry joined in-flight Digiflazz call")
+	}
+	if err == nil && shared == nil {
+		err = errors.New("empty response from Digiflazz")
+	}
 	if err != nil {
-		s.stats.ErrorCount++
-		s.logger.WithError(err).Error("Digiflazz PLN inquiry API call failed")
 		return nil, fmt.Errorf("failed to inquiry PLN: %w", err)
 	}
 
-	// Cache the response if successful and caching is enabled
-	if s.config.CacheEnabled && resp.Data.RC == "00" {
-		if err := s.setToCache(req.CustomerNo, refID, resp); err != nil {
-			s.logger.WithError(err).Warn("Failed to cache PLN inquiry response")
-		}
-	}
-
-	// Update response time
-	s.updateResponseTime(time.Since(startTime))
+	// Each caller gets its own copy carrying its own ref_id
+	respCopy := *shared
+	resp := &respCopy
 
 	// Ensure response has proper structure for cache miss
-	if resp.Data.RefID == "" {
+	if resp.Data.RefID == "" || !leader {
 		resp.Data.RefID = refID
 	}
 	if resp.Data.Message == "" && resp.Data.RC == "00" {
@@ -117,31 +228,96 @@ func (s *PLNInquiryService) InquiryPLN(req models.PLNInquiryRequest, refID strin
 		resp.Status = 1
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": req.CustomerNo,
 		"rc":          resp.Data.RC,
 		"status":      resp.Data.Status,
 		"ref_id":      resp.Data.RefID,
 		"message":     resp.Data.Message,
-		"cached":      s.config.CacheEnabled,
+		"cached":      cacheConfig.CacheEnabled,
 	}).Info("PLN inquiry completed")
 
 	return resp, nil
 }
 
+// fetchFromAPI calls the Digiflazz PLN inquiry API and caches successful responses
+func (s *PLNInquiryService) fetchFromAPI(ctx context.Context, req models.PLNInquiryRequest, refID string, cacheConfig models.PLNInquiryConfig, startTime time.Time) (*models.PLNInquiryResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	resp, err := s.digiflazzClient.InquiryPLN(ctx, req)
+	s.stats.recordAPICall(time.Since(startTime), err != nil)
+	if err != nil {
+		log.WithError(err).Error("Digiflazz PLN inquiry API call failed")
+
+		// Remember definitive "customer not found" answers; transient failures are retried
+		rc := digiflazz.RCFromError(err)
+		if cacheConfig.CacheEnabled && cacheConfig.NegativeCacheEnabled && digiflazz.IsCustomerNotFound(rc) {
+			var rcErr *digiflazz.RCError
+			message := ""
+			if errors.As(err, &rcErr) {
+				message = rcErr.Message
+			}
+			if err := s.setNegativeToCache(ctx, req.CustomerNo, rc, message, cacheConfig.NegativeCacheTTL); err != nil {
+				log.WithError(err).Warn("Failed to cache PLN customer not found response")
+			}
+		}
+		return nil, err
+	}
+
+	// Cache the response if successful and caching is enabled
+	if cacheConfig.CacheEnabled && resp.Data.RC == "00" {
+		if err := s.setToCache(ctx, req.CustomerNo, refID, resp); err != nil {
+			log.WithError(err).Warn("Failed to cache PLN inquiry response")
+		}
+	}
+	return resp, nil
+}
+
+// refreshInBackground re-fetches a stale cache entry from Digiflazz without blocking the caller.
+// At most one refresh per customer runs at a time and it shares in-flight foreground calls.
+func (s *PLNInquiryService) refreshInBackground(ctx context.Context, customerNo, refID string, cacheConfig models.PLNInquiryConfig) {
+	log := requestid.Logger(ctx, s.logger)
+	if _, busy := s.refreshing.LoadOrStore(customerNo, struct{}{}); busy {
+		return
+	}
+
+	// The refresh outlives the request but keeps its request ID
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer s.refreshing.Delete(customerNo)
+
+		req := models.PLNInquiryRequest{CustomerNo: customerNo}
+		_, err, _ := s.inflight.do(customerNo, func() (*models.PLNInquiryResponse, error) {
+			return s.fetchFromAPI(ctx, req, refID, cacheConfig, time.Now())
+		})
+		s.stats.recordRefresh(err != nil)
+		if err == nil {
+			log.WithField("customer_no", customerNo).Info("Stale PLN inquiry cache entry refreshed")
+			return
+		}
+
+		// A customer that no longer exists must not keep being served from cache
+		if digiflazz.IsCustomerNotFound(digiflazz.RCFromError(err)) {
+			if err := s.cache.Delete(ctx, s.getCacheKey(customerNo)); err != nil {
+				log.WithError(err).WithField("customer_no", customerNo).Warn("Failed to drop PLN cache entry of missing customer")
+			}
+		}
+		log.WithError(err).WithField("customer_no", customerNo).Warn("Background PLN inquiry refresh failed, keeping cached data")
+	}()
+}
+
 // getFromCache retrieves PLN inquiry data from cache
-func (s *PLNInquiryService) getFromCache(customerNo string) (*models.PLNInquiryCache, error) {
-	ctx := context.Background()
+func (s *PLNInquiryService) getFromCache(ctx context.Context, customerNo string) (*models.PLNInquiryCache, error) {
+	log := requestid.Logger(ctx, s.logger)
 	key := s.getCacheKey(customerNo)
 	
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": customerNo,
 		"cache_key":   key,
 	}).Debug("Attempting to get from cache")
 	
 	cachedData, err := s.cache.Get(ctx, key)
 	if err != nil {
-		s.logger.WithFields(logrus.Fields{
+		log.WithFields(logrus.Fields{
 			"customer_no": customerNo,
 			"cache_key":   key,
 			"error":       err.Error(),
@@ -149,7 +325,7 @@ func (s *PLNInquiryService) getFromCache(customerNo string) (*models.PLNInquiryC
 		return nil, err
 	}
 	
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": customerNo,
 		"cache_key":   key,
 		"data_length": len(cachedData),
@@ -157,7 +333,7 @@ func (s *PLNInquiryService) getFromCache(customerNo string) (*models.PLNInquiryC
 
 	var cache models.PLNInquiryCache
 	if err := json.Unmarshal([]byte(cachedData), &cache); err != nil {
-		s.logger.WithError(err).WithFields(logrus.Fields{
+		log.WithError(err).WithFields(logrus.Fields{
 			"customer_no": customerNo,
 			"cache_key":   key,
 			"raw_data":    string(cachedData),
@@ -165,7 +341,7 @@ func (s *PLNInquiryService) getFromCache(customerNo string) (*models.PLNInquiryC
 		return nil, err
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": customerNo,
 		"cache_key":   key,
 		"expires_at":  cache.ExpiresAt,
@@ -173,9 +349,15 @@ func (s *PLNInquiryService) getFromCache(customerNo string) (*models.PLNInquiryC
 		"now":         time.Now(),
 	}).Debug("Cache expiration check")
 
-	// Check if cache is expired (only if ExpiresAt is set and not zero time for permanent cache)
-	if !cache.ExpiresAt.IsZero() && time.Now().After(cache.ExpiresAt) {
-		s.logger.WithFields(logrus.Fields{
+	// Check if cache is expired (only if ExpiresAt is set and not zero time for permanent cache).
+	// Entries cached before the TTL was shortened expire according to the current TTL.
+	ttl := s.GetCacheConfig().CacheTTL
+	expired := !cache.ExpiresAt.IsZero() && time.Now().After(cache.ExpiresAt)
+	if ttl > 0 && time.Since(cache.CachedAt) > ttl {
+		expired = true
+	}
+	if expired {
+		log.WithFields(logrus.Fields{
 			"customer_no": customerNo,
 			"cache_key":   key,
 			"expires_at":  cache.ExpiresAt,
@@ -185,7 +367,7 @@ func (s *PLNInquiryService) getFromCache(customerNo string) (*models.PLNInquiryC
 		return nil, fmt.Errorf("cache expired")
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": customerNo,
 		"cache_key":   key,
 		"ref_id":      cache.RefID,
@@ -196,13 +378,15 @@ func (s *PLNInquiryService) getFromCache(customerNo string) (*models.PLNInquiryC
 }
 
 // setToCache stores PLN inquiry data in cache
-func (s *PLNInquiryService) setToCache(customerNo string, refID string, resp *models.PLNInquiryResponse) error {
-	ctx := context.Background()
+func (s *PLNInquiryService) setToCache(ctx context.Context, customerNo string, refID string, resp *models.PLNInquiryResponse) error {
+	log := requestid.Logger(ctx, s.logger)
 	key := s.getCacheKey(customerNo)
+	ttl := s.GetCacheConfig().CacheTTL
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": customerNo,
 		"cache_key":   key,
+		"cache_ttl":   ttl.String(),
 		"ref_id":      refID,
 		"rc":          resp.Data.RC,
 	}).Debug("Storing PLN inquiry data to cache")
@@ -218,23 +402,25 @@ func (s *PLNInquiryService) setToCache(customerNo string, refID string, resp *mo
 		RC:           resp.Data.RC,
 		Message:      resp.Data.Message,
 		CachedAt:     time.Now(),
-		ExpiresAt:    time.Time{}, // No expiration for static PLN data
+	}
+	// Zero TTL keeps static PLN data until it is cleared
+	if ttl > 0 {
+		cache.ExpiresAt = cache.CachedAt.Add(ttl)
 	}
 
 	cacheData, err := json.Marshal(cache)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to marshal cache data")
+		log.WithError(err).Error("Failed to marshal cache data")
 		return err
 	}
 
-	// Use 0 TTL for permanent cache (static PLN data)
-	err = s.cache.Set(ctx, key, string(cacheData), 0)
+	err = s.cache.Set(ctx, key, string(cacheData), ttl)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to set cache data")
+		log.WithError(err).Error("Failed to set cache data")
 		return err
 	}
 	
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": customerNo,
 		"cache_key":   key,
 		"data_length": len(cacheData),
@@ -243,9 +429,68 @@ func (s *PLNInquiryService) setToCache(customerNo string, refID string, resp *mo
 	return nil
 }
 
+// getNegativeFromCache retrieves a cached "customer not found" result
+func (s *PLNInquiryService) getNegativeFromCache(ctx context.Context, customerNo string) (*models.PLNInquiryNegativeCache, error) {
+	log := requestid.Logger(ctx, s.logger)
+	key := s.getNegativeCacheKey(customerNo)
+
+	cachedData, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry models.PLNInquiryNegativeCache
+	if err := json.Unmarshal([]byte(cachedData), &entry); err != nil {
+		log.WithError(err).WithField("cache_key", key).Error("Failed to unmarshal negative cache data")
+		return nil, err
+	}
+
+	// Entries written before the TTL was shortened expire according to the current TTL
+	ttl := s.GetCacheConfig().NegativeCacheTTL
+	if time.Now().After(entry.ExpiresAt) || time.Since(entry.CachedAt) > ttl {
+		s.cache.Delete(ctx, key)
+		return nil, fmt.Errorf("negative cache expired")
+	}
+	return &entry, nil
+}
+
+// setNegativeToCache stores a "customer not found" result for ttl
+func (s *PLNInquiryService) setNegativeToCache(ctx context.Context, customerNo, rc, message string, ttl time.Duration) error {
+	log := requestid.Logger(ctx, s.logger)
+	now := time.Now()
+	entry := models.PLNInquiryNegativeCache{
+		CustomerNo: customerNo,
+		RC:         rc,
+		Message:    message,
+		CachedAt:   now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.Set(ctx, s.getNegativeCacheKey(customerNo), string(data), ttl); err != nil {
+		return err
+	}
+
+	s.stats.recordNegativeStore()
+	log.WithFields(logrus.Fields{
+		"customer_no": customerNo,
+		"rc":          rc,
+		"ttl":         ttl.String(),
+	}).Info("PLN customer not found response cached")
+	return nil
+}
+
+// getNegativeCacheKey generates the negative cache key for customer number
+func (s *PLNInquiryService) getNegativeCacheKey(customerNo string) string {
+	return s.GetCacheConfig().CacheKeyPrefix + negativeCacheKeySegment + customerNo
+}
+
 // getCacheKey generates cache key for customer number
 func (s *PLNInquiryService) getCacheKey(customerNo string) string {
-	return s.config.CacheKeyPrefix + customerNo
+	return s.GetCacheConfig().CacheKeyPrefix + customerNo
 }
 
 // buildResponseFromCache builds response from cached data
@@ -277,27 +522,32 @@ func (s *PLNInquiryService) buildResponseFromCache(cache *models.PLNInquiryCache
 	}
 }
 
-// updateResponseTime updates the average response time
-func (s *PLNInquiryService) updateResponseTime(duration time.Duration) {
-	if s.stats.AverageResponseTime == 0 {
-		s.stats.AverageResponseTime = duration
+// GetStats returns PLN inquiry statistics together with the active cache configuration
+func (s *PLNInquiryService) GetStats() *models.PLNInquiryStats {
+	stats := s.stats.snapshot()
+	stats.InflightRequests = int64(s.inflight.inflight())
+	stats.Config = s.GetCacheConfig()
+
+	// Backend statistics, including per-tier hits when the in-memory tier is enabled
+	cacheStats, err := s.GetCacheStats()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get PLN cache backend statistics")
 	} else {
-		s.stats.AverageResponseTime = (s.stats.AverageResponseTime + duration) / 2
+		stats.Cache = cacheStats
 	}
+	return stats
 }
 
-// GetStats returns PLN inquiry statistics
-func (s *PLNInquiryService) GetStats() *models.PLNInquiryStats {
-	return s.stats
-}
-
-// ClearCache clears PLN inquiry cache for a specific customer
+// ClearCache clears PLN inquiry cache, including any negative entry, for a specific customer
 func (s *PLNInquiryService) ClearCache(customerNo string) error {
 	ctx := context.Background()
 	key := s.getCacheKey(customerNo)
 	
 	s.logger.WithField("customer_no", customerNo).Info("Clearing PLN inquiry cache")
-	return s.cache.Delete(ctx, key)
+	if err := s.cache.Delete(ctx, key); err != nil {
+		return err
+	}
+	return s.cache.Delete(ctx, s.getNegativeCacheKey(customerNo))
 }
 
 // ClearAllCache clears all PLN inquiry cache
@@ -320,11 +570,84 @@ func (s *PLNInquiryService) GetCacheStats() (map[string]interface{}, error) {
 	return s.cache.GetStats(ctx)
 }
 
-// SetCacheConfig updates cache configuration
-func (s *PLNInquiryService) SetCacheConfig(config models.PLNInquiryConfig) {
-	s.config = config
+// GetCacheConfig returns the active cache configuration
+func (s *PLNInquiryService) GetCacheConfig() models.PLNInquiryConfig {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
+
+// SetCacheConfig validates, applies and persists the cache configuration.
+// Entries cached under a previous key prefix are no longer served.
+func (s *PLNInquiryService) SetCacheConfig(config models.PLNInquiryConfig) error {
+	config.CacheKeyPrefix = strings.TrimSpace(config.CacheKeyPrefix)
+	if err := validateCacheConfig(config); err != nil {
+		return err
+	}
+
+	if s.configStore != nil {
+		if err := s.configStore.Save(config); err != nil {
+			s.logger.WithError(err).Error("Failed to persist PLN inquiry cache configuration")
+			return fmt.Errorf("failed to persist cache config: %w", err)
+		}
+	}
+
+	s.applyCacheConfig(config)
 	s.logger.WithFields(logrus.Fields{
-		"cache_enabled": config.CacheEnabled,
-		"cache_ttl":     config.CacheTTL,
+		"cache_enabled":          config.CacheEnabled,
+		"cache_ttl":              config.CacheTTL.String(),
+		"cache_soft_ttl":         config.CacheSoftTTL.String(),
+		"cache_key_prefix":       config.CacheKeyPrefix,
+		"negative_cache_enabled": config.NegativeCacheEnabled,
+		"negative_cache_ttl":     config.NegativeCacheTTL.String(),
 	}).Info("PLN inquiry cache configuration updated")
+	return nil
+}
+
+// applyCacheConfig replaces the active cache configuration
+func (s *PLNInquiryService) applyCacheConfig(config models.PLNInquiryConfig) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.config = config
+}
+
+// validateCacheConfig checks a cache configuration before it is applied
+func validateCacheConfig(config models.PLNInquiryConfig) error {
+	fieldErrors := validation.Errors{}
+	if config.CacheTTL < 0 {
+		fieldErrors["cache_ttl"] = "cache_ttl must not be negative; use 0 to keep entries until cleared"
+	} else if config.CacheTTL > 0 && config.CacheTTL < time.Second {
+		fieldErrors["cache_ttl"] = "cache_ttl must be at least 1s"
+	}
+
+	switch {
+	case config.CacheKeyPrefix == "":
+		fieldErrors["cache_key_prefix"] = "cache_key_prefix is required"
+	case len(config.CacheKeyPrefix) > maxCacheKeyPrefixLength:
+		fieldErrors["cache_key_prefix"] = fmt.Sprintf("cache_key_prefix must be at most %d characters", maxCacheKeyPrefixLength)
+	case strings.ContainsAny(config.CacheKeyPrefix, " \t\r\n"):
+		fieldErrors["cache_key_prefix"] = "cache_key_prefix must not contain whitespace"
+	}
+
+	if config.NegativeCacheEnabled && config.NegativeCacheTTL < time.Second {
+		fieldErrors["negative_cache_ttl"] = "negative_cache_ttl must be at least 1s when the negative cache is enabled"
+	} else if config.NegativeCacheTTL > maxNegativeCacheTTL {
+		fieldErrors["negative_cache_ttl"] = fmt.Sprintf("negative_cache_ttl must be at most %s", maxNegativeCacheTTL)
+	} else if config.NegativeCacheTTL < 0 {
+		fieldErrors["negative_cache_ttl"] = "negative_cache_ttl must not be negative"
+	}
+
+	switch {
+	case config.CacheSoftTTL < 0:
+		fieldErrors["cache_soft_ttl"] = "cache_soft_ttl must not be negative; use 0 to disable background refresh"
+	case config.CacheSoftTTL > 0 && config.CacheSoftTTL < time.Second:
+		fieldErrors["cache_soft_ttl"] = "cache_soft_ttl must be at least 1s"
+	case config.CacheSoftTTL > 0 && config.CacheTTL > 0 && config.CacheSoftTTL >= config.CacheTTL:
+		fieldErrors["cache_soft_ttl"] = "cache_soft_ttl must be shorter than cache_ttl"
+	}
+
+	if len(fieldErrors) > 0 {
+		return fieldErrors
+	}
+	return nil
 }
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"sync"
+
+	"gateway-digiflazz/internal/models"
+)
+
+// inflightInquiry is a Digiflazz PLN inquiry shared by concurrent requests
+type inflightInquiry struct {
+	wg   sync.WaitGroup
+	resp *models.PLNInquiryResponse
+	err  error
+}
+
+// errInquiryPanicked is the result followers receive when the shared call panicked
+var errInquiryPanicked = errors.New("pln inquiry panicked")
+
+// inquiryGroup deduplicates concurrent PLN inquiries for the same customer number
+type inquiryGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightInquiry
+}
+
+func newInquiryGroup() *inquiryGroup {
+	return &inquiryGroup{calls: make(map[string]*inflightInquiry)}
+}
+
+// do runs fn once per key among concurrent callers. Callers that joined an
+// in-flight call receive its result with leader set to false. If fn panics, the
+// followers receive errInquiryPanicked and the panic continues in the leader.
+func (g *inquiryGroup) do(key string, fn func() (*models.PLNInquiryResponse, error)) (resp *models.PLNInquiryResponse, err error, leader bool) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.resp, call.err, false
+	}
+	call := &inflightInquiry{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			call.resp, call.err = nil, errInquiryPanicked
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	call.resp, call.err = fn()
+	return call.resp, call.err, true
+}
+
+// inflight returns the number of keys with a call in progress
+func (g *inquiryGroup) inflight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.calls)
+}
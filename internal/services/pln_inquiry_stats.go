@@ -0,0 +1,306 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+)
+
+// latencyBucketBounds are the upper bounds of the PLN inquiry latency histogram buckets
+var latencyBucketBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// statsWindows are the sliding windows reported by GetStats
+var statsWindows = []struct {
+	name   string
+	length time.Duration
+}{
+	{"1m", time.Minute},
+	{"15m", 15 * time.Minute},
+	{"1h", time.Hour},
+}
+
+// rateSlotCount is the number of one-second slots kept for windowed rates
+const rateSlotCount = 3600
+
+// latencyHistogram is a fixed-bucket latency histogram safe for concurrent use
+type latencyHistogram struct {
+	mu     sync.Mutex
+	counts []int64
+	count  int64
+	total  time.Duration
+	max    time.Duration
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]int64, len(latencyBucketBounds)+1)}
+}
+
+// observe records one latency sample
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBucketBounds) && d > latencyBucketBounds[i] {
+		i++
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.count++
+	h.total += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// summary returns the sample count, mean and p50/p90/p99
+func (h *latencyHistogram) summary() models.LatencySummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count == 0 {
+		return models.LatencySummary{}
+	}
+	return models.LatencySummary{
+		Count:  h.count,
+		MeanMs: durationMs(h.total / time.Duration(h.count)),
+		P50Ms:  durationMs(h.quantile(0.50)),
+		P90Ms:  durationMs(h.quantile(0.90)),
+		P99Ms:  durationMs(h.quantile(0.99)),
+	}
+}
+
+// quantile estimates the q-th quantile by linear interpolation within its bucket.
+// Callers must hold h.mu.
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	rank := q * float64(h.count)
+	var seen int64
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		if float64(seen+c) >= rank {
+			lower := time.Duration(0)
+			if i > 0 {
+				lower = latencyBucketBounds[i-1]
+			}
+			upper := h.max
+			if i < len(latencyBucketBounds) && latencyBucketBounds[i] < upper {
+				upper = latencyBucketBounds[i]
+			}
+			if upper <= lower {
+				return upper
+			}
+			fraction := (rank - float64(seen)) / float64(c)
+			return lower + time.Duration(fraction*float64(upper-lower))
+		}
+		seen += c
+	}
+	return h.max
+}
+
+// rateSlot counts events within one second
+type rateSlot struct {
+	second       int64
+	requests     int64
+	cacheHits    int64
+	apiRequests  int64
+	coalesced    int64
+	negativeHits int64
+	errors       int64
+}
+
+// plnInquiryMetrics collects PLN inquiry statistics from concurrent requests
+type plnInquiryMetrics struct {
+	totalRequests int64
+	cacheHits     int64
+	cacheMisses   int64
+	apiRequests   int64
+	errorCount    int64
+	coalesced     int64
+	negativeHits  int64
+	negativeStore int64
+	staleHits     int64
+	refreshes     int64
+	refreshErrors int64
+	totalLatency  int64
+
+	cacheLatency *latencyHistogram
+	apiLatency   *latencyHistogram
+
+	mu    sync.Mutex
+	slots [rateSlotCount]rateSlot
+	now   func() time.Time
+}
+
+func newPLNInquiryMetrics() *plnInquiryMetrics {
+	return &plnInquiryMetrics{
+		cacheLatency: newLatencyHistogram(),
+		apiLatency:   newLatencyHistogram(),
+		now:          time.Now,
+	}
+}
+
+// recordRequest counts an inquiry that passed validation
+func (m *plnInquiryMetrics) recordRequest() {
+	atomic.AddInt64(&m.totalRequests, 1)
+	m.updateSlot(func(slot *rateSlot) { slot.requests++ })
+}
+
+// recordCacheHit counts a cache hit and its latency
+func (m *plnInquiryMetrics) recordCacheHit(d time.Duration) {
+	atomic.AddInt64(&m.cacheHits, 1)
+	atomic.AddInt64(&m.totalLatency, int64(d))
+	m.cacheLatency.observe(d)
+	m.updateSlot(func(slot *rateSlot) { slot.cacheHits++ })
+}
+
+// recordCacheMiss counts a cache miss
+func (m *plnInquiryMetrics) recordCacheMiss() {
+	atomic.AddInt64(&m.cacheMisses, 1)
+}
+
+// recordAPICall counts a Digiflazz call and its latency
+func (m *plnInquiryMetrics) recordAPICall(d time.Duration, failed bool) {
+	atomic.AddInt64(&m.apiRequests, 1)
+	atomic.AddInt64(&m.totalLatency, int64(d))
+	m.apiLatency.observe(d)
+	if failed {
+		atomic.AddInt64(&m.errorCount, 1)
+	}
+	m.updateSlot(func(slot *rateSlot) {
+		slot.apiRequests++
+		if failed {
+			slot.errors++
+		}
+	})
+}
+
+// recordCoalesced counts a request that shared another request's Digiflazz call
+func (m *plnInquiryMetrics) recordCoalesced(d time.Duration) {
+	atomic.AddInt64(&m.coalesced, 1)
+	atomic.AddInt64(&m.totalLatency, int64(d))
+	m.updateSlot(func(slot *rateSlot) { slot.coalesced++ })
+}
+
+// recordNegativeHit counts a request answered from the negative cache
+func (m *plnInquiryMetrics) recordNegativeHit(d time.Duration) {
+	atomic.AddInt64(&m.negativeHits, 1)
+	atomic.AddInt64(&m.totalLatency, int64(d))
+	m.updateSlot(func(slot *rateSlot) { slot.negativeHits++ })
+}
+
+// recordNegativeStore counts a "customer not found" result written to the negative cache
+func (m *plnInquiryMetrics) recordNegativeStore() {
+	atomic.AddInt64(&m.negativeStore, 1)
+}
+
+// recordStaleHit counts a cache hit older than the soft TTL
+func (m *plnInquiryMetrics) recordStaleHit() {
+	atomic.AddInt64(&m.staleHits, 1)
+}
+
+// recordRefresh counts a completed background refresh
+func (m *plnInquiryMetrics) recordRefresh(failed bool) {
+	atomic.AddInt64(&m.refreshes, 1)
+	if failed {
+		atomic.AddInt64(&m.refreshErrors, 1)
+	}
+}
+
+// updateSlot applies fn to the slot of the current second
+func (m *plnInquiryMetrics) updateSlot(fn func(slot *rateSlot)) {
+	second := m.now().Unix()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	slot := &m.slots[second%rateSlotCount]
+	if slot.second != second {
+		*slot = rateSlot{second: second}
+	}
+	fn(slot)
+}
+
+// window sums the slots of the last length
+func (m *plnInquiryMetrics) window(length time.Duration) models.PLNInquiryRateStats {
+	now := m.now().Unix()
+	oldest := now - int64(length/time.Second)
+
+	var rates models.PLNInquiryRateStats
+	m.mu.Lock()
+	for _, slot := range m.slots {
+		if slot.second > oldest && slot.second <= now {
+			rates.Requests += slot.requests
+			rates.CacheHits += slot.cacheHits
+			rates.APIRequests += slot.apiRequests
+			rates.CoalescedRequests += slot.coalesced
+			rates.NegativeCacheHits += slot.negativeHits
+			rates.ErrorCount += slot.errors
+		}
+	}
+	m.mu.Unlock()
+
+	rates.RequestsPerMinute = float64(rates.Requests) / length.Minutes()
+	rates.HitRatio = ratio(rates.CacheHits, rates.CacheHits+rates.APIRequests+rates.CoalescedRequests)
+	return rates
+}
+
+// snapshot returns a consistent-enough copy of the collected statistics
+func (m *plnInquiryMetrics) snapshot() *models.PLNInquiryStats {
+	stats := &models.PLNInquiryStats{
+		TotalRequests:           atomic.LoadInt64(&m.totalRequests),
+		CacheHits:               atomic.LoadInt64(&m.cacheHits),
+		CacheMisses:             atomic.LoadInt64(&m.cacheMisses),
+		APIRequests:             atomic.LoadInt64(&m.apiRequests),
+		ErrorCount:              atomic.LoadInt64(&m.errorCount),
+		CoalescedRequests:       atomic.LoadInt64(&m.coalesced),
+		NegativeCacheHits:       atomic.LoadInt64(&m.negativeHits),
+		NegativeCacheStores:     atomic.LoadInt64(&m.negativeStore),
+		StaleHits:               atomic.LoadInt64(&m.staleHits),
+		BackgroundRefreshes:     atomic.LoadInt64(&m.refreshes),
+		BackgroundRefreshErrors: atomic.LoadInt64(&m.refreshErrors),
+		Latency: models.PLNInquiryLatencyStats{
+			Cache: m.cacheLatency.summary(),
+			API:   m.apiLatency.summary(),
+		},
+		Rates: make(map[string]models.PLNInquiryRateStats, len(statsWindows)),
+	}
+
+	if served := stats.CacheHits + stats.APIRequests + stats.CoalescedRequests + stats.NegativeCacheHits; served > 0 {
+		stats.AverageResponseTime = time.Duration(atomic.LoadInt64(&m.totalLatency) / served)
+	}
+	stats.HitRatio = ratio(stats.CacheHits, stats.CacheHits+stats.CacheMisses)
+	for _, w := range statsWindows {
+		stats.Rates[w.name] = m.window(w.length)
+	}
+	return stats
+}
+
+// ratio returns part/total, or 0 when total is 0
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+// durationMs converts d to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
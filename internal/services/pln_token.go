@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/pkg/requestid"
+
+	"github.com/sirupsen/logrus"
+)
+
+// plnTokenLength is the number of digits in a PLN prepaid token
+const plnTokenLength = 20
+
+// plnTokenCategory is the category of PLN prepaid token purchases
+const plnTokenCategory = "PLN"
+
+// PurchasePLNToken purchases a PLN prepaid token, optionally validating the meter via
+// PLN inquiry first, and parses the token details from the Digiflazz SN
+func (s *OtomaxService) PurchasePLNToken(ctx context.Context, req models.OtomaxPLNTokenRequest) (*models.OtomaxTransactionResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithFields(logrus.Fields{
+		"ref_id":       req.RefID,
+		"customer_no":  req.CustomerNo,
+		"buyer_sku":    req.BuyerSKU,
+		"skip_inquiry": req.SkipInquiry,
+		"client":       req.ClientID,
+	}).Info("Processing PLN token purchase")
+
+	// Validate PLN meter number
+	customerNo, err := s.validators.Validate("pln", req.CustomerNo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the meter with PLN inquiry before spending deposit
+	customerName := ""
+	if !req.SkipInquiry && s.plnInquiryService != nil {
+		inquiry, err := s.plnInquiryService.InquiryPLN(ctx, models.PLNInquiryRequest{CustomerNo: customerNo}, req.RefID)
+		if err != nil {
+			log.WithError(err).WithField("ref_id", req.RefID).Warn("PLN inquiry before token purchase failed")
+			return nil, fmt.Errorf("PLN inquiry failed: %w", err)
+		}
+		customerName = inquiry.Data.Name
+	}
+
+	amount := 0.0
+	if req.Amount != "" {
+		amount, err = strconv.ParseFloat(req.Amount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount format")
+		}
+	}
+
+	transaction := &models.OtomaxTransaction{
+		RefID:      req.RefID,
+		CustomerNo: customerNo,
+		BuyerSKU:   req.BuyerSKU,
+		Amount:     amount,
+		Type:       "prabayar",
+		ResellerID: req.ResellerID,
+		ClientID:   req.ClientID,
+		Category:   plnTokenCategory,
+		Account:    s.digiflazzPool.Route(req.ResellerID, plnTokenCategory),
+		RequestID:  requestid.FromContext(ctx),
+		Status:     "pending",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	response, err := s.processPrabayarTransaction(ctx, transaction)
+	if err != nil {
+		log.WithError(err).Error("PLN token purchase failed")
+		transaction.Status = "failed"
+		transaction.Message = err.Error()
+		s.saveTransaction(ctx, transaction)
+		s.metrics.ObserveTransaction(transaction.BuyerSKU, transaction.Category, transaction.Status)
+		return nil, err
+	}
+
+	// Pending purchases get their token from the callback or a later status check
+	s.attachPLNToken(ctx, transaction, customerName)
+	response.PLNToken = transaction.PLNToken
+
+	s.signTransactionResponse(transaction, response)
+	s.saveTransaction(ctx, transaction)
+	s.metrics.ObserveTransaction(transaction.BuyerSKU, transaction.Category, transaction.Status)
+	log.WithField("ref_id", req.RefID).Info("PLN token purchase processed")
+	return response, nil
+}
+
+// attachPLNToken parses the token details of a successful PLN purchase from its SN.
+// An unparseable SN is logged and leaves the transaction without token details.
+func (s *OtomaxService) attachPLNToken(ctx context.Context, transaction *models.OtomaxTransaction, customerName string) {
+	if !strings.EqualFold(transaction.Category, plnTokenCategory) || transaction.Status != "success" ||
+		transaction.SN == "" || transaction.PLNToken != nil {
+		return
+	}
+
+	token, err := ParsePLNTokenSN(transaction.SN)
+	if err != nil {
+		requestid.Logger(ctx, s.logger).WithError(err).WithField("ref_id", transaction.RefID).Warn("Failed to parse PLN token SN")
+		return
+	}
+	if token.CustomerName == "" {
+		token.CustomerName = customerName
+	}
+	transaction.PLNToken = token
+}
+
+// ParsePLNTokenSN parses a Digiflazz PLN SN in the form token/name/tarif/daya/kWh.
+// Trailing fields are optional; the token must contain 20 digits. An unreadable kWh
+// value leaves KWh at zero rather than losing the token.
+func ParsePLNTokenSN(sn string) (*models.PLNTokenDetails, error) {
+	parts := strings.Split(sn, "/")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	token := digitsOf(parts[0])
+	if len(token) != plnTokenLength {
+		return nil, fmt.Errorf("PLN token must be %d digits, got %d", plnTokenLength, len(token))
+	}
+
+	details := &models.PLNTokenDetails{
+		Token:          token,
+		TokenFormatted: formatPLNToken(token),
+	}
+	if len(parts) > 1 {
+		details.CustomerName = parts[1]
+	}
+	if len(parts) > 2 {
+		details.Tariff = strings.ToUpper(parts[2])
+	}
+	if len(parts) > 3 {
+		details.Power = digitsOf(parts[3])
+	}
+	if len(parts) > 4 {
+		kwh := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(parts[4]), "kwh"))
+		kwh = strings.ReplaceAll(kwh, ",", ".")
+		if value, err := strconv.ParseFloat(kwh, 64); err == nil {
+			details.KWh = value
+		}
+	}
+
+	return details, nil
+}
+
+// formatPLNToken formats a token in groups of four digits separated by dashes
+func formatPLNToken(token string) string {
+	groups := make([]string, 0, len(token)/4+1)
+	for i := 0; i < len(token); i += 4 {
+		end := i + 4
+		if end > len(token) {
+			end = len(token)
+		}
+		groups = append(groups, token[i:end])
+	}
+	return strings.Join(groups, "-")
+}
+
+// digitsOf returns only the digits of value
+func digitsOf(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -1,10 +1,13 @@
 package services
 
 import (
+	"context"
 	"fmt"
 
 	"gateway-digiflazz/internal/models"
 	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/metrics"
+	"gateway-digiflazz/pkg/requestid"
 
 	"github.com/sirupsen/logrus"
 )
@@ -13,6 +16,7 @@ import (
 type PriceService struct {
 	digiflazzClient *digiflazz.Client
 	logger          *logrus.Logger
+	metrics         *metrics.Metrics
 }
 
 // NewPriceService creates a new price service
@@ -23,9 +27,16 @@ func NewPriceService(client *digiflazz.Client, logger *logrus.Logger) *PriceServ
 	}
 }
 
+// SetMetrics configures where listed products are registered, so their failed
+// transactions keep their SKU label
+func (s *PriceService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
 // GetPrices retrieves the price list
-func (s *PriceService) GetPrices(priceType string) (*models.PriceResponse, error) {
-	s.logger.WithField("type", priceType).Info("Retrieving price list")
+func (s *PriceService) GetPrices(ctx context.Context, priceType string) (*models.PriceResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithField("type", priceType).Info("Retrieving price list")
 
 	// Validate price type
 	if priceType != "" && priceType != "prabayar" && priceType != "pascabayar" {
@@ -33,22 +44,27 @@ func (s *PriceService) GetPrices(priceType string) (*models.PriceResponse, error
 	}
 
 	// Call Digiflazz API
-	resp, err := s.digiflazzClient.GetPrices(priceType)
+	resp, err := s.digiflazzClient.GetPrices(ctx, priceType)
 	if err != nil {
-		s.logger.WithError(err).Error("Digiflazz price API call failed")
+		log.WithError(err).Error("Digiflazz price API call failed")
 		return nil, fmt.Errorf("failed to get prices: %w", err)
 	}
 
-	s.logger.WithField("product_count", len(resp.Data)).Info("Price list retrieved successfully")
+	for _, product := range resp.Data {
+		s.metrics.AddKnownProduct(product.Code, product.Category)
+	}
+
+	log.WithField("product_count", len(resp.Data)).Info("Price list retrieved successfully")
 	return resp, nil
 }
 
 // GetProductByCode retrieves a specific product by code
-func (s *PriceService) GetProductByCode(code string) (*models.Product, error) {
-	s.logger.WithField("code", code).Info("Retrieving product by code")
+func (s *PriceService) GetProductByCode(ctx context.Context, code string) (*models.Product, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithField("code", code).Info("Retrieving product by code")
 
 	// Get all products first
-	resp, err := s.GetPrices("")
+	resp, err := s.GetPrices(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -56,21 +72,22 @@ func (s *PriceService) GetProductByCode(code string) (*models.Product, error) {
 	// Find product by code
 	for _, product := range resp.Data {
 		if product.Code == code {
-			s.logger.WithField("code", code).Info("Product found")
+			log.WithField("code", code).Info("Product found")
 			return &product, nil
 		}
 	}
 
-	s.logger.WithField("code", code).Warn("Product not found")
+	log.WithField("code", code).Warn("Product not found")
 	return nil, fmt.Errorf("product with code %s not found", code)
 }
 
 // GetProductsByCategory retrieves products by category
-func (s *PriceService) GetProductsByCategory(category string) ([]models.Product, error) {
-	s.logger.WithField("category", category).Info("Retrieving products by category")
+func (s *PriceService) GetProductsByCategory(ctx context.Context, category string) ([]models.Product, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithField("category", category).Info("Retrieving products by category")
 
 	// Get all products first
-	resp, err := s.GetPrices("")
+	resp, err := s.GetPrices(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +100,7 @@ func (s *PriceService) GetProductsByCategory(category string) ([]models.Product,
 		}
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"category": category,
 		"count":    len(products),
 	}).Info("Products retrieved by category")
@@ -1,11 +1,17 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"gateway-digiflazz/internal/config"
 	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/validation"
 	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/metrics"
+	"gateway-digiflazz/pkg/operator"
+	"gateway-digiflazz/pkg/requestid"
 
 	"github.com/sirupsen/logrus"
 )
@@ -14,6 +20,9 @@ import (
 type TransactionService struct {
 	digiflazzClient *digiflazz.Client
 	logger          *logrus.Logger
+	productResolver *operator.Resolver
+	validators      *validation.Registry
+	metrics         *metrics.Metrics
 }
 
 // NewTransactionService creates a new transaction service
@@ -21,31 +30,64 @@ func NewTransactionService(client *digiflazz.Client, logger *logrus.Logger) *Tra
 	return &TransactionService{
 		digiflazzClient: client,
 		logger:          logger,
+		productResolver: operator.NewResolver(nil),
+		validators:      validation.NewRegistry(config.ValidationConfig{}),
 	}
 }
 
+// SetValidators configures the customer number validator registry
+func (s *TransactionService) SetValidators(validators *validation.Registry) {
+	s.validators = validators
+}
+
+// SetProductResolver configures the resolver for generic product codes
+func (s *TransactionService) SetProductResolver(resolver *operator.Resolver) {
+	s.productResolver = resolver
+}
+
+// SetMetrics configures where transaction outcomes are recorded
+func (s *TransactionService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
 // Topup performs a topup transaction
-func (s *TransactionService) Topup(req models.TopupRequest) (*models.TopupResponse, error) {
-	s.logger.WithFields(logrus.Fields{
+func (s *TransactionService) Topup(ctx context.Context, req models.TopupRequest) (*models.TopupResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithFields(logrus.Fields{
 		"ref_id":      req.RefID,
 		"customer_no": req.CustomerNo,
 		"buyer_sku":   req.BuyerSKU,
 	}).Info("Processing topup transaction")
 
+	// Resolve generic product codes (e.g. PULSA10) to the operator specific SKU
+	category := ""
+	if s.productResolver.IsGeneric(req.BuyerSKU) {
+		resolution, err := s.productResolver.Resolve(req.BuyerSKU, req.CustomerNo)
+		if err != nil {
+			log.WithError(err).WithField("buyer_sku", req.BuyerSKU).Error("Failed to resolve generic product code")
+			return nil, fmt.Errorf("failed to resolve product %s: %w", req.BuyerSKU, err)
+		}
+		req.BuyerSKU = resolution.BuyerSKU
+		req.CustomerNo = resolution.CustomerNo
+		category = resolution.Category
+	}
+
 	// Validate request
-	if err := s.validateTopupRequest(req); err != nil {
-		s.logger.WithError(err).Error("Topup request validation failed")
+	if err := s.validateTopupRequest(&req); err != nil {
+		log.WithError(err).Error("Topup request validation failed")
 		return nil, err
 	}
 
 	// Call Digiflazz API
-	resp, err := s.digiflazzClient.Topup(req)
+	resp, err := s.digiflazzClient.Topup(ctx, req)
 	if err != nil {
-		s.logger.WithError(err).Error("Digiflazz topup API call failed")
+		log.WithError(err).Error("Digiflazz topup API call failed")
+		s.metrics.ObserveTransaction(req.BuyerSKU, category, "failed")
 		return nil, fmt.Errorf("failed to process topup: %w", err)
 	}
+	s.metrics.ObserveTransaction(req.BuyerSKU, category, mapDigiflazzStatus(resp.Data.Status))
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"ref_id": resp.Data.RefID,
 		"status": resp.Data.Status,
 		"rc":     resp.Data.RC,
@@ -55,27 +97,30 @@ func (s *TransactionService) Topup(req models.TopupRequest) (*models.TopupRespon
 }
 
 // Pay performs a payment transaction
-func (s *TransactionService) Pay(req models.PayRequest) (*models.PayResponse, error) {
-	s.logger.WithFields(logrus.Fields{
+func (s *TransactionService) Pay(ctx context.Context, req models.PayRequest) (*models.PayResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithFields(logrus.Fields{
 		"ref_id":      req.RefID,
 		"customer_no": req.CustomerNo,
 		"buyer_sku":   req.BuyerSKU,
 	}).Info("Processing payment transaction")
 
 	// Validate request
-	if err := s.validatePayRequest(req); err != nil {
-		s.logger.WithError(err).Error("Payment request validation failed")
+	if err := s.validatePayRequest(&req); err != nil {
+		log.WithError(err).Error("Payment request validation failed")
 		return nil, err
 	}
 
 	// Call Digiflazz API
-	resp, err := s.digiflazzClient.Pay(req)
+	resp, err := s.digiflazzClient.Pay(ctx, req)
 	if err != nil {
-		s.logger.WithError(err).Error("Digiflazz payment API call failed")
+		log.WithError(err).Error("Digiflazz payment API call failed")
+		s.metrics.ObserveTransaction(req.BuyerSKU, pascabayarCategory, "failed")
 		return nil, fmt.Errorf("failed to process payment: %w", err)
 	}
+	s.metrics.ObserveTransaction(req.BuyerSKU, pascabayarCategory, mapDigiflazzStatus(resp.Data.Status))
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"ref_id": resp.Data.RefID,
 		"status": resp.Data.Status,
 		"rc":     resp.Data.RC,
@@ -85,8 +130,9 @@ func (s *TransactionService) Pay(req models.PayRequest) (*models.PayResponse, er
 }
 
 // GetStatus checks the transaction status
-func (s *TransactionService) GetStatus(refID string) (*models.StatusResponse, error) {
-	s.logger.WithField("ref_id", refID).Info("Checking transaction status")
+func (s *TransactionService) GetStatus(ctx context.Context, refID string) (*models.StatusResponse, error) {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithField("ref_id", refID).Info("Checking transaction status")
 
 	// Validate refID
 	if refID == "" {
@@ -94,13 +140,13 @@ func (s *TransactionService) GetStatus(refID string) (*models.StatusResponse, er
 	}
 
 	// Call Digiflazz API
-	resp, err := s.digiflazzClient.CheckStatus(refID)
+	resp, err := s.digiflazzClient.CheckStatus(ctx, refID)
 	if err != nil {
-		s.logger.WithError(err).Error("Digiflazz status check API call failed")
+		log.WithError(err).Error("Digiflazz status check API call failed")
 		return nil, fmt.Errorf("failed to check status: %w", err)
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"ref_id": resp.Data.RefID,
 		"status": resp.Data.Status,
 		"rc":     resp.Data.RC,
@@ -110,8 +156,9 @@ func (s *TransactionService) GetStatus(refID string) (*models.StatusResponse, er
 }
 
 // ProcessWebhook processes incoming webhook from Digiflazz
-func (s *TransactionService) ProcessWebhook(webhook models.WebhookRequest) error {
-	s.logger.WithFields(logrus.Fields{
+func (s *TransactionService) ProcessWebhook(ctx context.Context, webhook models.WebhookRequest) error {
+	log := requestid.Logger(ctx, s.logger)
+	log.WithFields(logrus.Fields{
 		"ref_id": webhook.RefID,
 		"status": webhook.Status,
 		"rc":     webhook.RC,
@@ -119,7 +166,7 @@ func (s *TransactionService) ProcessWebhook(webhook models.WebhookRequest) error
 
 	// Validate webhook signature
 	if !s.digiflazzClient.ValidateWebhook(webhook) {
-		s.logger.Error("Invalid webhook signature")
+		log.Error("Invalid webhook signature")
 		return fmt.Errorf("invalid webhook signature")
 	}
 
@@ -127,36 +174,47 @@ func (s *TransactionService) ProcessWebhook(webhook models.WebhookRequest) error
 	// TODO: Send notification to user
 	// TODO: Update internal systems
 
-	s.logger.WithField("ref_id", webhook.RefID).Info("Webhook processed successfully")
+	log.WithField("ref_id", webhook.RefID).Info("Webhook processed successfully")
 	return nil
 }
 
-// validateTopupRequest validates topup request
-func (s *TransactionService) validateTopupRequest(req models.TopupRequest) error {
-	if req.RefID == "" {
-		return fmt.Errorf("ref_id is required")
-	}
-	if req.CustomerNo == "" {
-		return fmt.Errorf("customer_no is required")
+// validateTopupRequest validates topup request and normalises its customer number
+func (s *TransactionService) validateTopupRequest(req *models.TopupRequest) error {
+	customerNo, err := s.validateTransactionFields(req.RefID, req.CustomerNo, req.BuyerSKU)
+	if err != nil {
+		return err
 	}
-	if req.BuyerSKU == "" {
-		return fmt.Errorf("buyer_sku is required")
+	req.CustomerNo = customerNo
+	return nil
+}
+
+// validatePayRequest validates payment request and normalises its customer number
+func (s *TransactionService) validatePayRequest(req *models.PayRequest) error {
+	customerNo, err := s.validateTransactionFields(req.RefID, req.CustomerNo, req.BuyerSKU)
+	if err != nil {
+		return err
 	}
+	req.CustomerNo = customerNo
 	return nil
 }
 
-// validatePayRequest validates payment request
-func (s *TransactionService) validatePayRequest(req models.PayRequest) error {
-	if req.RefID == "" {
-		return fmt.Errorf("ref_id is required")
+// validateTransactionFields checks required fields and validates the customer number for the SKU
+func (s *TransactionService) validateTransactionFields(refID, customerNo, buyerSKU string) (string, error) {
+	fieldErrors := validation.Errors{}
+	if refID == "" {
+		fieldErrors["ref_id"] = "ref_id is required"
 	}
-	if req.CustomerNo == "" {
-		return fmt.Errorf("customer_no is required")
+	if customerNo == "" {
+		fieldErrors["customer_no"] = "customer_no is required"
 	}
-	if req.BuyerSKU == "" {
-		return fmt.Errorf("buyer_sku is required")
+	if buyerSKU == "" {
+		fieldErrors["buyer_sku"] = "buyer_sku is required"
 	}
-	return nil
+	if len(fieldErrors) > 0 {
+		return "", fieldErrors
+	}
+
+	return s.validators.ValidateCustomerNo(buyerSKU, "", customerNo)
 }
 
 // CreateTransaction creates a new transaction record
@@ -0,0 +1,163 @@
+package validation
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"gateway-digiflazz/internal/config"
+)
+
+// Errors maps request fields to validation messages
+type Errors map[string]string
+
+// Error implements the error interface
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field+": "+e[field])
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// AsErrors extracts field errors from err
+func AsErrors(err error) (Errors, bool) {
+	var fieldErrors Errors
+	if errors.As(err, &fieldErrors) {
+		return fieldErrors, true
+	}
+	return nil, false
+}
+
+// Validator validates a customer number and returns its normalised form
+type Validator interface {
+	Validate(customerNo string) (string, error)
+}
+
+// ValidatorFunc adapts a function to the Validator interface
+type ValidatorFunc func(customerNo string) (string, error)
+
+// Validate calls f(customerNo)
+func (f ValidatorFunc) Validate(customerNo string) (string, error) {
+	return f(customerNo)
+}
+
+// defaultSKUPrefixes maps the buyer_sku prefixes of common Digiflazz products to a
+// validator key, so requests without a category are still validated. Configured
+// prefixes override them; a prefix configured with an empty key is not validated.
+var defaultSKUPrefixes = map[string]string{
+	"pln":     "pln",
+	"tsel":    "pulsa",
+	"isat":    "pulsa",
+	"xl":      "pulsa",
+	"axis":    "pulsa",
+	"three":   "pulsa",
+	"smart":   "pulsa",
+	"dana":    "dana",
+	"ovo":     "ovo",
+	"gopay":   "gopay",
+	"shopee":  "shopee pay",
+	"linkaja": "linkaja",
+	"ml":      "mobile legends",
+	"ff":      "free fire",
+	"bpjs":    "bpjs",
+	"pdam":    "pdam",
+}
+
+// Registry holds customer number validators keyed by product category or brand
+type Registry struct {
+	mu          sync.RWMutex
+	validators  map[string]Validator
+	skuPrefixes map[string]string
+}
+
+// NewRegistry creates a registry with the built-in validators and the configured rules
+func NewRegistry(cfg config.ValidationConfig) *Registry {
+	r := &Registry{
+		validators:  make(map[string]Validator),
+		skuPrefixes: make(map[string]string),
+	}
+
+	registerBuiltins(r)
+
+	for _, rule := range cfg.Lengths {
+		r.Register(rule.Key, NumericLength(rule.Key, rule.Prefix, rule.MinLength, rule.MaxLength))
+	}
+	for prefix, key := range defaultSKUPrefixes {
+		r.skuPrefixes[prefix] = key
+	}
+	for prefix, key := range cfg.SKUPrefixes {
+		r.skuPrefixes[strings.ToLower(prefix)] = normalizeKey(key)
+	}
+
+	return r
+}
+
+// Register adds or replaces the validator for a category or brand
+func (r *Registry) Register(key string, validator Validator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.validators[normalizeKey(key)] = validator
+}
+
+// Validate validates customerNo with the validator registered for key.
+// Customer numbers for keys without a validator are returned unchanged.
+func (r *Registry) Validate(key, customerNo string) (string, error) {
+	r.mu.RLock()
+	validator, ok := r.validators[normalizeKey(key)]
+	r.mu.RUnlock()
+	if !ok {
+		return customerNo, nil
+	}
+
+	normalized, err := validator.Validate(customerNo)
+	if err != nil {
+		return "", Errors{"customer_no": err.Error()}
+	}
+	return normalized, nil
+}
+
+// ValidateCustomerNo validates customerNo for a transaction. A category supplied by
+// the caller takes precedence when a validator is registered for it; otherwise the
+// brand mapped from the buyer_sku prefix is used.
+func (r *Registry) ValidateCustomerNo(buyerSKU, category, customerNo string) (string, error) {
+	if r.hasValidator(category) {
+		return r.Validate(category, customerNo)
+	}
+	if key := r.keyForSKU(buyerSKU); key != "" {
+		return r.Validate(key, customerNo)
+	}
+	return customerNo, nil
+}
+
+// hasValidator reports whether a validator is registered for key
+func (r *Registry) hasValidator(key string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.validators[normalizeKey(key)]
+	return ok
+}
+
+// keyForSKU returns the validator key of the longest configured prefix matching buyerSKU
+func (r *Registry) keyForSKU(buyerSKU string) string {
+	sku := strings.ToLower(buyerSKU)
+	match := ""
+	for prefix := range r.skuPrefixes {
+		if strings.HasPrefix(sku, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	return r.skuPrefixes[match]
+}
+
+// normalizeKey lower-cases and trims a category or brand name
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gateway-digiflazz/pkg/operator"
+)
+
+var (
+	digitsOnly     = regexp.MustCompile(`^[0-9]+$`)
+	gameIDPattern  = regexp.MustCompile(`^[A-Za-z0-9]{4,20}$`)
+	mlIDWithZone   = regexp.MustCompile(`^([0-9]{6,10})\s*[\(\|\+\s-]\s*([0-9]{4,5})\)?$`)
+	mlConcatenated = regexp.MustCompile(`^[0-9]{10,15}$`)
+)
+
+// registerBuiltins registers the validators shipped with the gateway
+func registerBuiltins(r *Registry) {
+	for _, key := range []string{"pln", "pln prabayar", "pln pascabayar"} {
+		r.Register(key, ValidatorFunc(validatePLN))
+	}
+	for _, key := range []string{"pulsa", "data", "paket sms & telpon", "masa aktif"} {
+		r.Register(key, ValidatorFunc(validateMobileNumber))
+	}
+	for _, key := range []string{"e-money", "dana", "ovo", "go pay", "gopay", "shopee pay", "linkaja"} {
+		r.Register(key, ValidatorFunc(validateEWallet))
+	}
+	r.Register("games", ValidatorFunc(validateGameID))
+	r.Register("mobile legends", ValidatorFunc(validateMobileLegends))
+	r.Register("free fire", NumericLength("Free Fire ID", "", 6, 12))
+	for _, key := range []string{"bpjs", "bpjs kesehatan"} {
+		r.Register(key, NumericLength("BPJS virtual account", "8888", 16, 16))
+	}
+	r.Register("pdam", NumericLength("PDAM customer number", "", 4, 20))
+}
+
+// NumericLength returns a validator for digit-only numbers with an optional prefix and length bounds
+func NumericLength(label, prefix string, minLength, maxLength int) Validator {
+	return ValidatorFunc(func(customerNo string) (string, error) {
+		number := stripSeparators(customerNo)
+		if !digitsOnly.MatchString(number) {
+			return "", fmt.Errorf("%s must contain digits only", label)
+		}
+		if prefix != "" && !strings.HasPrefix(number, prefix) {
+			return "", fmt.Errorf("%s must start with %s", label, prefix)
+		}
+		if len(number) < minLength || (maxLength > 0 && len(number) > maxLength) {
+			if minLength == maxLength {
+				return "", fmt.Errorf("%s must be %d digits", label, minLength)
+			}
+			return "", fmt.Errorf("%s must be %d-%d digits", label, minLength, maxLength)
+		}
+		return number, nil
+	})
+}
+
+// validatePLN accepts the 10-15 digit meter numbers and ID pelanggan the gateway has
+// always taken. Stricter rules can be configured with a validation.lengths entry for "pln".
+func validatePLN(customerNo string) (string, error) {
+	number := stripSeparators(customerNo)
+	if !digitsOnly.MatchString(number) {
+		return "", fmt.Errorf("PLN customer number must contain digits only")
+	}
+	if len(number) < 10 || len(number) > 15 {
+		return "", fmt.Errorf("PLN customer number must be 10-15 digits")
+	}
+	return number, nil
+}
+
+// validateMobileNumber accepts numbers of a supported mobile operator
+func validateMobileNumber(customerNo string) (string, error) {
+	_, normalized, err := operator.Detect(customerNo)
+	if err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
+
+// validateEWallet accepts phone numbers registered to an e-wallet account
+func validateEWallet(customerNo string) (string, error) {
+	normalized, err := operator.Normalize(customerNo)
+	if err != nil {
+		return "", fmt.Errorf("e-wallet account must be a phone number in 08, 62 or +62 format")
+	}
+	if len(normalized) < 10 || len(normalized) > 13 {
+		return "", fmt.Errorf("e-wallet phone number must be 10-13 digits")
+	}
+	return normalized, nil
+}
+
+// validateGameID accepts alphanumeric game user IDs
+func validateGameID(customerNo string) (string, error) {
+	id := strings.TrimSpace(customerNo)
+	if !gameIDPattern.MatchString(id) {
+		return "", fmt.Errorf("game ID must be 4-20 letters or digits")
+	}
+	return id, nil
+}
+
+// validateMobileLegends accepts "userid(zone)", "userid zone", "userid|zone" or
+// the concatenated form and returns the concatenated form Digiflazz expects
+func validateMobileLegends(customerNo string) (string, error) {
+	value := strings.TrimSpace(customerNo)
+	if matches := mlIDWithZone.FindStringSubmatch(value); matches != nil {
+		return matches[1] + matches[2], nil
+	}
+	if mlConcatenated.MatchString(value) {
+		return value, nil
+	}
+	return "", fmt.Errorf("Mobile Legends ID must be the user ID followed by the zone ID, e.g. 12345678(1234)")
+}
+
+// stripSeparators removes spaces and dashes commonly typed in account numbers
+func stripSeparators(value string) string {
+	return strings.NewReplacer(" ", "", "-", "", ".", "").Replace(strings.TrimSpace(value))
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+)
+
+// ErrNotFound is returned by Get when a key is missing or expired
+var ErrNotFound = errors.New("key not found")
+
+// Cache is implemented by every cache backend
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	ClearAll(ctx context.Context) error
+	DeleteExpired(ctx context.Context) error
+	GetStats(ctx context.Context) (map[string]interface{}, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Entry is a live cache entry returned by Scan; a zero ExpiresAt never expires
+type Entry struct {
+	Key       string
+	Value     string
+	ExpiresAt time.Time
+}
+
+// Scanner is implemented by backends that can enumerate their live entries.
+// Scan calls fn for every entry whose key starts with prefix.
+type Scanner interface {
+	Scan(ctx context.Context, prefix string, fn func(Entry) error) error
+}
+
+// EntryGetter is implemented by backends that can return a live entry together with
+// its expiry, so an entry copied elsewhere does not outlive the original
+type EntryGetter interface {
+	GetEntry(ctx context.Context, key string) (Entry, error)
+}
+
+// ErrScanUnsupported is returned when a backend cannot enumerate its entries
+var ErrScanUnsupported = errors.New("cache backend does not support scanning")
+
+// Supported cache backends
+const (
+	TypeSQLite = "sqlite"
+	TypeRedis  = "redis"
+	TypeMemory = "memory"
+)
+
+// New creates the cache backend selected by cfg.Type, wrapped in an in-memory
+// LRU tier when cfg.MemoryTier is set
+func New(cfg config.CacheConfig, redisCfg config.RedisConfig) (Cache, error) {
+	backend, err := newBackend(cfg, redisCfg)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.MemoryTier || strings.EqualFold(cfg.Type, TypeMemory) {
+		return backend, nil
+	}
+
+	return NewTieredCache(backend, TieredOptions{
+		MaxEntries: cfg.MemoryMaxEntries,
+		MaxBytes:   cfg.MemoryMaxBytes,
+		TTL:        cfg.MemoryTTL,
+	}), nil
+}
+
+// newBackend creates the persistent cache backend selected by cfg.Type
+func newBackend(cfg config.CacheConfig, redisCfg config.RedisConfig) (Cache, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "", TypeSQLite:
+		if cfg.SQLitePath == "" {
+			return nil, fmt.Errorf("sqlite cache requires a database path")
+		}
+		return NewSQLiteCacheWithOptions(cfg.SQLitePath, SQLiteOptions{
+			MaxOpenConns: cfg.SQLiteMaxOpenConns,
+			BusyTimeout:  cfg.SQLiteBusyTimeout,
+		})
+	case TypeRedis:
+		host := redisCfg.Host
+		if host == "" {
+			host = "localhost"
+		}
+		port := redisCfg.Port
+		if port == 0 {
+			port = 6379
+		}
+
+		redisCache := NewRedisCacheWithOptions(RedisOptions{
+			Addr:         host + ":" + strconv.Itoa(port),
+			Password:     redisCfg.Password,
+			DB:           redisCfg.DB,
+			PoolSize:     redisCfg.PoolSize,
+			MinIdleConns: redisCfg.MinIdleConnections,
+			KeyPrefix:    cfg.KeyPrefix,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := redisCache.Ping(ctx); err != nil {
+			redisCache.Close()
+			return nil, fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		return redisCache, nil
+	case TypeMemory:
+		return NewMemoryCache(), nil
+	default:
+		return nil, fmt.Errorf("unsupported cache type %q (expected sqlite, redis or memory)", cfg.Type)
+	}
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryEntry is a value stored by MemoryCache
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// expired reports whether the entry has expired at now; entries without expiry never expire
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryCache implements CacheInterface in process memory. Data is lost on restart.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+// NewMemoryCache creates a new in-memory cache instance
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry)}
+}
+
+// Get retrieves a value from cache
+func (m *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+	if !ok || entry.expired(time.Now()) {
+		return "", ErrNotFound
+	}
+	return entry.value, nil
+}
+
+// GetEntry retrieves a value from cache with its expiry
+func (m *MemoryCache) GetEntry(ctx context.Context, key string) (Entry, error) {
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+	if !ok || entry.expired(time.Now()) {
+		return Entry{}, ErrNotFound
+	}
+	return Entry{Key: key, Value: entry.value, ExpiresAt: entry.expiresAt}, nil
+}
+
+// Set stores a value in cache with TTL; a zero TTL never expires
+func (m *MemoryCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	m.entries[key] = entry
+	m.mu.Unlock()
+	return nil
+}
+
+// Delete removes a value from cache
+func (m *MemoryCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// DeleteExpired removes expired entries from cache
+func (m *MemoryCache) DeleteExpired(ctx context.Context) error {
+	now := time.Now()
+	m.mu.Lock()
+	for key, entry := range m.entries {
+		if entry.expired(now) {
+			delete(m.entries, key)
+		}
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// ClearAll removes all entries from cache
+func (m *MemoryCache) ClearAll(ctx context.Context) error {
+	m.mu.Lock()
+	m.entries = make(map[string]memoryEntry)
+	m.mu.Unlock()
+	return nil
+}
+
+// GetStats returns cache statistics
+func (m *MemoryCache) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	now := time.Now()
+	expired := 0
+
+	m.mu.RLock()
+	total := len(m.entries)
+	for _, entry := range m.entries {
+		if entry.expired(now) {
+			expired++
+		}
+	}
+	m.mu.RUnlock()
+
+	return map[string]interface{}{
+		"backend":         TypeMemory,
+		"total_entries":   total,
+		"expired_entries": expired,
+		"active_entries":  total - expired,
+	}, nil
+}
+
+// Scan calls fn for every live entry whose key starts with prefix, in key order
+func (m *MemoryCache) Scan(ctx context.Context, prefix string, fn func(Entry) error) error {
+	now := time.Now()
+	var entries []Entry
+
+	m.mu.RLock()
+	for key, entry := range m.entries {
+		if strings.HasPrefix(key, prefix) && !entry.expired(now) {
+			entries = append(entries, Entry{Key: key, Value: entry.value, ExpiresAt: entry.expiresAt})
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping always succeeds for the in-memory cache
+func (m *MemoryCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close releases nothing for the in-memory cache
+func (m *MemoryCache) Close() error {
+	return nil
+}
@@ -2,42 +2,223 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// redisScanCount is the number of keys requested per SCAN iteration
+const redisScanCount = 500
+
+// RedisOptions configures a RedisCache
+type RedisOptions struct {
+	Addr         string
+	Password     string
+	DB           int
+	PoolSize     int
+	MinIdleConns int
+	// KeyPrefix scopes every key of this cache so ClearAll and GetStats leave other data alone
+	KeyPrefix string
+}
+
 // RedisCache implements CacheInterface using Redis
 type RedisCache struct {
-	client *redis.Client
+	client    *redis.Client
+	keyPrefix string
 }
 
 // NewRedisCache creates a new Redis cache instance
 func NewRedisCache(addr, password string, db int) *RedisCache {
-	rdb := redis.NewClient(&redis.Options{
+	return NewRedisCacheWithOptions(RedisOptions{
 		Addr:     addr,
 		Password: password,
 		DB:       db,
 	})
+}
+
+// NewRedisCacheWithOptions creates a new Redis cache instance from opts
+func NewRedisCacheWithOptions(opts RedisOptions) *RedisCache {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:         opts.Addr,
+		Password:     opts.Password,
+		DB:           opts.DB,
+		PoolSize:     opts.PoolSize,
+		MinIdleConns: opts.MinIdleConns,
+	})
 
 	return &RedisCache{
-		client: rdb,
+		client:    rdb,
+		keyPrefix: opts.KeyPrefix,
 	}
 }
 
 // Get retrieves a value from cache
 func (r *RedisCache) Get(ctx context.Context, key string) (string, error) {
-	return r.client.Get(ctx, key).Result()
+	value, err := r.client.Get(ctx, r.keyPrefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
 }
 
-// Set stores a value in cache with TTL
+// GetEntry retrieves a value from cache with its expiry
+func (r *RedisCache) GetEntry(ctx context.Context, key string) (Entry, error) {
+	pipe := r.client.Pipeline()
+	value := pipe.Get(ctx, r.keyPrefix+key)
+	ttl := pipe.PTTL(ctx, r.keyPrefix+key)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return Entry{}, err
+	}
+	if errors.Is(value.Err(), redis.Nil) {
+		return Entry{}, ErrNotFound
+	}
+
+	entry := Entry{Key: key, Value: value.Val()}
+	if remaining := ttl.Val(); remaining > 0 {
+		entry.ExpiresAt = time.Now().Add(remaining)
+	}
+	return entry, nil
+}
+
+// Set stores a value in cache with TTL; a zero TTL never expires
 func (r *RedisCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
-	return r.client.Set(ctx, key, value, ttl).Err()
+	return r.client.Set(ctx, r.keyPrefix+key, value, ttl).Err()
 }
 
 // Delete removes a value from cache
 func (r *RedisCache) Delete(ctx context.Context, key string) error {
-	return r.client.Del(ctx, key).Err()
+	return r.client.Del(ctx, r.keyPrefix+key).Err()
+}
+
+// DeleteExpired is a no-op because Redis evicts expired keys itself
+func (r *RedisCache) DeleteExpired(ctx context.Context) error {
+	return nil
+}
+
+// ClearAll removes every key under the cache prefix using SCAN, never FLUSHDB.
+// Passes repeat until one finds nothing, since deleting while scanning may skip keys.
+func (r *RedisCache) ClearAll(ctx context.Context) error {
+	for {
+		deleted := 0
+		err := r.scan(ctx, func(keys []string) error {
+			deleted += len(keys)
+			return r.client.Del(ctx, keys...).Err()
+		})
+		if err != nil || deleted == 0 {
+			return err
+		}
+	}
+}
+
+// GetStats returns key counts under the cache prefix and server memory usage
+func (r *RedisCache) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	total := 0
+	persistent := 0
+	err := r.scan(ctx, func(keys []string) error {
+		total += len(keys)
+
+		pipe := r.client.Pipeline()
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, key := range keys {
+			ttls[i] = pipe.TTL(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		for _, ttl := range ttls {
+			if ttl.Val() < 0 {
+				persistent++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]interface{}{
+		"backend":            TypeRedis,
+		"key_prefix":         r.keyPrefix,
+		"total_entries":      total,
+		"active_entries":     total,
+		"expired_entries":    0,
+		"persistent_entries": persistent,
+	}
+
+	if dbSize, err := r.client.DBSize(ctx).Result(); err == nil {
+		stats["db_keys"] = dbSize
+	}
+	// INFO is optional; managed Redis services may restrict it
+	if info, err := r.client.Info(ctx, "memory").Result(); err == nil {
+		if usedMemory, ok := parseRedisInfo(info)["used_memory"]; ok {
+			if bytes, err := strconv.ParseInt(usedMemory, 10, 64); err == nil {
+				stats["used_memory_bytes"] = bytes
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// Scan calls fn for every entry whose key starts with prefix
+func (r *RedisCache) Scan(ctx context.Context, prefix string, fn func(Entry) error) error {
+	return r.scanPattern(ctx, escapeRedisPattern(r.keyPrefix+prefix)+"*", func(keys []string) error {
+		pipe := r.client.Pipeline()
+		values := make([]*redis.StringCmd, len(keys))
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, key := range keys {
+			values[i] = pipe.Get(ctx, key)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		now := time.Now()
+		for i, key := range keys {
+			value, err := values[i].Result()
+			if err != nil {
+				// Expired or deleted since SCAN returned it
+				continue
+			}
+			entry := Entry{Key: strings.TrimPrefix(key, r.keyPrefix), Value: value}
+			if ttl := ttls[i].Val(); ttl > 0 {
+				entry.ExpiresAt = now.Add(ttl)
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// scan calls fn with batches of keys under the cache prefix
+func (r *RedisCache) scan(ctx context.Context, fn func(keys []string) error) error {
+	return r.scanPattern(ctx, escapeRedisPattern(r.keyPrefix)+"*", fn)
+}
+
+// scanPattern calls fn with batches of keys matching pattern
+func (r *RedisCache) scanPattern(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, pattern, redisScanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
 }
 
 // Ping tests the connection to Redis
@@ -49,3 +230,23 @@ func (r *RedisCache) Ping(ctx context.Context) error {
 func (r *RedisCache) Close() error {
 	return r.client.Close()
 }
+
+// escapeRedisPattern escapes glob metacharacters so the prefix matches literally
+func escapeRedisPattern(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(prefix)
+}
+
+// parseRedisInfo parses "key:value" lines of an INFO reply
+func parseRedisInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
@@ -4,137 +4,212 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// SQLiteCache implements CacheInterface using SQLite
+// DefaultNamespace is the namespace used by NewSQLiteCache; it holds PLN inquiry data
+const DefaultNamespace = "pln_inquiry"
+
+// Defaults for the SQLite connection pool
+const (
+	DefaultSQLiteMaxOpenConns = 8
+	DefaultSQLiteBusyTimeout  = 5 * time.Second
+)
+
+// SQLiteOptions configures the SQLite connection pool
+type SQLiteOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// BusyTimeout is how long a writer waits for the database lock
+	BusyTimeout time.Duration
+}
+
+// SQLiteCache implements CacheInterface using SQLite. Entries live in a generic
+// key/value table partitioned by namespace; each SQLiteCache is scoped to one namespace.
 type SQLiteCache struct {
-	db *sql.DB
+	db        *sql.DB
+	namespace string
+	owner     bool
 }
 
-// NewSQLiteCache creates a new SQLite cache instance
+// NewSQLiteCache creates a new SQLite cache instance scoped to DefaultNamespace
 func NewSQLiteCache(dbPath string) (*SQLiteCache, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	return NewSQLiteCacheWithOptions(dbPath, SQLiteOptions{})
+}
+
+// NewSQLiteCacheWithOptions opens dbPath in WAL mode, migrates the schema and
+// returns a cache scoped to DefaultNamespace
+func NewSQLiteCacheWithOptions(dbPath string, opts SQLiteOptions) (*SQLiteCache, error) {
+	if opts.MaxOpenConns <= 0 {
+		opts.MaxOpenConns = DefaultSQLiteMaxOpenConns
+	}
+	if opts.MaxIdleConns <= 0 || opts.MaxIdleConns > opts.MaxOpenConns {
+		opts.MaxIdleConns = opts.MaxOpenConns
+	}
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = DefaultSQLiteBusyTimeout
+	}
+
+	db, err := sql.Open("sqlite3", sqliteDSN(dbPath, opts))
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
 
-	cache := &SQLiteCache{db: db}
-	
-	// Create cache table if not exists
-	if err := cache.createTable(); err != nil {
+	cache := &SQLiteCache{db: db, namespace: DefaultNamespace, owner: true}
+	if err := cache.migrate(context.Background()); err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return cache, nil
 }
 
-// createTable creates the cache table
-func (s *SQLiteCache) createTable() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS pln_inquiry_cache (
-		customer_no TEXT PRIMARY KEY,
-		data TEXT NOT NULL,
-		created_at DATETIME NOT NULL,
-		expires_at DATETIME NOT NULL
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_expires_at ON pln_inquiry_cache(expires_at);
-	`
-	
-	_, err := s.db.Exec(query)
-	return err
+// sqliteDSN builds a go-sqlite3 DSN enabling WAL mode and a busy timeout on every connection
+func sqliteDSN(dbPath string, opts SQLiteOptions) string {
+	params := fmt.Sprintf("_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=%d", opts.BusyTimeout.Milliseconds())
+	if strings.Contains(dbPath, "?") {
+		return dbPath + "&" + params
+	}
+	return "file:" + dbPath + "?" + params
+}
+
+// Namespace returns a cache sharing this database but scoped to namespace.
+// Closing it does not close the shared database.
+func (s *SQLiteCache) Namespace(namespace string) *SQLiteCache {
+	return &SQLiteCache{db: s.db, namespace: namespace}
 }
 
 // Get retrieves a value from cache
 func (s *SQLiteCache) Get(ctx context.Context, key string) (string, error) {
-	// Handle permanent cache (TTL = 0) by checking expires_at with timezone support
-	query := `SELECT data FROM pln_inquiry_cache WHERE customer_no = ? AND (expires_at > ? OR expires_at LIKE '0001-01-01%')`
-	
+	query := `SELECT value FROM cache_entries WHERE namespace = ? AND key = ? AND (expires_at IS NULL OR expires_at > ?)`
+
 	var data string
-	err := s.db.QueryRowContext(ctx, query, key, time.Now()).Scan(&data)
+	err := s.db.QueryRowContext(ctx, query, s.namespace, key, time.Now().UnixNano()).Scan(&data)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", fmt.Errorf("key not found")
+			return "", ErrNotFound
 		}
 		return "", err
 	}
-	
+
 	return data, nil
 }
 
-// Set stores a value in cache with TTL
+// GetEntry retrieves a value from cache with its expiry
+func (s *SQLiteCache) GetEntry(ctx context.Context, key string) (Entry, error) {
+	query := `SELECT value, expires_at FROM cache_entries WHERE namespace = ? AND key = ? AND (expires_at IS NULL OR expires_at > ?)`
+
+	entry := Entry{Key: key}
+	var expiresAt sql.NullInt64
+	err := s.db.QueryRowContext(ctx, query, s.namespace, key, time.Now().UnixNano()).Scan(&entry.Value, &expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Entry{}, ErrNotFound
+		}
+		return Entry{}, err
+	}
+	if expiresAt.Valid {
+		entry.ExpiresAt = time.Unix(0, expiresAt.Int64)
+	}
+
+	return entry, nil
+}
+
+// Set stores a value in cache with TTL; a zero TTL never expires
 func (s *SQLiteCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
 	now := time.Now()
-	var expiresAt time.Time
-	
-	if ttl == 0 {
-		// Permanent cache - use zero time
-		expiresAt = time.Time{}
-	} else {
-		expiresAt = now.Add(ttl)
-	}
-	
+	var expiresAt interface{}
+	if ttl > 0 {
+		expiresAt = now.Add(ttl).UnixNano()
+	}
+
 	query := `
-	INSERT OR REPLACE INTO pln_inquiry_cache (customer_no, data, created_at, expires_at)
-	VALUES (?, ?, ?, ?)
+	INSERT OR REPLACE INTO cache_entries (namespace, key, value, created_at, expires_at)
+	VALUES (?, ?, ?, ?, ?)
 	`
-	
-	_, err := s.db.ExecContext(ctx, query, key, value, now, expiresAt)
+
+	_, err := s.db.ExecContext(ctx, query, s.namespace, key, value, now.UnixNano(), expiresAt)
 	return err
 }
 
 // Delete removes a value from cache
 func (s *SQLiteCache) Delete(ctx context.Context, key string) error {
-	query := `DELETE FROM pln_inquiry_cache WHERE customer_no = ?`
-	_, err := s.db.ExecContext(ctx, query, key)
+	query := `DELETE FROM cache_entries WHERE namespace = ? AND key = ?`
+	_, err := s.db.ExecContext(ctx, query, s.namespace, key)
 	return err
 }
 
-// DeleteExpired removes expired entries from cache
+// DeleteExpired removes expired entries of the namespace
 func (s *SQLiteCache) DeleteExpired(ctx context.Context) error {
-	query := `DELETE FROM pln_inquiry_cache WHERE expires_at <= ?`
-	_, err := s.db.ExecContext(ctx, query, time.Now())
+	query := `DELETE FROM cache_entries WHERE namespace = ? AND expires_at IS NOT NULL AND expires_at <= ?`
+	_, err := s.db.ExecContext(ctx, query, s.namespace, time.Now().UnixNano())
 	return err
 }
 
-// ClearAll removes all entries from cache
+// ClearAll removes all entries of the namespace
 func (s *SQLiteCache) ClearAll(ctx context.Context) error {
-	query := `DELETE FROM pln_inquiry_cache`
-	_, err := s.db.ExecContext(ctx, query)
+	query := `DELETE FROM cache_entries WHERE namespace = ?`
+	_, err := s.db.ExecContext(ctx, query, s.namespace)
 	return err
 }
 
-// GetStats returns cache statistics
+// GetStats returns statistics of the namespace
 func (s *SQLiteCache) GetStats(ctx context.Context) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
-	
-	// Total entries
-	var total int
-	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pln_inquiry_cache`).Scan(&total)
-	if err != nil {
+	stats["backend"] = TypeSQLite
+	stats["namespace"] = s.namespace
+
+	var total, expired, persistent int
+	query := `
+	SELECT
+		COUNT(*),
+		COALESCE(SUM(CASE WHEN expires_at IS NOT NULL AND expires_at <= ? THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN expires_at IS NULL THEN 1 ELSE 0 END), 0)
+	FROM cache_entries WHERE namespace = ?
+	`
+	if err := s.db.QueryRowContext(ctx, query, time.Now().UnixNano(), s.namespace).Scan(&total, &expired, &persistent); err != nil {
 		return nil, err
 	}
 	stats["total_entries"] = total
-	
-	// Expired entries
-	var expired int
-	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pln_inquiry_cache WHERE expires_at <= ?`, time.Now()).Scan(&expired)
-	if err != nil {
-		return nil, err
-	}
 	stats["expired_entries"] = expired
-	
-	// Active entries
 	stats["active_entries"] = total - expired
-	
+	stats["persistent_entries"] = persistent
+
 	return stats, nil
 }
 
-// Close closes the database connection
+// Namespaces returns the number of entries stored per namespace
+func (s *SQLiteCache) Namespaces(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT namespace, COUNT(*) FROM cache_entries GROUP BY namespace`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var namespace string
+		var count int
+		if err := rows.Scan(&namespace, &count); err != nil {
+			return nil, err
+		}
+		counts[namespace] = count
+	}
+	return counts, rows.Err()
+}
+
+// Close closes the database connection; namespaced views leave it open
 func (s *SQLiteCache) Close() error {
+	if !s.owner {
+		return nil
+	}
 	return s.db.Close()
 }
 
@@ -142,3 +217,51 @@ func (s *SQLiteCache) Close() error {
 func (s *SQLiteCache) Ping(ctx context.Context) error {
 	return s.db.PingContext(ctx)
 }
+
+// Scan calls fn for every live entry of the namespace whose key starts with prefix, in key order
+func (s *SQLiteCache) Scan(ctx context.Context, prefix string, fn func(Entry) error) error {
+	// A key range rather than a substring match, so the primary key index serves the prefix
+	keyRange, args := "key >= ?", []interface{}{s.namespace, prefix}
+	if upper := prefixUpperBound(prefix); upper != "" {
+		keyRange += " AND key < ?"
+		args = append(args, upper)
+	}
+	query := `
+	SELECT key, value, expires_at FROM cache_entries
+	WHERE namespace = ? AND ` + keyRange + ` AND (expires_at IS NULL OR expires_at > ?)
+	ORDER BY key
+	`
+	rows, err := s.db.QueryContext(ctx, query, append(args, time.Now().UnixNano())...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry Entry
+		var expiresAt sql.NullInt64
+		if err := rows.Scan(&entry.Key, &entry.Value, &expiresAt); err != nil {
+			return err
+		}
+		if expiresAt.Valid {
+			entry.ExpiresAt = time.Unix(0, expiresAt.Int64)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// prefixUpperBound returns the smallest key greater than every key starting with prefix,
+// or "" when there is none
+func prefixUpperBound(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
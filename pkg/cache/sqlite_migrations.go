@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// sqliteMigrations are applied in order; the schema version is the number applied
+var sqliteMigrations = []func(ctx context.Context, tx *sql.Tx) error{
+	createCacheEntries,
+	migratePLNInquiryCache,
+}
+
+// SchemaVersion returns the schema version of the cache database
+func (s *SQLiteCache) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// migrate brings the database schema up to date, one transaction per migration
+func (s *SQLiteCache) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	current, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for version := current + 1; version <= len(sqliteMigrations); version++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := sqliteMigrations[version-1](ctx, tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("cache schema migration %d failed: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_version (version, applied_at) VALUES (?, ?)`, version, time.Now().UnixNano()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createCacheEntries creates the generic namespaced key/value table.
+// Timestamps are unix nanoseconds; a NULL expires_at never expires.
+func createCacheEntries(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS cache_entries (
+		namespace TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER,
+		PRIMARY KEY (namespace, key)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_cache_entries_expires_at ON cache_entries(namespace, expires_at);
+	`)
+	return err
+}
+
+// migratePLNInquiryCache moves rows of the legacy pln_inquiry_cache table into DefaultNamespace
+func migratePLNInquiryCache(ctx context.Context, tx *sql.Tx) error {
+	var exists int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'pln_inquiry_cache'`).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT customer_no, data, created_at, expires_at FROM pln_inquiry_cache`)
+	if err != nil {
+		return err
+	}
+
+	type legacyRow struct {
+		key       string
+		value     string
+		createdAt time.Time
+		expiresAt time.Time
+	}
+	var legacy []legacyRow
+	for rows.Next() {
+		var row legacyRow
+		if err := rows.Scan(&row.key, &row.value, &row.createdAt, &row.expiresAt); err != nil {
+			rows.Close()
+			return err
+		}
+		legacy = append(legacy, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, row := range legacy {
+		// The legacy table stored permanent entries with a zero expiry
+		var expiresAt interface{}
+		if !row.expiresAt.IsZero() && row.expiresAt.Year() > 1 {
+			expiresAt = row.expiresAt.UnixNano()
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR REPLACE INTO cache_entries (namespace, key, value, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			DefaultNamespace, row.key, row.value, row.createdAt.UnixNano(), expiresAt); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DROP TABLE pln_inquiry_cache`)
+	return err
+}
@@ -0,0 +1,302 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for the in-memory tier
+const (
+	DefaultMemoryMaxEntries = 10000
+	DefaultMemoryMaxBytes   = 16 << 20
+	DefaultMemoryTTL        = 5 * time.Minute
+)
+
+// TieredOptions bounds the in-memory tier of a TieredCache
+type TieredOptions struct {
+	MaxEntries int
+	MaxBytes   int64
+	// TTL caps how long an entry is served from memory before the backend is consulted again
+	TTL time.Duration
+}
+
+// lruEntry is an entry of the in-memory tier
+type lruEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// size approximates the memory held by the entry
+func (e *lruEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// TieredCache serves reads from a bounded in-memory LRU in front of a persistent
+// backend. Writes and deletes go to both tiers.
+type TieredCache struct {
+	backend Cache
+	opts    TieredOptions
+
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+	bytes int64
+	// generation changes on every write, delete and clear. A read fills memory from
+	// the backend only if no write happened meanwhile, so it cannot bring back a value
+	// deleted or replaced while it was reading.
+	generation uint64
+
+	memoryHits    int64
+	memoryMisses  int64
+	backendHits   int64
+	backendMisses int64
+	evictions     int64
+}
+
+// NewTieredCache wraps backend with an in-memory LRU tier
+func NewTieredCache(backend Cache, opts TieredOptions) *TieredCache {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultMemoryMaxEntries
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMemoryMaxBytes
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultMemoryTTL
+	}
+
+	return &TieredCache{
+		backend: backend,
+		opts:    opts,
+		order:   list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+// Get retrieves a value from memory, falling back to the backend
+func (t *TieredCache) Get(ctx context.Context, key string) (string, error) {
+	if value, ok := t.getMemory(key); ok {
+		atomic.AddInt64(&t.memoryHits, 1)
+		return value, nil
+	}
+	atomic.AddInt64(&t.memoryMisses, 1)
+
+	t.mu.Lock()
+	generation := t.generation
+	t.mu.Unlock()
+
+	entry, err := t.getBackend(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			atomic.AddInt64(&t.backendMisses, 1)
+		}
+		return "", err
+	}
+	atomic.AddInt64(&t.backendHits, 1)
+
+	// Never serve an entry from memory past its backend expiry
+	ttl := t.opts.TTL
+	if !entry.ExpiresAt.IsZero() {
+		if remaining := time.Until(entry.ExpiresAt); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	if ttl > 0 {
+		t.fillMemory(key, entry.Value, ttl, generation)
+	}
+	return entry.Value, nil
+}
+
+// getBackend reads an entry from the backend, with its expiry when the backend reports it
+func (t *TieredCache) getBackend(ctx context.Context, key string) (Entry, error) {
+	if getter, ok := t.backend.(EntryGetter); ok {
+		return getter.GetEntry(ctx, key)
+	}
+	value, err := t.backend.Get(ctx, key)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Key: key, Value: value}, nil
+}
+
+// Set writes value through to the backend and then to memory
+func (t *TieredCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if err := t.backend.Set(ctx, key, value, ttl); err != nil {
+		t.deleteMemory(key)
+		return err
+	}
+
+	memoryTTL := t.opts.TTL
+	if ttl > 0 && ttl < memoryTTL {
+		memoryTTL = ttl
+	}
+	t.setMemory(key, value, memoryTTL)
+	return nil
+}
+
+// Delete removes a value from both tiers. Memory is cleared after the backend so a
+// concurrent read cannot refill it from the old backend value.
+func (t *TieredCache) Delete(ctx context.Context, key string) error {
+	err := t.backend.Delete(ctx, key)
+	t.deleteMemory(key)
+	return err
+}
+
+// ClearAll removes all entries from both tiers, the backend first as in Delete
+func (t *TieredCache) ClearAll(ctx context.Context) error {
+	err := t.backend.ClearAll(ctx)
+
+	t.mu.Lock()
+	t.order.Init()
+	t.items = make(map[string]*list.Element)
+	t.bytes = 0
+	t.generation++
+	t.mu.Unlock()
+
+	return err
+}
+
+// DeleteExpired removes expired entries from both tiers
+func (t *TieredCache) DeleteExpired(ctx context.Context) error {
+	now := time.Now()
+	t.mu.Lock()
+	for element := t.order.Front(); element != nil; {
+		next := element.Next()
+		if entry := element.Value.(*lruEntry); !now.Before(entry.expiresAt) {
+			t.removeElement(element)
+		}
+		element = next
+	}
+	t.mu.Unlock()
+
+	return t.backend.DeleteExpired(ctx)
+}
+
+// GetStats returns backend statistics with hit counts for each tier
+func (t *TieredCache) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	stats, err := t.backend.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	entries := t.order.Len()
+	bytes := t.bytes
+	t.mu.Unlock()
+
+	stats["memory_tier"] = map[string]interface{}{
+		"entries":     entries,
+		"bytes":       bytes,
+		"max_entries": t.opts.MaxEntries,
+		"max_bytes":   t.opts.MaxBytes,
+		"ttl":         t.opts.TTL.String(),
+		"hits":        atomic.LoadInt64(&t.memoryHits),
+		"misses":      atomic.LoadInt64(&t.memoryMisses),
+		"evictions":   atomic.LoadInt64(&t.evictions),
+	}
+	stats["backend_tier"] = map[string]interface{}{
+		"hits":   atomic.LoadInt64(&t.backendHits),
+		"misses": atomic.LoadInt64(&t.backendMisses),
+	}
+	return stats, nil
+}
+
+// Scan enumerates the entries of the backend, which holds every entry of the memory tier
+func (t *TieredCache) Scan(ctx context.Context, prefix string, fn func(Entry) error) error {
+	scanner, ok := t.backend.(Scanner)
+	if !ok {
+		return ErrScanUnsupported
+	}
+	return scanner.Scan(ctx, prefix, fn)
+}
+
+// Ping tests the backend connection
+func (t *TieredCache) Ping(ctx context.Context) error {
+	return t.backend.Ping(ctx)
+}
+
+// Close closes the backend
+func (t *TieredCache) Close() error {
+	return t.backend.Close()
+}
+
+// getMemory returns an unexpired value from memory and marks it recently used
+func (t *TieredCache) getMemory(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	element, ok := t.items[key]
+	if !ok {
+		return "", false
+	}
+	entry := element.Value.(*lruEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		t.removeElement(element)
+		return "", false
+	}
+	t.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// setMemory stores a written value in memory
+func (t *TieredCache) setMemory(key, value string, ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.generation++
+	t.storeMemory(&lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)})
+}
+
+// fillMemory stores a value read from the backend, unless the cache was written to
+// since generation was taken
+func (t *TieredCache) fillMemory(key, value string, ttl time.Duration, generation uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.generation != generation {
+		return
+	}
+	t.storeMemory(&lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)})
+}
+
+// storeMemory stores an entry, evicting least recently used entries over the limits;
+// callers must hold t.mu
+func (t *TieredCache) storeMemory(entry *lruEntry) {
+	if element, ok := t.items[entry.key]; ok {
+		t.removeElement(element)
+	}
+	if entry.size() > t.opts.MaxBytes {
+		return
+	}
+
+	t.items[entry.key] = t.order.PushFront(entry)
+	t.bytes += entry.size()
+
+	for t.order.Len() > t.opts.MaxEntries || t.bytes > t.opts.MaxBytes {
+		t.removeElement(t.order.Back())
+		atomic.AddInt64(&t.evictions, 1)
+	}
+}
+
+// deleteMemory removes a key from memory
+func (t *TieredCache) deleteMemory(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.generation++
+	if element, ok := t.items[key]; ok {
+		t.removeElement(element)
+	}
+}
+
+// removeElement unlinks an entry; callers must hold t.mu
+func (t *TieredCache) removeElement(element *list.Element) {
+	entry := element.Value.(*lruEntry)
+	t.order.Remove(element)
+	delete(t.items, entry.key)
+	t.bytes -= entry.size()
+}
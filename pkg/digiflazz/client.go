@@ -2,6 +2,7 @@ package digiflazz
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
@@ -13,6 +14,8 @@ import (
 
 	"gateway-digiflazz/internal/config"
 	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/pkg/metrics"
+	"gateway-digiflazz/pkg/requestid"
 
 	"github.com/sirupsen/logrus"
 )
@@ -22,21 +25,63 @@ type Client struct {
 	config     config.DigiflazzConfig
 	httpClient *http.Client
 	baseURL    string
+	account    string
 	logger     *logrus.Logger
+	// Redacted request and response bodies are logged at bodyLogLevel when logBodies is set
+	bodyLogLevel logrus.Level
+	logBodies    bool
+	metrics      *metrics.Metrics
 }
 
+// userAgent identifies the gateway and its platform to Digiflazz
+var userAgent = fmt.Sprintf("Digiflazz-Gateway/1.0 (%s/%s)", runtime.GOOS, runtime.GOARCH)
+
 // NewClient creates a new Digiflazz API client
+// An invalid body log level falls back to DefaultBodyLogLevel; NewPool rejects it.
 func NewClient(cfg config.DigiflazzConfig, logger *logrus.Logger) *Client {
+	return newAccountClient(cfg, DefaultAccountName, logger)
+}
+
+// newAccountClient creates the client of a named account
+func newAccountClient(cfg config.DigiflazzConfig, account string, logger *logrus.Logger) *Client {
+	bodyLogLevel, logBodies, err := ParseBodyLogLevel(cfg.BodyLogLevel)
+	if err != nil {
+		bodyLogLevel, logBodies = logrus.DebugLevel, true
+	}
+
+	// Logged once here rather than on every request
+	logger.WithFields(logrus.Fields{
+		"account":        account,
+		"base_url":       cfg.BaseURL,
+		"username":       cfg.Username,
+		"timeout":        cfg.Timeout,
+		"retry_attempts": cfg.RetryAttempts,
+		"user_agent":     userAgent,
+	}).Debug("Digiflazz client configuration")
+
 	return &Client{
 		config: cfg,
 		httpClient: &http.Client{
 			Timeout: cfg.Timeout,
 		},
-		baseURL: cfg.BaseURL,
-		logger:  logger,
+		baseURL:      cfg.BaseURL,
+		account:      account,
+		logger:       logger,
+		bodyLogLevel: bodyLogLevel,
+		logBodies:    logBodies,
 	}
 }
 
+// SetMetrics configures where API call latency and errors are recorded
+func (c *Client) SetMetrics(m *metrics.Metrics) {
+	c.metrics = m
+}
+
+// Account returns the name of the Digiflazz account used by the client
+func (c *Client) Account() string {
+	return c.account
+}
+
 // generateSign generates MD5 signature for Digiflazz API
 func (c *Client) generateSign(username, apiKey, refID string) string {
 	data := fmt.Sprintf("%s%s%s", username, apiKey, refID)
@@ -44,7 +89,7 @@ func (c *Client) generateSign(username, apiKey, refID string) string {
 }
 
 // CheckBalance checks the account balance
-func (c *Client) CheckBalance() (*models.BalanceResponse, error) {
+func (c *Client) CheckBalance(ctx context.Context) (*models.BalanceResponse, error) {
 	req := models.BalanceRequest{
 		DigiflazzRequest: models.DigiflazzRequest{
 			Username: c.config.Username,
@@ -54,7 +99,7 @@ func (c *Client) CheckBalance() (*models.BalanceResponse, error) {
 	}
 
 	var resp models.BalanceResponse
-	if err := c.makeRequest("/cek-saldo", req, &resp); err != nil {
+	if err := c.makeRequest(ctx, "/cek-saldo", req, &resp); err != nil {
 		return nil, err
 	}
 
@@ -62,7 +107,7 @@ func (c *Client) CheckBalance() (*models.BalanceResponse, error) {
 }
 
 // GetPrices gets the price list
-func (c *Client) GetPrices(priceType string) (*models.PriceResponse, error) {
+func (c *Client) GetPrices(ctx context.Context, priceType string) (*models.PriceResponse, error) {
 	req := models.PriceRequest{
 		DigiflazzRequest: models.DigiflazzRequest{
 			Username: c.config.Username,
@@ -73,7 +118,7 @@ func (c *Client) GetPrices(priceType string) (*models.PriceResponse, error) {
 	}
 
 	var resp models.PriceResponse
-	if err := c.makeRequest("/daftar-harga", req, &resp); err != nil {
+	if err := c.makeRequest(ctx, "/daftar-harga", req, &resp); err != nil {
 		return nil, err
 	}
 
@@ -81,14 +126,14 @@ func (c *Client) GetPrices(priceType string) (*models.PriceResponse, error) {
 }
 
 // Topup performs a topup transaction
-func (c *Client) Topup(req models.TopupRequest) (*models.TopupResponse, error) {
+func (c *Client) Topup(ctx context.Context, req models.TopupRequest) (*models.TopupResponse, error) {
 	// Generate signature for topup
 	req.Sign = c.generateSign(c.config.Username, c.config.APIKey, req.RefID)
 	req.Username = c.config.Username
 	req.APIKey = c.config.APIKey
 
 	var resp models.TopupResponse
-	if err := c.makeRequest("/topup", req, &resp); err != nil {
+	if err := c.makeRequest(ctx, "/topup", req, &resp); err != nil {
 		return nil, err
 	}
 
@@ -96,14 +141,14 @@ func (c *Client) Topup(req models.TopupRequest) (*models.TopupResponse, error) {
 }
 
 // Pay performs a payment transaction
-func (c *Client) Pay(req models.PayRequest) (*models.PayResponse, error) {
+func (c *Client) Pay(ctx context.Context, req models.PayRequest) (*models.PayResponse, error) {
 	// Generate signature for payment
 	req.Sign = c.generateSign(c.config.Username, c.config.APIKey, req.RefID)
 	req.Username = c.config.Username
 	req.APIKey = c.config.APIKey
 
 	var resp models.PayResponse
-	if err := c.makeRequest("/pascabayar", req, &resp); err != nil {
+	if err := c.makeRequest(ctx, "/pascabayar", req, &resp); err != nil {
 		return nil, err
 	}
 
@@ -111,7 +156,7 @@ func (c *Client) Pay(req models.PayRequest) (*models.PayResponse, error) {
 }
 
 // CheckStatus checks the transaction status
-func (c *Client) CheckStatus(refID string) (*models.StatusResponse, error) {
+func (c *Client) CheckStatus(ctx context.Context, refID string) (*models.StatusResponse, error) {
 	req := models.StatusRequest{
 		DigiflazzRequest: models.DigiflazzRequest{
 			Username: c.config.Username,
@@ -122,58 +167,61 @@ func (c *Client) CheckStatus(refID string) (*models.StatusResponse, error) {
 	}
 
 	var resp models.StatusResponse
-	if err := c.makeRequest("/cek-status", req, &resp); err != nil {
+	if err := c.makeRequest(ctx, "/cek-status", req, &resp); err != nil {
 		return nil, err
 	}
 
 	return &resp, nil
 }
 
-// makeRequest makes an HTTP request to Digiflazz API
-func (c *Client) makeRequest(endpoint string, req interface{}, resp interface{}) error {
+// makeRequest makes an HTTP request to Digiflazz API and records its metrics
+func (c *Client) makeRequest(ctx context.Context, endpoint string, req interface{}, resp interface{}) error {
+	start := time.Now()
+	rc, err := c.doRequest(ctx, endpoint, req, resp)
+	if err != nil {
+		rc = RCFromError(err)
+	}
+	// Pending is not a failure; an empty rc on success is a response without one (e.g. price list)
+	failed := err != nil || (rc != "" && rc != RCSuccess && rc != RCPending)
+	c.metrics.ObserveDigiflazzRequest(endpoint, rc, failed, time.Since(start))
+	return err
+}
+
+// doRequest sends the request with retries and decodes the response into resp,
+// returning the response code of the answer
+func (c *Client) doRequest(ctx context.Context, endpoint string, req interface{}, resp interface{}) (string, error) {
+	log := requestid.Logger(ctx, c.logger)
+
 	// Marshal request to JSON
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request
 	url := c.baseURL + endpoint
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	// A purchase must not be abandoned half way because the caller went away, so only
+	// the request values of ctx are used
+	httpReq, err := http.NewRequestWithContext(context.WithoutCancel(ctx), "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers with platform-specific User-Agent
 	httpReq.Header.Set("Content-Type", "application/json")
-	userAgent := fmt.Sprintf("Digiflazz-Gateway/1.0 (%s/%s)", runtime.GOOS, runtime.GOARCH)
 	httpReq.Header.Set("User-Agent", userAgent)
 
-	// Log request details
-	c.logger.WithFields(logrus.Fields{
+	// Log request details; the payload carries the API key and signature, so it is
+	// only logged redacted
+	log.WithFields(logrus.Fields{
 		"url":          url,
 		"method":       "POST",
 		"endpoint":     endpoint,
-		"payload":      string(jsonData),
 		"payload_size": len(jsonData),
 		"timeout":      c.httpClient.Timeout,
 		"user_agent":   userAgent,
-		"headers":      httpReq.Header,
 	}).Debug("Making request to Digiflazz API")
-	
-	// Log full JSON payload for debugging
-	c.logger.WithField("json_payload", string(jsonData)).Info("Full JSON payload being sent to Digiflazz")
-	
-	// Log environment information
-	c.logger.WithFields(logrus.Fields{
-		"base_url":     c.baseURL,
-		"username":     c.config.Username,
-		"api_key_len":  len(c.config.APIKey),
-		"timeout":      c.config.Timeout,
-		"retry_attempts": c.config.RetryAttempts,
-		"user_agent":   userAgent,
-		"platform":     fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
-	}).Info("Digiflazz client configuration")
+	c.logBody(log, endpoint, "payload", jsonData, "Digiflazz request body")
 
 	// Make request with retry logic
 	var lastErr error
@@ -197,17 +245,18 @@ func (c *Client) makeRequest(endpoint string, req interface{}, resp interface{})
 		}
 
 		// Log response details
-		c.logger.WithFields(logrus.Fields{
-			"status_code": httpResp.StatusCode,
-			"response":    string(body),
-			"endpoint":    endpoint,
+		log.WithFields(logrus.Fields{
+			"status_code":  httpResp.StatusCode,
+			"endpoint":     endpoint,
+			"response_len": len(body),
 		}).Debug("Received response from Digiflazz API")
+		c.logBody(log, endpoint, "response", body, "Digiflazz response body")
 
 		// Check HTTP status
 		if httpResp.StatusCode != http.StatusOK {
-			c.logger.WithFields(logrus.Fields{
+			log.WithFields(logrus.Fields{
 				"status_code": httpResp.StatusCode,
-				"response":    string(body),
+				"response":    RedactBody(body),
 				"endpoint":    endpoint,
 			}).Error("Digiflazz API returned error status")
 			
@@ -215,49 +264,47 @@ func (c *Client) makeRequest(endpoint string, req interface{}, resp interface{})
 			if strings.Contains(string(body), "IP Anda tidak kami kenali") {
 				lastErr = fmt.Errorf("IP whitelist error: your IP is not registered in Digiflazz whitelist")
 			} else {
-				lastErr = fmt.Errorf("HTTP error %d: %s", httpResp.StatusCode, string(body))
+				lastErr = newAPIError(httpResp.StatusCode, body)
 			}
 			continue
 		}
 
-		// Log raw response for debugging
-		c.logger.WithFields(logrus.Fields{
-			"endpoint":     endpoint,
-			"raw_response": string(body),
-			"response_len": len(body),
-		}).Debug("Raw response from Digiflazz API")
-
 		// Unmarshal response
 		if err := json.Unmarshal(body, resp); err != nil {
-			c.logger.WithError(err).WithFields(logrus.Fields{
+			log.WithError(err).WithFields(logrus.Fields{
 				"endpoint":     endpoint,
-				"raw_response": string(body),
+				"raw_response": RedactBody(body),
 			}).Error("Failed to unmarshal Digiflazz API response")
 			lastErr = fmt.Errorf("failed to unmarshal response: %w", err)
 			continue
 		}
 
-		// Log unmarshaled response for debugging
-		c.logger.WithFields(logrus.Fields{
-			"endpoint":    endpoint,
-			"unmarshaled": resp,
-		}).Debug("Unmarshaled response from Digiflazz API")
-
-		return nil
+		return responseRC(body), nil
 	}
 
-	return lastErr
+	return "", lastErr
+}
+
+// logBody logs a request or response body, redacted, at the configured body log level
+func (c *Client) logBody(log *logrus.Entry, endpoint, field string, body []byte, message string) {
+	if !c.logBodies || !c.logger.IsLevelEnabled(c.bodyLogLevel) {
+		return
+	}
+	log.WithFields(logrus.Fields{
+		"endpoint": endpoint,
+		field:      RedactBody(body),
+	}).Log(c.bodyLogLevel, message)
 }
 
 // CheckPascabayarBill checks the Pascabayar bill
-func (c *Client) CheckPascabayarBill(req models.PascabayarCheckRequest) (*models.PascabayarCheckResponse, error) {
+func (c *Client) CheckPascabayarBill(ctx context.Context, req models.PascabayarCheckRequest) (*models.PascabayarCheckResponse, error) {
 	// Generate signature for check request
 	req.Sign = c.generateSign(c.config.Username, c.config.APIKey, req.RefID)
 	req.Username = c.config.Username
 	req.APIKey = c.config.APIKey
 
 	var resp models.PascabayarCheckResponse
-	if err := c.makeRequest("/pascabayar/check", req, &resp); err != nil {
+	if err := c.makeRequest(ctx, "/pascabayar/check", req, &resp); err != nil {
 		return nil, err
 	}
 
@@ -265,14 +312,14 @@ func (c *Client) CheckPascabayarBill(req models.PascabayarCheckRequest) (*models
 }
 
 // PayPascabayarBill pays the Pascabayar bill
-func (c *Client) PayPascabayarBill(req models.PascabayarPayRequest) (*models.PascabayarPayResponse, error) {
+func (c *Client) PayPascabayarBill(ctx context.Context, req models.PascabayarPayRequest) (*models.PascabayarPayResponse, error) {
 	// Generate signature for pay request
 	req.Sign = c.generateSign(c.config.Username, c.config.APIKey, req.RefID)
 	req.Username = c.config.Username
 	req.APIKey = c.config.APIKey
 
 	var resp models.PascabayarPayResponse
-	if err := c.makeRequest("/pascabayar/pay", req, &resp); err != nil {
+	if err := c.makeRequest(ctx, "/pascabayar/pay", req, &resp); err != nil {
 		return nil, err
 	}
 
@@ -280,65 +327,28 @@ func (c *Client) PayPascabayarBill(req models.PascabayarPayRequest) (*models.Pas
 }
 
 // InquiryPLN performs PLN inquiry
-func (c *Client) InquiryPLN(req models.PLNInquiryRequest) (*models.PLNInquiryResponse, error) {
+func (c *Client) InquiryPLN(ctx context.Context, req models.PLNInquiryRequest) (*models.PLNInquiryResponse, error) {
+	log := requestid.Logger(ctx, c.logger)
+
 	// Log request details
-	c.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": req.CustomerNo,
 		"endpoint":    "/inquiry-pln",
-		"username":    c.config.Username,
+		"account":     c.account,
 	}).Info("Sending PLN inquiry request to Digiflazz API")
 
 	// Generate signature for PLN inquiry
 	req.Username = c.config.Username
 	req.Sign = c.generatePLNInquirySign(c.config.Username, c.config.APIKey, req.CustomerNo)
-	
-	// Log signature generation details
-	signatureInput := fmt.Sprintf("%s%s%s", c.config.Username, c.config.APIKey, req.CustomerNo)
-	c.logger.WithFields(logrus.Fields{
-		"username":        c.config.Username,
-		"customer_no":     req.CustomerNo,
-		"signature":       req.Sign,
-		"signature_input": signatureInput,
-		"signature_length": len(req.Sign),
-		"api_key_length":  len(c.config.APIKey),
-		"base_url":        c.baseURL,
-	}).Info("PLN inquiry signature generated")
-	
-	// Verify signature manually for debugging
-	expectedSignature := fmt.Sprintf("%x", md5.Sum([]byte(signatureInput)))
-	if req.Sign != expectedSignature {
-		c.logger.WithFields(logrus.Fields{
-			"generated": req.Sign,
-			"expected":  expectedSignature,
-		}).Error("Signature mismatch detected!")
-	} else {
-		c.logger.Debug("Signature verification passed")
-	}
-
-	// Log request payload (without sensitive data)
-	requestPayload := map[string]interface{}{
-		"username":    req.Username,
-		"customer_no": req.CustomerNo,
-		"sign":        req.Sign,
-	}
-	c.logger.WithField("request_payload", requestPayload).Debug("PLN inquiry request payload")
-	
-	// Log full request for debugging
-	fullRequest := map[string]interface{}{
-		"username":    req.Username,
-		"customer_no": req.CustomerNo,
-		"sign":        req.Sign,
-	}
-	c.logger.WithField("full_request", fullRequest).Info("Full PLN inquiry request to Digiflazz")
 
 	var resp models.PLNInquiryResponse
-	if err := c.makeRequest("/inquiry-pln", req, &resp); err != nil {
-		c.logger.WithError(err).Error("PLN inquiry request failed")
+	if err := c.makeRequest(ctx, "/inquiry-pln", req, &resp); err != nil {
+		log.WithError(err).Error("PLN inquiry request failed")
 		return nil, err
 	}
 
 	// Log response details
-	c.logger.WithFields(logrus.Fields{
+	log.WithFields(logrus.Fields{
 		"customer_no": req.CustomerNo,
 		"rc":          resp.Data.RC,
 		"status":      resp.Data.Status,
@@ -350,9 +360,9 @@ func (c *Client) InquiryPLN(req models.PLNInquiryRequest) (*models.PLNInquiryRes
 
 	// Validate response - check for empty or invalid response
 	if resp.Data.RC == "" && resp.Data.Status == "" && resp.Data.Message == "" {
-		c.logger.WithFields(logrus.Fields{
+		log.WithFields(logrus.Fields{
 			"customer_no": req.CustomerNo,
-			"response":    resp,
+			"response":    RedactValue(resp),
 		}).Warn("PLN inquiry returned empty response - customer may not exist or API issue")
 		
 		// Return error for empty response
@@ -361,13 +371,13 @@ func (c *Client) InquiryPLN(req models.PLNInquiryRequest) (*models.PLNInquiryRes
 
 	// Check for specific error codes
 	if resp.Data.RC != "00" && resp.Data.RC != "" {
-		c.logger.WithFields(logrus.Fields{
+		log.WithFields(logrus.Fields{
 			"customer_no": req.CustomerNo,
 			"rc":          resp.Data.RC,
 			"message":     resp.Data.Message,
 		}).Warn("PLN inquiry returned error code")
 		
-		return nil, fmt.Errorf("PLN inquiry failed: RC=%s, Message=%s", resp.Data.RC, resp.Data.Message)
+		return nil, fmt.Errorf("PLN inquiry failed: %w", &RCError{RC: resp.Data.RC, Message: resp.Data.Message})
 	}
 
 	return &resp, nil
@@ -0,0 +1,135 @@
+package digiflazz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Digiflazz response codes used by the gateway
+const (
+	RCSuccess             = "00"
+	RCTimeout             = "01"
+	RCFailed              = "02"
+	RCPending             = "03"
+	RCSKUNotFound         = "43"
+	RCInsufficientBalance = "44"
+	RCIPNotRecognized     = "45"
+	RCRefIDNotUnique      = "49"
+	RCTransactionNotFound = "50"
+	RCNumberBlocked       = "51"
+	RCPrefixMismatch      = "52"
+	RCProductUnavailable  = "53"
+	RCInvalidNumber       = "54"
+	RCProductDisturbance  = "55"
+	RCSellerBalanceLimit  = "56"
+	RCInvalidDigits       = "57"
+	RCCutOff              = "58"
+	RCOutOfArea           = "59"
+	RCBillNotAvailable    = "60"
+	RCSellerDisturbance   = "62"
+	RCOutOfStock          = "68"
+	RCSellerPriceTooHigh  = "69"
+	RCBillerTimeout       = "70"
+	RCProductUnstable     = "71"
+)
+
+// IsSellerError reports whether rc is a seller-side failure that another SKU may not share
+func IsSellerError(rc string) bool {
+	switch rc {
+	case RCSKUNotFound, RCProductUnavailable, RCProductDisturbance, RCSellerBalanceLimit,
+		RCCutOff, RCSellerDisturbance, RCOutOfStock, RCProductUnstable:
+		return true
+	}
+	return false
+}
+
+// IsCustomerError reports whether rc is a final error caused by the customer number
+func IsCustomerError(rc string) bool {
+	switch rc {
+	case RCNumberBlocked, RCPrefixMismatch, RCInvalidNumber, RCInvalidDigits, RCOutOfArea:
+		return true
+	}
+	return false
+}
+
+// IsCustomerNotFound reports whether rc definitively means the customer number does not exist.
+// Unlike transient failures these results can be cached.
+func IsCustomerNotFound(rc string) bool {
+	switch rc {
+	case RCInvalidNumber, RCInvalidDigits:
+		return true
+	}
+	return false
+}
+
+// RCError is returned when Digiflazz answers a request with a failure response code
+type RCError struct {
+	RC      string
+	Message string
+}
+
+// Error implements the error interface
+func (e *RCError) Error() string {
+	return fmt.Sprintf("RC=%s, Message=%s", e.RC, e.Message)
+}
+
+// APIError is returned when Digiflazz responds with a non-200 HTTP status
+type APIError struct {
+	StatusCode int
+	RC         string
+	Message    string
+	Body       string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("HTTP error %d: %s", e.StatusCode, e.Body)
+}
+
+// newAPIError builds an APIError, extracting rc and message from the body when present
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+
+	var payload struct {
+		Data struct {
+			RC      string `json:"rc"`
+			Message string `json:"message"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		apiErr.RC = payload.Data.RC
+		apiErr.Message = payload.Data.Message
+	}
+
+	return apiErr
+}
+
+// responseRC extracts the response code from a Digiflazz response body, if any
+func responseRC(body []byte) string {
+	var payload struct {
+		Data struct {
+			RC string `json:"rc"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return payload.Data.RC
+}
+
+// RCFromError returns the Digiflazz response code carried by err, if any
+func RCFromError(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RC
+	}
+	var rcErr *RCError
+	if errors.As(err, &rcErr) {
+		return rcErr.RC
+	}
+	return ""
+}
@@ -0,0 +1,240 @@
+package digiflazz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/pkg/metrics"
+	"gateway-digiflazz/pkg/requestid"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAccountName is the name of the account built from the top-level Digiflazz credentials
+const DefaultAccountName = "default"
+
+// Pool manages Digiflazz clients for multiple accounts keyed by account name
+type Pool struct {
+	clients  map[string]*Client
+	failover map[string]string
+	routes   []config.DigiflazzRouteConfig
+	balances map[string]models.AccountBalance
+	mu       sync.RWMutex
+	logger   *logrus.Logger
+	metrics  *metrics.Metrics
+}
+
+// NewPool creates a client pool from the Digiflazz configuration
+func NewPool(cfg config.DigiflazzConfig, logger *logrus.Logger) (*Pool, error) {
+	if _, _, err := ParseBodyLogLevel(cfg.BodyLogLevel); err != nil {
+		return nil, err
+	}
+
+	pool := &Pool{
+		clients:  make(map[string]*Client),
+		failover: make(map[string]string),
+		routes:   cfg.Routes,
+		balances: make(map[string]models.AccountBalance),
+		logger:   logger,
+	}
+
+	pool.clients[DefaultAccountName] = NewClient(cfg, logger)
+	if cfg.Failover != "" {
+		pool.failover[DefaultAccountName] = cfg.Failover
+	}
+
+	for _, account := range cfg.Accounts {
+		if account.Name == "" {
+			return nil, fmt.Errorf("digiflazz account name is required")
+		}
+		if _, exists := pool.clients[account.Name]; exists {
+			return nil, fmt.Errorf("duplicate digiflazz account: %s", account.Name)
+		}
+		if account.Username == "" || account.APIKey == "" {
+			return nil, fmt.Errorf("digiflazz account %s requires username and api_key", account.Name)
+		}
+
+		accountCfg := cfg
+		accountCfg.Username = account.Username
+		accountCfg.APIKey = account.APIKey
+		if account.BaseURL != "" {
+			accountCfg.BaseURL = account.BaseURL
+		}
+		if account.IPWhitelist != "" {
+			accountCfg.IPWhitelist = account.IPWhitelist
+		}
+
+		pool.clients[account.Name] = newAccountClient(accountCfg, account.Name, logger)
+		if account.Failover != "" {
+			pool.failover[account.Name] = account.Failover
+		}
+	}
+
+	// Validate references to accounts
+	for name, target := range pool.failover {
+		if _, exists := pool.clients[target]; !exists {
+			return nil, fmt.Errorf("digiflazz account %s fails over to unknown account %s", name, target)
+		}
+	}
+	for _, route := range pool.routes {
+		if _, exists := pool.clients[route.Account]; !exists {
+			return nil, fmt.Errorf("digiflazz route references unknown account %s", route.Account)
+		}
+	}
+
+	return pool, nil
+}
+
+// SetMetrics configures where the clients record API calls and the pool records balances
+func (p *Pool) SetMetrics(m *metrics.Metrics) {
+	p.metrics = m
+	for _, client := range p.clients {
+		client.SetMetrics(m)
+	}
+}
+
+// Default returns the client for the default account
+func (p *Pool) Default() *Client {
+	return p.clients[DefaultAccountName]
+}
+
+// Client returns the client for the named account
+func (p *Pool) Client(name string) (*Client, bool) {
+	client, ok := p.clients[name]
+	return client, ok
+}
+
+// Accounts returns the configured account names in sorted order
+func (p *Pool) Accounts() []string {
+	names := make([]string, 0, len(p.clients))
+	for name := range p.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Route selects the account for a reseller and product category.
+// Routes are evaluated in order; empty rule fields match anything.
+func (p *Pool) Route(reseller, category string) string {
+	for _, route := range p.routes {
+		if route.Reseller == "" && route.Category == "" {
+			continue
+		}
+		if route.Reseller != "" && route.Reseller != reseller {
+			continue
+		}
+		if route.Category != "" && !strings.EqualFold(route.Category, category) {
+			continue
+		}
+		return route.Account
+	}
+	return DefaultAccountName
+}
+
+// Topup performs a topup on the given account, failing over to the configured
+// secondary account when Digiflazz reports insufficient balance.
+// It returns the response together with the account that served it.
+func (p *Pool) Topup(ctx context.Context, account string, req models.TopupRequest) (*models.TopupResponse, string, error) {
+	visited := make(map[string]bool)
+
+	for {
+		client, ok := p.clients[account]
+		if !ok {
+			return nil, account, fmt.Errorf("unknown digiflazz account: %s", account)
+		}
+		visited[account] = true
+
+		resp, err := client.Topup(ctx, req)
+		if err == nil && resp.Data.BuyerLastSaldo > 0 {
+			p.recordBalance(account, resp.Data.BuyerLastSaldo)
+		}
+
+		rc := RCFromError(err)
+		if err == nil {
+			rc = resp.Data.RC
+		}
+		if rc != RCInsufficientBalance {
+			return resp, account, err
+		}
+
+		next, hasFailover := p.failover[account]
+		if !hasFailover || visited[next] {
+			return resp, account, err
+		}
+
+		requestid.Logger(ctx, p.logger).WithFields(logrus.Fields{
+			"ref_id":   req.RefID,
+			"account":  account,
+			"failover": next,
+		}).Warn("Digiflazz account has insufficient balance, failing over")
+		account = next
+	}
+}
+
+// CheckBalance checks the balance of the named account and records it
+func (p *Pool) CheckBalance(ctx context.Context, account string) (*models.BalanceResponse, error) {
+	client, ok := p.clients[account]
+	if !ok {
+		return nil, fmt.Errorf("unknown digiflazz account: %s", account)
+	}
+
+	resp, err := client.CheckBalance(ctx)
+	if err != nil {
+		p.mu.Lock()
+		balance := p.balances[account]
+		balance.Account = account
+		balance.Error = err.Error()
+		p.balances[account] = balance
+		p.mu.Unlock()
+		return nil, err
+	}
+
+	p.recordBalance(account, resp.Data.Deposit)
+	return resp, nil
+}
+
+// RefreshBalances checks the balance of every account
+func (p *Pool) RefreshBalances(ctx context.Context) []models.AccountBalance {
+	for _, name := range p.Accounts() {
+		if _, err := p.CheckBalance(ctx, name); err != nil {
+			requestid.Logger(ctx, p.logger).WithError(err).WithField("account", name).Warn("Failed to refresh Digiflazz account balance")
+		}
+	}
+	return p.Balances()
+}
+
+// Balances returns the last known balance of every account
+func (p *Pool) Balances() []models.AccountBalance {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	balances := make([]models.AccountBalance, 0, len(p.clients))
+	for _, name := range p.Accounts() {
+		balance, ok := p.balances[name]
+		if !ok {
+			balance = models.AccountBalance{Account: name}
+		}
+		balances = append(balances, balance)
+	}
+	return balances
+}
+
+// recordBalance stores the latest known balance of an account
+func (p *Pool) recordBalance(account string, deposit float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.balances[account] = models.AccountBalance{
+		Account:   account,
+		Deposit:   deposit,
+		UpdatedAt: time.Now(),
+	}
+	p.metrics.SetDepositBalance(account, deposit)
+}
@@ -0,0 +1,113 @@
+package digiflazz
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces secrets in logged Digiflazz traffic
+const Redacted = "[redacted]"
+
+// BodyLogOff disables logging of Digiflazz request and response bodies
+const BodyLogOff = "off"
+
+// DefaultBodyLogLevel is the level at which redacted bodies are logged by default
+const DefaultBodyLogLevel = "debug"
+
+// secretFields are the JSON fields never logged, at any depth
+var secretFields = map[string]bool{
+	"api_key":         true,
+	"apikey":          true,
+	"key":             true,
+	"sign":            true,
+	"signature":       true,
+	"signature_input": true,
+	"password":        true,
+	"token":           true,
+}
+
+// plnTokenPattern matches 20 digit PLN tokens, optionally grouped in fours
+var plnTokenPattern = regexp.MustCompile(`\b\d{4}(?:[- ]?\d{4}){4}\b`)
+
+// secretFieldPattern matches secret JSON string fields in bodies that are not valid JSON
+var secretFieldPattern = regexp.MustCompile(`"(?i:api_key|apikey|key|sign|signature|signature_input|password|token)"\s*:\s*"[^"]*"`)
+
+// ParseBodyLogLevel parses the level at which bodies are logged; ok is false when
+// body logging is off. An empty level is DefaultBodyLogLevel.
+func ParseBodyLogLevel(level string) (parsed logrus.Level, ok bool, err error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case "":
+		level = DefaultBodyLogLevel
+	case BodyLogOff, "none", "false":
+		return logrus.DebugLevel, false, nil
+	}
+	parsed, err = logrus.ParseLevel(level)
+	if err != nil {
+		return logrus.DebugLevel, false, fmt.Errorf("invalid digiflazz body log level %q (expected off or a log level)", level)
+	}
+	return parsed, true, nil
+}
+
+// RedactBody returns body for logging with secret fields and PLN tokens masked
+func RedactBody(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		masked := secretFieldPattern.ReplaceAllStringFunc(string(body), func(field string) string {
+			name := field[:strings.Index(field, ":")]
+			return name + `:"` + Redacted + `"`
+		})
+		return RedactSN(masked)
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return Redacted
+	}
+	return string(redacted)
+}
+
+// RedactValue returns v, marshalled to JSON, for logging with secrets masked
+func RedactValue(v interface{}) string {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return Redacted
+	}
+	return RedactBody(body)
+}
+
+// RedactSN masks PLN tokens in a serial number, keeping their last four digits
+func RedactSN(sn string) string {
+	return plnTokenPattern.ReplaceAllStringFunc(sn, func(token string) string {
+		digits := strings.NewReplacer("-", "", " ", "").Replace(token)
+		return "****-****-****-****-" + digits[len(digits)-4:]
+	})
+}
+
+// redactValue masks secret fields and tokens in decoded JSON
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, item := range v {
+			if secretFields[strings.ToLower(field)] {
+				v[field] = Redacted
+				continue
+			}
+			v[field] = redactValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		return RedactSN(v)
+	default:
+		return v
+	}
+}
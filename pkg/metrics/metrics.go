@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric exported by the gateway
+const namespace = "gateway"
+
+// PLN cache lookup results
+const (
+	PLNCacheHit         = "hit"
+	PLNCacheMiss        = "miss"
+	PLNCacheNegativeHit = "negative_hit"
+)
+
+// OtherLabel replaces the caller-supplied SKU and category of failed transactions for
+// products the gateway does not know, so arbitrary buyer_sku values cannot grow the
+// label set.
+const OtherLabel = "other"
+
+// Metrics holds the Prometheus collectors exported on /metrics. A nil *Metrics records
+// nothing, so components work unchanged when metrics are disabled.
+type Metrics struct {
+	registry          *prometheus.Registry
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	digiflazzRequests *prometheus.CounterVec
+	digiflazzErrors   *prometheus.CounterVec
+	digiflazzDuration *prometheus.HistogramVec
+	transactions      *prometheus.CounterVec
+	plnCache          *prometheus.CounterVec
+	depositBalance    *prometheus.GaugeVec
+
+	// knownProducts maps the SKUs whose failures keep their labels to their category
+	knownMu       sync.RWMutex
+	knownProducts map[string]string
+}
+
+// New creates the gateway metrics on their own registry, together with the Go runtime
+// and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry:      prometheus.NewRegistry(),
+		knownProducts: make(map[string]string),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		digiflazzRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "digiflazz_requests_total",
+			Help:      "Digiflazz API calls by endpoint and response code.",
+		}, []string{"endpoint", "rc"}),
+		digiflazzErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "digiflazz_errors_total",
+			Help:      "Failed Digiflazz API calls by endpoint and response code; rc is empty when Digiflazz did not answer with one.",
+		}, []string{"endpoint", "rc"}),
+		digiflazzDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "digiflazz_request_duration_seconds",
+			Help:      "Digiflazz API call latency by endpoint, including retries.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"endpoint"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_total",
+			Help:      "Transaction outcomes by buyer SKU, category and status; failures of unknown SKUs are labelled sku=\"other\", category=\"other\".",
+		}, []string{"sku", "category", "status"}),
+		plnCache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pln_cache_lookups_total",
+			Help:      "PLN inquiry cache lookups by result (hit, miss or negative_hit).",
+		}, []string{"result"}),
+		depositBalance: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "digiflazz_deposit_balance",
+			Help:      "Last known Digiflazz deposit balance by account.",
+		}, []string{"account"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.digiflazzRequests,
+		m.digiflazzErrors,
+		m.digiflazzDuration,
+		m.transactions,
+		m.plnCache,
+		m.depositBalance,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a served HTTP request. route is the route pattern, not
+// the raw path, to keep the label set bounded.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveDigiflazzRequest records a Digiflazz API call and whether it failed
+func (m *Metrics) ObserveDigiflazzRequest(endpoint, rc string, failed bool, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.digiflazzRequests.WithLabelValues(endpoint, rc).Inc()
+	if failed {
+		m.digiflazzErrors.WithLabelValues(endpoint, rc).Inc()
+	}
+	m.digiflazzDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
+
+// AddKnownProduct marks sku as a product the gateway sells: a configured, resolved or
+// listed SKU. Its failures keep the SKU label, under category when one is given.
+func (m *Metrics) AddKnownProduct(sku, category string) {
+	if m == nil || sku == "" {
+		return
+	}
+	m.knownMu.Lock()
+	defer m.knownMu.Unlock()
+	if category != "" || m.knownProducts[sku] == "" {
+		m.knownProducts[sku] = category
+	}
+}
+
+// knownProduct returns the category of a known product
+func (m *Metrics) knownProduct(sku string) (string, bool) {
+	m.knownMu.RLock()
+	defer m.knownMu.RUnlock()
+	category, ok := m.knownProducts[sku]
+	return category, ok
+}
+
+// ObserveTransaction records the outcome of a purchase. status is success, pending or
+// failed. SKUs Digiflazz accepts become known products; failures of unknown SKUs are
+// counted under OtherLabel, and those of known ones under their known category.
+func (m *Metrics) ObserveTransaction(sku, category, status string) {
+	if m == nil {
+		return
+	}
+	if status == "success" || status == "pending" {
+		if _, ok := m.knownProduct(sku); !ok {
+			m.AddKnownProduct(sku, category)
+		}
+	} else {
+		status = "failed"
+		knownCategory, ok := m.knownProduct(sku)
+		switch {
+		case !ok:
+			sku, category = OtherLabel, OtherLabel
+		case knownCategory != "":
+			category = knownCategory
+		default:
+			category = OtherLabel
+		}
+	}
+	m.transactions.WithLabelValues(sku, category, status).Inc()
+}
+
+// ObservePLNCache records a PLN inquiry cache lookup result
+func (m *Metrics) ObservePLNCache(result string) {
+	if m == nil {
+		return
+	}
+	m.plnCache.WithLabelValues(result).Inc()
+}
+
+// SetDepositBalance records the last known deposit balance of a Digiflazz account
+func (m *Metrics) SetDepositBalance(account string, deposit float64) {
+	if m == nil {
+		return
+	}
+	m.depositBalance.WithLabelValues(account).Set(deposit)
+}
+
+// RegisterPendingTransactions exports the number of pending transactions, read from
+// count on every scrape
+func (m *Metrics) RegisterPendingTransactions(count func() int) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_transactions",
+		Help:      "Transactions still pending at Digiflazz.",
+	}, func() float64 {
+		return float64(count())
+	}))
+}
@@ -0,0 +1,126 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned when a phone number cannot be normalised or matched to an operator
+var (
+	ErrInvalidNumber   = errors.New("invalid phone number")
+	ErrUnknownOperator = errors.New("unknown operator prefix")
+	ErrInvalidLength   = errors.New("invalid phone number length")
+)
+
+// Operator describes an Indonesian mobile operator and its number format
+type Operator struct {
+	Code      string   `json:"code"`
+	Name      string   `json:"name"`
+	Prefixes  []string `json:"prefixes"`
+	MinLength int      `json:"min_length"`
+	MaxLength int      `json:"max_length"`
+}
+
+// Operators lists the supported operators with their 08xx prefixes
+var Operators = []Operator{
+	{
+		Code:      "telkomsel",
+		Name:      "Telkomsel",
+		Prefixes:  []string{"0811", "0812", "0813", "0821", "0822", "0823", "0851", "0852", "0853"},
+		MinLength: 11,
+		MaxLength: 13,
+	},
+	{
+		Code:      "indosat",
+		Name:      "Indosat",
+		Prefixes:  []string{"0814", "0815", "0816", "0855", "0856", "0857", "0858"},
+		MinLength: 10,
+		MaxLength: 13,
+	},
+	{
+		Code:      "xl",
+		Name:      "XL",
+		Prefixes:  []string{"0817", "0818", "0819", "0859", "0877", "0878"},
+		MinLength: 10,
+		MaxLength: 13,
+	},
+	{
+		Code:      "axis",
+		Name:      "Axis",
+		Prefixes:  []string{"0831", "0832", "0833", "0838"},
+		MinLength: 11,
+		MaxLength: 13,
+	},
+	{
+		Code:      "tri",
+		Name:      "Tri",
+		Prefixes:  []string{"0895", "0896", "0897", "0898", "0899"},
+		MinLength: 10,
+		MaxLength: 13,
+	},
+	{
+		Code:      "smartfren",
+		Name:      "Smartfren",
+		Prefixes:  []string{"0881", "0882", "0883", "0884", "0885", "0886", "0887", "0888", "0889"},
+		MinLength: 11,
+		MaxLength: 13,
+	},
+}
+
+// Normalize converts 62, +62, 8 and 08 formatted numbers to the local 08 format
+func Normalize(number string) (string, error) {
+	cleaned := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(number))
+
+	switch {
+	case strings.HasPrefix(cleaned, "+62"):
+		cleaned = "0" + cleaned[3:]
+	case strings.HasPrefix(cleaned, "62"):
+		cleaned = "0" + cleaned[2:]
+	case strings.HasPrefix(cleaned, "8"):
+		cleaned = "0" + cleaned
+	}
+
+	if !strings.HasPrefix(cleaned, "08") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidNumber, number)
+	}
+	for _, r := range cleaned {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%w: %s", ErrInvalidNumber, number)
+		}
+	}
+
+	return cleaned, nil
+}
+
+// Detect normalises number and returns its operator, validating the length for that operator
+func Detect(number string) (*Operator, string, error) {
+	normalized, err := Normalize(number)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(normalized) < 4 {
+		return nil, "", fmt.Errorf("%w: %s", ErrInvalidNumber, number)
+	}
+
+	prefix := normalized[:4]
+	for i := range Operators {
+		op := &Operators[i]
+		for _, p := range op.Prefixes {
+			if p != prefix {
+				continue
+			}
+			if len(normalized) < op.MinLength || len(normalized) > op.MaxLength {
+				return nil, "", fmt.Errorf("%w: %s numbers must be %d-%d digits", ErrInvalidLength, op.Name, op.MinLength, op.MaxLength)
+			}
+			return op, normalized, nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("%w: %s", ErrUnknownOperator, prefix)
+}
+
+// IsNumberError reports whether err was caused by an invalid or unrecognised phone number
+func IsNumberError(err error) bool {
+	return errors.Is(err, ErrInvalidNumber) || errors.Is(err, ErrUnknownOperator) || errors.Is(err, ErrInvalidLength)
+}
@@ -0,0 +1,76 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gateway-digiflazz/internal/config"
+)
+
+// Errors returned when a generic product code cannot be resolved for a customer number
+var (
+	ErrUnknownProduct     = errors.New("unknown generic product code")
+	ErrProductUnavailable = errors.New("product is not available for operator")
+)
+
+// Resolution is the result of resolving a generic product code for a customer number
+type Resolution struct {
+	BuyerSKU   string    `json:"buyer_sku"`
+	CustomerNo string    `json:"customer_no"`
+	Category   string    `json:"category"`
+	Operator   *Operator `json:"operator"`
+}
+
+// Resolver resolves generic product codes (e.g. PULSA10) to operator specific SKUs
+type Resolver struct {
+	products map[string]config.GenericProductConfig
+}
+
+// NewResolver creates a resolver from the configured generic products
+func NewResolver(products []config.GenericProductConfig) *Resolver {
+	resolver := &Resolver{
+		products: make(map[string]config.GenericProductConfig, len(products)),
+	}
+	for _, product := range products {
+		resolver.products[strings.ToUpper(product.Code)] = product
+	}
+	return resolver
+}
+
+// IsGeneric reports whether code is a configured generic product code
+func (r *Resolver) IsGeneric(code string) bool {
+	_, ok := r.products[strings.ToUpper(code)]
+	return ok
+}
+
+// Resolve detects the operator of customerNo and returns the SKU configured for it
+func (r *Resolver) Resolve(code, customerNo string) (*Resolution, error) {
+	product, ok := r.products[strings.ToUpper(code)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProduct, code)
+	}
+
+	op, normalized, err := Detect(customerNo)
+	if err != nil {
+		return nil, err
+	}
+
+	sku, ok := product.SKUs[op.Code]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not available for %s", ErrProductUnavailable, product.Code, op.Name)
+	}
+
+	return &Resolution{
+		BuyerSKU:   sku,
+		CustomerNo: normalized,
+		Category:   product.Category,
+		Operator:   op,
+	}, nil
+}
+
+// IsProductError reports whether err was caused by a generic product code that does not
+// resolve for the customer number
+func IsProductError(err error) bool {
+	return errors.Is(err, ErrUnknownProduct) || errors.Is(err, ErrProductUnavailable)
+}
@@ -0,0 +1,73 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Header carries the request ID in requests and responses
+const Header = "X-Request-ID"
+
+// Field is the log field and response body key holding the request ID
+const Field = "request_id"
+
+// maxLength bounds request IDs accepted from clients
+const maxLength = 64
+
+type contextKey struct{}
+
+// New returns a request ID made of the current time and 64 random bits
+func New() string {
+	now := time.Now().UTC()
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms; stay unique per nanosecond regardless
+		return now.Format("20060102150405") + "-" + strconv.FormatInt(now.UnixNano(), 16)
+	}
+	return now.Format("20060102150405") + "-" + hex.EncodeToString(b)
+}
+
+// Valid reports whether a client supplied request ID can be reused as is: at most 64
+// letters, digits, '-', '_', '.' or ':'
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithContext returns a copy of ctx carrying id
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or ""
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Logger returns a log entry tagged with the request ID carried by ctx, if any
+func Logger(ctx context.Context, logger *logrus.Logger) *logrus.Entry {
+	entry := logrus.NewEntry(logger)
+	if id := FromContext(ctx); id != "" {
+		entry = entry.WithField(Field, id)
+	}
+	return entry
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthRouterForTest serves /inquiry and /admin behind the matching scopes and echoes the client and query
+func newAuthRouterForTest(t *testing.T, store *middleware.APIKeyStore, logger *logrus.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Logger(logger))
+	echo := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"client": middleware.ClientName(c), "query": c.Request.URL.RawQuery})
+	}
+	router.GET("/inquiry", middleware.APIKeyAuth(store, middleware.ScopeInquiry, logger), echo)
+	router.GET("/admin", middleware.APIKeyAuth(store, middleware.ScopeAdmin, logger), echo)
+	return router
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	store, err := middleware.NewAPIKeyStore([]config.APIKeyConfig{
+		{Name: "otomax", KeyHash: middleware.HashAPIKey("otomax-key"), Scopes: []string{middleware.ScopeTransact, middleware.ScopeInquiry}},
+		{Name: "ops", KeyHash: middleware.HashAPIKey("ops-key"), Scopes: []string{middleware.ScopeAdmin}},
+	})
+	require.NoError(t, err)
+	require.True(t, store.Enabled())
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+	router := newAuthRouterForTest(t, store, logger)
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/inquiry", nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "UNAUTHORIZED")
+
+	rec = serve("/inquiry", http.Header{"X-Api-Key": {"wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "INVALID_API_KEY")
+
+	rec = serve("/inquiry", http.Header{"X-Api-Key": {"otomax-key"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"client":"otomax"`)
+
+	rec = serve("/inquiry", http.Header{"Authorization": {"Bearer otomax-key"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Query parameter keys are removed before the handler and redacted in logs
+	rec = serve("/inquiry?ref_id=1&api_key=otomax-key", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"query":"ref_id=1"`)
+	assert.NotContains(t, logs.String(), "otomax-key")
+	assert.Contains(t, logs.String(), "client=otomax")
+
+	rec = serve("/admin", http.Header{"X-Api-Key": {"otomax-key"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "INSUFFICIENT_SCOPE")
+
+	rec = serve("/admin", http.Header{"X-Api-Key": {"ops-key"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	usage := store.Usage()
+	require.Len(t, usage, 2)
+	assert.Equal(t, "ops", usage[0].Name)
+	assert.Equal(t, int64(1), usage[0].Requests)
+	assert.Equal(t, "otomax", usage[1].Name)
+	assert.Equal(t, int64(4), usage[1].Requests)
+	require.NotNil(t, usage[1].LastUsedAt)
+	assert.NotEmpty(t, usage[1].LastUsedIP)
+}
+
+func TestAPIKeyAuthDisabledWithoutKeys(t *testing.T) {
+	store, err := middleware.NewAPIKeyStore(nil)
+	require.NoError(t, err)
+	assert.False(t, store.Enabled())
+
+	router := newAuthRouterForTest(t, store, logrus.New())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAPIKeyStoreValidation(t *testing.T) {
+	valid := middleware.HashAPIKey("key")
+	cases := map[string][]config.APIKeyConfig{
+		"missing name":   {{KeyHash: valid, Scopes: []string{"admin"}}},
+		"plaintext key":  {{Name: "a", KeyHash: "key", Scopes: []string{"admin"}}},
+		"unknown scope":  {{Name: "a", KeyHash: valid, Scopes: []string{"root"}}},
+		"no scopes":      {{Name: "a", KeyHash: valid}},
+		"duplicate name": {{Name: "a", KeyHash: valid, Scopes: []string{"admin"}}, {Name: "a", KeyHash: middleware.HashAPIKey("other"), Scopes: []string{"admin"}}},
+		"duplicate key":  {{Name: "a", KeyHash: valid, Scopes: []string{"admin"}}, {Name: "b", KeyHash: valid, Scopes: []string{"admin"}}},
+	}
+	for name, keys := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := middleware.NewAPIKeyStore(keys)
+			assert.Error(t, err)
+		})
+	}
+}
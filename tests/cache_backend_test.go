@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/pkg/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedisCacheForTest starts an in-process Redis stand-in and returns a cache scoped to prefix
+func newRedisCacheForTest(t *testing.T, prefix string) (*cache.RedisCache, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	redisCache := cache.NewRedisCacheWithOptions(cache.RedisOptions{Addr: mr.Addr(), KeyPrefix: prefix})
+	t.Cleanup(func() { redisCache.Close() })
+	return redisCache, mr
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GetSetDelete", func(t *testing.T) {
+		redisCache, mr := newRedisCacheForTest(t, "gw:")
+
+		require.NoError(t, redisCache.Set(ctx, "pln_inquiry:1", "value", 0))
+		value, err := redisCache.Get(ctx, "pln_inquiry:1")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+		assert.True(t, mr.Exists("gw:pln_inquiry:1"))
+
+		require.NoError(t, redisCache.Delete(ctx, "pln_inquiry:1"))
+		_, err = redisCache.Get(ctx, "pln_inquiry:1")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		redisCache, mr := newRedisCacheForTest(t, "gw:")
+
+		require.NoError(t, redisCache.Set(ctx, "short", "value", time.Minute))
+		mr.FastForward(2 * time.Minute)
+		_, err := redisCache.Get(ctx, "short")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+		assert.NoError(t, redisCache.DeleteExpired(ctx))
+	})
+
+	t.Run("ClearAllIsPrefixScoped", func(t *testing.T) {
+		redisCache, mr := newRedisCacheForTest(t, "gw:")
+
+		for i := 0; i < 1200; i++ {
+			require.NoError(t, redisCache.Set(ctx, "key:"+strconv.Itoa(i), "value", 0))
+		}
+		require.NoError(t, mr.Set("other:key", "keep"))
+
+		stats, err := redisCache.GetStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1200, stats["total_entries"])
+		assert.Equal(t, 1200, stats["persistent_entries"])
+		assert.Equal(t, "redis", stats["backend"])
+
+		require.NoError(t, redisCache.ClearAll(ctx))
+		assert.Len(t, mr.Keys(), 1)
+		assert.True(t, mr.Exists("other:key"))
+	})
+
+	t.Run("PLNInquiryService", func(t *testing.T) {
+		redisCache, _ := newRedisCacheForTest(t, "gw:")
+		server, calls := newFakePLNServer(t, 0)
+
+		client := newDigiflazzClientForTest(server.URL)
+		service := services.NewPLNInquiryService(client, logrus.New(), redisCache)
+		for i := 0; i < 2; i++ {
+			_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: "12345678901"}, "REF")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int64(1), *calls)
+	})
+}
+
+func TestCacheFactory(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		backend, err := cache.New(config.CacheConfig{Type: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "cache.db")}, config.RedisConfig{})
+		require.NoError(t, err)
+		defer backend.Close()
+		assert.IsType(t, &cache.SQLiteCache{}, backend)
+	})
+
+	t.Run("Redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		port, err := strconv.Atoi(mr.Port())
+		require.NoError(t, err)
+
+		backend, err := cache.New(config.CacheConfig{Type: "redis", KeyPrefix: "gw:"}, config.RedisConfig{Host: mr.Host(), Port: port})
+		require.NoError(t, err)
+		defer backend.Close()
+		assert.IsType(t, &cache.RedisCache{}, backend)
+	})
+
+	t.Run("Memory", func(t *testing.T) {
+		backend, err := cache.New(config.CacheConfig{Type: "memory"}, config.RedisConfig{})
+		require.NoError(t, err)
+		assert.IsType(t, &cache.MemoryCache{}, backend)
+
+		ctx := context.Background()
+		require.NoError(t, backend.Set(ctx, "key", "value", time.Millisecond))
+		time.Sleep(5 * time.Millisecond)
+		_, err = backend.Get(ctx, "key")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := cache.New(config.CacheConfig{Type: "memcached"}, config.RedisConfig{})
+		assert.Error(t, err)
+	})
+}
+
+func TestCacheScan(t *testing.T) {
+	ctx := context.Background()
+	sqliteCache, err := cache.NewSQLiteCache(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer sqliteCache.Close()
+	redisCache, _ := newRedisCacheForTest(t, "gw:")
+
+	backends := map[string]cache.Cache{
+		"sqlite": sqliteCache,
+		"redis":  redisCache,
+		"memory": cache.NewMemoryCache(),
+		"tiered": cache.NewTieredCache(cache.NewMemoryCache(), cache.TieredOptions{}),
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, backend.Set(ctx, "pln:2", "b", time.Hour))
+			require.NoError(t, backend.Set(ctx, "pln:1", "a", 0))
+			require.NoError(t, backend.Set(ctx, "other:1", "c", 0))
+
+			var keys []string
+			err := backend.(cache.Scanner).Scan(ctx, "pln:", func(entry cache.Entry) error {
+				keys = append(keys, entry.Key)
+				if entry.Key == "pln:1" {
+					assert.True(t, entry.ExpiresAt.IsZero())
+				} else {
+					assert.WithinDuration(t, time.Now().Add(time.Hour), entry.ExpiresAt, time.Minute)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"pln:1", "pln:2"}, keys)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gateway-digiflazz/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadSecretsFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret := func(name, value string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(value), 0600))
+		return path
+	}
+
+	t.Setenv("DIGIFLAZZ_API_KEY", "placeholder-from-env")
+	t.Setenv("DIGIFLAZZ_API_KEY_FILE", writeSecret("digiflazz_api_key", "dev-key-from-file\n"))
+	t.Setenv("DB_PASSWORD_FILE", writeSecret("db_password", "db-secret"))
+	t.Setenv("OTOMAX_RESELLERS_FILE", writeSecret("otomax_resellers", "R001@otomax-r001:hmac-sha256:reseller-secret"))
+	t.Setenv("REDIS_PASSWORD", "redis-secret")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	// The file wins over the plain variable and is trimmed
+	assert.Equal(t, "dev-key-from-file", cfg.Digiflazz.APIKey)
+	assert.Equal(t, "db-secret", cfg.Database.Password)
+	assert.Equal(t, "redis-secret", cfg.Redis.Password)
+	require.Len(t, cfg.Otomax.Resellers, 1)
+	assert.Equal(t, "reseller-secret", cfg.Otomax.Resellers[0].Secret)
+	assert.Equal(t, "R001", cfg.Otomax.Resellers[0].ID)
+	assert.Equal(t, "otomax-r001", cfg.Otomax.Resellers[0].Client)
+
+	t.Setenv("JWT_SECRET_FILE", filepath.Join(dir, "missing"))
+	_, err = config.Load()
+	assert.ErrorContains(t, err, "JWT_SECRET_FILE")
+
+	t.Setenv("JWT_SECRET_FILE", writeSecret("jwt_secret", "  \n"))
+	_, err = config.Load()
+	assert.ErrorContains(t, err, "is empty")
+}
+
+func TestLoadRejectsMalformedCredentialEntries(t *testing.T) {
+	for _, tc := range []struct {
+		name, variable, value string
+	}{
+		{"APIKeyMissingScopes", "API_KEYS", "otomax:0123456789abcdef"},
+		{"APIKeyEmptyHash", "API_KEYS", "ops:0123456789abcdef:admin,otomax::transact"},
+		{"ResellerMissingSecret", "OTOMAX_RESELLERS", "R001:hmac-sha256"},
+		{"ResellerEmptyID", "OTOMAX_RESELLERS", "@otomax-r001:md5:reseller-secret"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(tc.variable, tc.value)
+			_, err := config.Load()
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tc.variable)
+			assert.NotContains(t, err.Error(), "reseller-secret")
+		})
+	}
+}
+
+func TestMaskedConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Digiflazz.Username = "gateway-user"
+	cfg.Digiflazz.APIKey = "digiflazz-api-key"
+	cfg.Digiflazz.Accounts = []config.DigiflazzAccountConfig{{Name: "backup", APIKey: "backup-api-key"}}
+	cfg.Database.Password = "db-password"
+	cfg.Redis.Password = "redis-password"
+	cfg.Security.JWTSecret = "jwt-secret"
+	cfg.Security.APIKeys = []config.APIKeyConfig{{Name: "otomax", KeyHash: "0123456789abcdef"}}
+	cfg.Otomax.SecretKey = "otomax-secret"
+	cfg.Otomax.Resellers = []config.OtomaxResellerConfig{{ID: "R001", Secret: "reseller-secret"}}
+
+	data, err := yaml.Marshal(cfg.Masked())
+	require.NoError(t, err)
+	out := string(data)
+	for _, secret := range []string{"digiflazz-api-key", "backup-api-key", "db-password", "redis-password",
+		"jwt-secret", "0123456789abcdef", "otomax-secret", "reseller-secret"} {
+		assert.NotContains(t, out, secret)
+	}
+	assert.Contains(t, out, "gateway-user")
+	assert.Contains(t, out, "backup")
+	assert.Contains(t, out, "R001")
+
+	// The original configuration is left untouched
+	assert.Equal(t, "backup-api-key", cfg.Digiflazz.Accounts[0].APIKey)
+	assert.Equal(t, "reseller-secret", cfg.Otomax.Resellers[0].Secret)
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/pkg/digiflazz"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	redactionAPIKey = "dev-6f1b2c3d4e5f"
+	redactionToken  = "1234-5678-9012-3456-7890"
+)
+
+// newRedactionServerForTest answers every Digiflazz endpoint, echoing the secrets it received
+func newRedactionServerForTest(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		switch r.URL.Path {
+		case "/topup":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"ref_id": req["ref_id"], "status": "Sukses", "rc": "00",
+				"sn": redactionToken + "/BUDI/R1/1300/20", "sign": req["sign"],
+			}})
+		case "/inquiry-pln":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"customer_no": req["customer_no"], "status": "Sukses", "rc": "00", "name": "BUDI",
+			}})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"data":{"message":"invalid","api_key":%q,"sign":%q}}`, req["api_key"], req["sign"])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDigiflazzClientRedactsSecrets(t *testing.T) {
+	server := newRedactionServerForTest(t)
+	cfg := config.DigiflazzConfig{
+		BaseURL:       server.URL,
+		Username:      "gatewayuser",
+		APIKey:        redactionAPIKey,
+		Timeout:       5 * time.Second,
+		RetryAttempts: 1,
+		BodyLogLevel:  "info",
+	}
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetLevel(logrus.TraceLevel)
+	logger.SetOutput(&logs)
+	client := digiflazz.NewClient(cfg, logger)
+	ctx := context.Background()
+
+	_, err := client.Topup(ctx, models.TopupRequest{RefID: "TRX500", CustomerNo: "12345678901", BuyerSKU: "pln20"})
+	require.NoError(t, err)
+	_, err = client.InquiryPLN(ctx, models.PLNInquiryRequest{CustomerNo: "12345678901"})
+	require.NoError(t, err)
+	_, err = client.CheckStatus(ctx, "TRX500")
+	require.Error(t, err)
+
+	output := logs.String()
+	assert.Contains(t, output, "Digiflazz request body")
+	assert.Contains(t, output, "[redacted]")
+	assert.Contains(t, output, "****-****-****-****-7890")
+
+	secrets := map[string]string{
+		"api key":               redactionAPIKey,
+		"transaction sign":      md5Hex("gatewayuser" + redactionAPIKey + "TRX500"),
+		"pln sign":              md5Hex("gatewayuser" + redactionAPIKey + "12345678901"),
+		"pln signature input":   "gatewayuser" + redactionAPIKey + "12345678901",
+		"topup signature input": "gatewayuser" + redactionAPIKey + "TRX500",
+		"username and api key":  "gatewayuser" + redactionAPIKey,
+		"api key and customer":  redactionAPIKey + "12345678901",
+		"pln token":             redactionToken,
+		"pln token (no dashes)": "12345678901234567890",
+	}
+	for name, secret := range secrets {
+		assert.NotContains(t, output, secret, name)
+	}
+}
+
+func TestDigiflazzBodyLogLevel(t *testing.T) {
+	server := newRedactionServerForTest(t)
+
+	serve := func(bodyLogLevel string, level logrus.Level) string {
+		var logs bytes.Buffer
+		logger := logrus.New()
+		logger.SetLevel(level)
+		logger.SetOutput(&logs)
+		client := digiflazz.NewClient(config.DigiflazzConfig{
+			BaseURL: server.URL, Username: "gatewayuser", APIKey: redactionAPIKey,
+			Timeout: 5 * time.Second, RetryAttempts: 1, BodyLogLevel: bodyLogLevel,
+		}, logger)
+		_, err := client.Topup(context.Background(), models.TopupRequest{RefID: "TRX501", CustomerNo: "12345678901", BuyerSKU: "pln20"})
+		require.NoError(t, err)
+		return logs.String()
+	}
+
+	// Bodies are logged at debug by default, and nothing else at info names the username
+	// or repeats the client configuration
+	infoLogs := serve("", logrus.InfoLevel)
+	assert.NotContains(t, infoLogs, "Digiflazz request body")
+	assert.NotContains(t, infoLogs, "gatewayuser")
+	assert.NotContains(t, infoLogs, "Digiflazz client configuration")
+	assert.Equal(t, 1, strings.Count(serve("", logrus.DebugLevel), "Digiflazz client configuration"))
+	assert.Contains(t, serve("", logrus.DebugLevel), "Digiflazz request body")
+	assert.Contains(t, serve("info", logrus.InfoLevel), "Digiflazz response body")
+	assert.NotContains(t, serve("off", logrus.TraceLevel), "Digiflazz request body")
+
+	_, err := digiflazz.NewPool(config.DigiflazzConfig{BaseURL: server.URL, BodyLogLevel: "loud"}, logrus.New())
+	assert.Error(t, err)
+}
+
+func TestRedactBody(t *testing.T) {
+	assert.JSONEq(t,
+		`{"username":"u","api_key":"[redacted]","sign":"[redacted]","data":[{"sn":"****-****-****-****-7890/BUDI"}]}`,
+		digiflazz.RedactBody([]byte(`{"username":"u","api_key":"k","sign":"s","data":[{"sn":"`+redactionToken+`/BUDI"}]}`)))
+
+	// Bodies that are not valid JSON are still redacted
+	assert.Equal(t, `{"api_key":"[redacted]", "sn":"****-****-****-****-7890"`,
+		digiflazz.RedactBody([]byte(`{"api_key":"k", "sn":"12345678901234567890"`)))
+}
+
+func md5Hex(value string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(value)))
+}
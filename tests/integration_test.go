@@ -1,11 +1,13 @@
 package tests
 
 import (
+	"context"
 	"testing"
 
 	"gateway-digiflazz/internal/config"
 	"gateway-digiflazz/pkg/digiflazz"
 
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,7 +17,7 @@ func TestDigiflazzClient(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Create client
-	client := digiflazz.NewClient(cfg.Digiflazz)
+	client := digiflazz.NewClient(cfg.Digiflazz, logrus.New())
 
 	// Test balance check (this will fail without valid credentials)
 	t.Run("CheckBalance", func(t *testing.T) {
@@ -24,7 +26,7 @@ func TestDigiflazzClient(t *testing.T) {
 			t.Skip("Skipping test: No Digiflazz credentials provided")
 		}
 
-		balance, err := client.CheckBalance()
+		balance, err := client.CheckBalance(context.Background())
 		assert.NoError(t, err)
 		assert.NotNil(t, balance)
 	})
@@ -36,7 +38,7 @@ func TestDigiflazzClient(t *testing.T) {
 			t.Skip("Skipping test: No Digiflazz credentials provided")
 		}
 
-		prices, err := client.GetPrices("prabayar")
+		prices, err := client.GetPrices(context.Background(), "prabayar")
 		assert.NoError(t, err)
 		assert.NotNil(t, prices)
 	})
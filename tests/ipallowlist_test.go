@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gateway-digiflazz/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	list, err := middleware.NewIPAllowlist(middleware.AllowlistGroupOtomax, []string{"203.0.113.0/24", "198.51.100.7", "2001:db8::/32"})
+	require.NoError(t, err)
+	assert.True(t, list.Enabled())
+	assert.True(t, list.Allows("203.0.113.20"))
+	assert.True(t, list.Allows("198.51.100.7"))
+	assert.True(t, list.Allows("2001:db8::1"))
+	assert.False(t, list.Allows("198.51.100.8"))
+	assert.False(t, list.Allows("not-an-ip"))
+
+	empty, err := middleware.NewIPAllowlist(middleware.AllowlistGroupAdmin, nil)
+	require.NoError(t, err)
+	assert.False(t, empty.Enabled())
+	assert.True(t, empty.Allows("192.0.2.1"))
+
+	_, err = middleware.NewIPAllowlist(middleware.AllowlistGroupAdmin, []string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = middleware.NewIPAllowlists(map[string][]string{"public": {"10.0.0.0/8"}})
+	assert.Error(t, err)
+}
+
+func TestIPAllowlistAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+
+	lists, err := middleware.NewIPAllowlists(map[string][]string{middleware.AllowlistGroupOtomax: {"203.0.113.0/24"}})
+	require.NoError(t, err)
+
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/otomax/status", middleware.IPAllowlistAuth(lists[middleware.AllowlistGroupOtomax], logger), ok)
+	// Groups without an allowlist are open
+	router.GET("/admin", middleware.IPAllowlistAuth(lists[middleware.AllowlistGroupAdmin], logger), ok)
+
+	serve := func(path, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/otomax/status", "203.0.113.5:4000", "").Code)
+
+	rec := serve("/otomax/status", "192.0.2.1:4000", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "IP_NOT_ALLOWED")
+	assert.Contains(t, logs.String(), "ip=192.0.2.1")
+
+	// The forwarded client IP is used behind a trusted proxy
+	assert.Equal(t, http.StatusOK, serve("/otomax/status", "10.0.0.2:4000", "203.0.113.9").Code)
+	assert.Equal(t, http.StatusForbidden, serve("/otomax/status", "10.0.0.2:4000", "192.0.2.1").Code)
+
+	// A spoofed header from an untrusted peer is ignored
+	assert.Equal(t, http.StatusForbidden, serve("/otomax/status", "192.0.2.1:4000", "203.0.113.9").Code)
+
+	assert.Equal(t, http.StatusOK, serve("/admin", "192.0.2.1:4000", "").Code)
+}
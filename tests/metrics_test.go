@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/middleware"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrapeMetrics returns the exposition text served by the metrics handler
+func scrapeMetrics(t *testing.T, m *metrics.Metrics) string {
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.New()
+
+	router := gin.New()
+	router.Use(middleware.Metrics(m))
+	router.GET("/api/v1/transaction/:ref_id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/api/v1/transaction/TRX1", "/api/v1/transaction/TRX2", "/does-not-exist"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	out := scrapeMetrics(t, m)
+	// Requests are labelled by route pattern, not by raw path
+	assert.Contains(t, out, `gateway_http_requests_total{method="GET",route="/api/v1/transaction/:ref_id",status="200"} 2`)
+	assert.Contains(t, out, `gateway_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, out, `gateway_http_request_duration_seconds_count{method="GET",route="/api/v1/transaction/:ref_id"} 2`)
+	assert.NotContains(t, out, "TRX1")
+
+	// Disabled metrics leave the middleware a no-op
+	router = gin.New()
+	router.Use(middleware.Metrics(nil))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestDigiflazzAndTransactionMetrics(t *testing.T) {
+	// Fake Digiflazz: the primary account has no balance, the backup leaves purchases pending
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.TopupRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		var resp models.TopupResponse
+		resp.Data.RefID = req.RefID
+		if req.Username == "primary" {
+			resp.Data.RC = digiflazz.RCInsufficientBalance
+			resp.Data.Status = "Gagal"
+		} else {
+			resp.Data.RC = digiflazz.RCPending
+			resp.Data.Status = "Pending"
+			resp.Data.BuyerLastSaldo = 150000
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	pool, err := digiflazz.NewPool(config.DigiflazzConfig{
+		BaseURL:       server.URL,
+		Username:      "primary",
+		APIKey:        "primary-key",
+		Timeout:       5 * time.Second,
+		RetryAttempts: 1,
+		Failover:      "backup",
+		Accounts: []config.DigiflazzAccountConfig{
+			{Name: "backup", Username: "backup", APIKey: "backup-key"},
+		},
+	}, logger)
+	require.NoError(t, err)
+
+	m := metrics.New()
+	pool.SetMetrics(m)
+	repository := repositories.NewMemoryOtomaxTransactionRepository()
+	service := services.NewOtomaxService(pool, repository, logger, "secret")
+	service.SetMetrics(m)
+	m.RegisterPendingTransactions(service.PendingTransactions)
+
+	resp, err := service.ProcessTransaction(context.Background(), models.OtomaxTransactionRequest{
+		RefID:      "TRX200",
+		CustomerNo: "081234567890",
+		BuyerSKU:   "tsel10",
+		Amount:     "10000",
+		Type:       "prabayar",
+		Category:   "Pulsa",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pending", resp.Status)
+
+	out := scrapeMetrics(t, m)
+	assert.Contains(t, out, `gateway_digiflazz_requests_total{endpoint="/topup",rc="44"} 1`)
+	assert.Contains(t, out, `gateway_digiflazz_errors_total{endpoint="/topup",rc="44"} 1`)
+	// Pending is not an error
+	assert.Contains(t, out, `gateway_digiflazz_requests_total{endpoint="/topup",rc="03"} 1`)
+	assert.NotContains(t, out, `gateway_digiflazz_errors_total{endpoint="/topup",rc="03"}`)
+	assert.Contains(t, out, `gateway_digiflazz_request_duration_seconds_count{endpoint="/topup"} 2`)
+	assert.Contains(t, out, `gateway_transactions_total{category="Pulsa",sku="tsel10",status="pending"} 1`)
+	assert.Contains(t, out, "gateway_pending_transactions 1")
+	assert.Contains(t, out, `gateway_digiflazz_deposit_balance{account="backup"} 150000`)
+}
+
+func TestTransactionMetricsLabels(t *testing.T) {
+	t.Run("FailedSKUsAreOther", func(t *testing.T) {
+		server, _ := newFakeTopupServer(t, map[string]string{"tsel10": digiflazz.RCSuccess})
+		service, _ := newOtomaxServiceForTest(t, server.URL)
+		m := metrics.New()
+		service.SetMetrics(m)
+
+		for i, sku := range []string{"tsel10", "made-up-1", "made-up-2"} {
+			_, _ = service.ProcessTransaction(context.Background(), models.OtomaxTransactionRequest{
+				RefID:      fmt.Sprintf("TRX30%d", i),
+				CustomerNo: "081234567890",
+				BuyerSKU:   sku,
+				Amount:     "10000",
+				Type:       "prabayar",
+				Category:   "Pulsa",
+			})
+		}
+
+		out := scrapeMetrics(t, m)
+		assert.Contains(t, out, `gateway_transactions_total{category="Pulsa",sku="tsel10",status="success"} 1`)
+		assert.Contains(t, out, `gateway_transactions_total{category="other",sku="other",status="failed"} 2`)
+		assert.NotContains(t, out, "made-up")
+	})
+
+	t.Run("KnownSKUsKeepLabels", func(t *testing.T) {
+		server, _ := newFakeTopupServer(t, map[string]string{
+			"tsel10": digiflazz.RCSuccess,
+			"isat10": digiflazz.RCOutOfStock,
+			"xl10":   digiflazz.RCOutOfStock,
+		})
+		service, _ := newOtomaxServiceForTest(t, server.URL)
+		m := metrics.New()
+		service.SetMetrics(m)
+
+		// A configured SKU keeps its configured category, whatever the caller sent
+		m.AddKnownProduct("isat10", "Pulsa")
+		// The price list makes its products known
+		priceServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(models.PriceResponse{Data: []models.Product{{Code: "xl10", Category: "Pulsa"}}})
+		}))
+		defer priceServer.Close()
+		priceService := services.NewPriceService(newDigiflazzClientForTest(priceServer.URL), logrus.New())
+		priceService.SetMetrics(m)
+		_, err := priceService.GetPrices(context.Background(), "")
+		require.NoError(t, err)
+
+		for i, sku := range []string{"tsel10", "isat10", "xl10", "made-up"} {
+			_, _ = service.ProcessTransaction(context.Background(), models.OtomaxTransactionRequest{
+				RefID:      fmt.Sprintf("TRX31%d", i),
+				CustomerNo: "081234567890",
+				BuyerSKU:   sku,
+				Amount:     "10000",
+				Type:       "prabayar",
+				Category:   "anything",
+			})
+		}
+		// A SKU Digiflazz accepted is known from then on
+		server.Close()
+		_, _ = service.ProcessTransaction(context.Background(), models.OtomaxTransactionRequest{
+			RefID: "TRX319", CustomerNo: "081234567890", BuyerSKU: "tsel10", Amount: "10000", Type: "prabayar", Category: "anything",
+		})
+
+		out := scrapeMetrics(t, m)
+		assert.Contains(t, out, `gateway_transactions_total{category="Pulsa",sku="isat10",status="failed"} 1`)
+		assert.Contains(t, out, `gateway_transactions_total{category="Pulsa",sku="xl10",status="failed"} 1`)
+		assert.Contains(t, out, `gateway_transactions_total{category="anything",sku="tsel10",status="failed"} 1`)
+		assert.Contains(t, out, `gateway_transactions_total{category="other",sku="other",status="failed"} 1`)
+		assert.NotContains(t, out, "made-up")
+	})
+
+	t.Run("PLNTokenAndPendingGauge", func(t *testing.T) {
+		server := newPendingPLNServer(t, "12345678901234567890")
+		service, _ := newOtomaxServiceForTest(t, server.URL)
+		m := metrics.New()
+		service.SetMetrics(m)
+		m.RegisterPendingTransactions(service.PendingTransactions)
+
+		_, err := service.PurchasePLNToken(context.Background(), models.OtomaxPLNTokenRequest{
+			RefID:       "PLN300",
+			CustomerNo:  "12345678901",
+			BuyerSKU:    "pln20",
+			SkipInquiry: true,
+		})
+		require.NoError(t, err)
+		out := scrapeMetrics(t, m)
+		assert.Contains(t, out, `gateway_transactions_total{category="PLN",sku="pln20",status="pending"} 1`)
+		assert.Contains(t, out, "gateway_pending_transactions 1")
+
+		// The status check resolves the purchase and the gauge with it
+		_, err = service.CheckStatus(context.Background(), models.OtomaxStatusRequest{RefID: "PLN300"})
+		require.NoError(t, err)
+		assert.Contains(t, scrapeMetrics(t, m), "gateway_pending_transactions 0")
+	})
+}
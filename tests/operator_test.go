@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/handlers"
+	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/operator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperatorDetection(t *testing.T) {
+	cases := []struct {
+		number     string
+		operator   string
+		normalized string
+	}{
+		{"081234567890", "telkomsel", "081234567890"},
+		{"+62 857-1234-5678", "indosat", "085712345678"},
+		{"6281712345678", "xl", "081712345678"},
+		{"89612345678", "tri", "089612345678"},
+		{"0881-2345-6789", "smartfren", "088123456789"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.number, func(t *testing.T) {
+			op, normalized, err := operator.Detect(tc.number)
+			require.NoError(t, err)
+			assert.Equal(t, tc.operator, op.Code)
+			assert.Equal(t, tc.normalized, normalized)
+		})
+	}
+
+	t.Run("InvalidNumbers", func(t *testing.T) {
+		_, _, err := operator.Detect("021555123")
+		assert.ErrorIs(t, err, operator.ErrInvalidNumber)
+
+		_, _, err = operator.Detect("0800123456789")
+		assert.ErrorIs(t, err, operator.ErrUnknownOperator)
+
+		_, _, err = operator.Detect("08123")
+		assert.ErrorIs(t, err, operator.ErrInvalidLength)
+	})
+}
+
+func TestGenericProductResolution(t *testing.T) {
+	resolver := operator.NewResolver([]config.GenericProductConfig{
+		{Code: "PULSA10", Category: "Pulsa", SKUs: map[string]string{"telkomsel": "tsel10", "indosat": "isat10"}},
+	})
+
+	assert.True(t, resolver.IsGeneric("pulsa10"))
+	assert.False(t, resolver.IsGeneric("tsel10"))
+
+	resolution, err := resolver.Resolve("PULSA10", "+6285712345678")
+	require.NoError(t, err)
+	assert.Equal(t, "isat10", resolution.BuyerSKU)
+	assert.Equal(t, "085712345678", resolution.CustomerNo)
+	assert.Equal(t, "Pulsa", resolution.Category)
+
+	_, err = resolver.Resolve("PULSA10", "081712345678")
+	assert.ErrorIs(t, err, operator.ErrProductUnavailable)
+	assert.True(t, operator.IsProductError(err))
+
+	_, err = resolver.Resolve("PULSA99", "081234567890")
+	assert.ErrorIs(t, err, operator.ErrUnknownProduct)
+	assert.False(t, operator.IsNumberError(err))
+}
+
+func TestOtomaxUnavailableGenericProduct(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, received := newFakeTopupServer(t, map[string]string{"tsel10": digiflazz.RCSuccess})
+	service, _ := newOtomaxServiceForTest(t, server.URL)
+	service.SetProductResolver(operator.NewResolver([]config.GenericProductConfig{
+		{Code: "PULSA10", Category: "Pulsa", SKUs: map[string]string{"telkomsel": "tsel10"}},
+	}))
+
+	handler := handlers.NewOtomaxHandler(service, nil, logrus.New())
+	router := gin.New()
+	router.GET("/otomax/transaction", handler.ProcessTransaction)
+
+	// XL has no SKU for PULSA10: a client error, not a gateway failure
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet,
+		"/otomax/transaction?ref_id=TRX600&customer_no=081712345678&buyer_sku=PULSA10&amount=10000&type=prabayar", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "PRODUCT_NOT_AVAILABLE")
+	assert.Empty(t, *received)
+}
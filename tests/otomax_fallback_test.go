@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/pkg/digiflazz"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeTopupServer returns a Digiflazz stand-in answering topups with the RC configured per SKU
+func newFakeTopupServer(t *testing.T, rcBySKU map[string]string) (*httptest.Server, *[]models.TopupRequest) {
+	var mu sync.Mutex
+	var received []models.TopupRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.TopupRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		mu.Lock()
+		received = append(received, req)
+		mu.Unlock()
+
+		var resp models.TopupResponse
+		resp.Data.RefID = req.RefID
+		resp.Data.BuyerSKU = req.BuyerSKU
+		resp.Data.RC = rcBySKU[req.BuyerSKU]
+		if resp.Data.RC == digiflazz.RCSuccess {
+			resp.Data.Status = "Sukses"
+			resp.Data.SN = "SN-" + req.BuyerSKU
+		} else {
+			resp.Data.Status = "Gagal"
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+func newOtomaxServiceForTest(t *testing.T, baseURL string) (*services.OtomaxService, repositories.OtomaxTransactionRepository) {
+	pool, err := digiflazz.NewPool(config.DigiflazzConfig{
+		BaseURL:       baseURL,
+		Username:      "user",
+		APIKey:        "key",
+		Timeout:       5 * time.Second,
+		RetryAttempts: 1,
+	}, logrus.New())
+	require.NoError(t, err)
+
+	repository := repositories.NewMemoryOtomaxTransactionRepository()
+	service := services.NewOtomaxService(pool, repository, logrus.New(), "secret")
+	service.SetFallbackChains([]config.FallbackChainConfig{
+		{SKU: "tsel10", Fallbacks: []string{"tsel10b", "tsel10c"}, MaxPrice: 11000},
+	})
+	return service, repository
+}
+
+func TestOtomaxFallbackChain(t *testing.T) {
+	t.Run("WalksChainUntilSuccess", func(t *testing.T) {
+		server, received := newFakeTopupServer(t, map[string]string{
+			"tsel10":  digiflazz.RCProductDisturbance,
+			"tsel10b": digiflazz.RCOutOfStock,
+			"tsel10c": digiflazz.RCSuccess,
+		})
+		service, repository := newOtomaxServiceForTest(t, server.URL)
+
+		resp, err := service.ProcessTransaction(context.Background(), models.OtomaxTransactionRequest{
+			RefID:      "TRX100",
+			CustomerNo: "081234567890",
+			BuyerSKU:   "tsel10",
+			Amount:     "10000",
+			Type:       "prabayar",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "success", resp.Status)
+		assert.Equal(t, "SN-tsel10c", resp.SN)
+		assert.Equal(t, "tsel10c", resp.BuyerSKU)
+
+		require.Len(t, *received, 3)
+		assert.Equal(t, "TRX100", (*received)[0].RefID)
+		assert.Equal(t, "TRX100-1", (*received)[1].RefID)
+		assert.Equal(t, "TRX100-2", (*received)[2].RefID)
+		assert.Equal(t, float64(11000), (*received)[2].MaxPrice)
+
+		transaction, err := repository.GetByRefID("TRX100")
+		require.NoError(t, err)
+		assert.Len(t, transaction.Attempts, 3)
+		assert.Equal(t, "tsel10c", transaction.BuyerSKU)
+		assert.Equal(t, "tsel10", transaction.Attempts[0].BuyerSKU)
+	})
+
+	t.Run("StopsOnCustomerError", func(t *testing.T) {
+		server, received := newFakeTopupServer(t, map[string]string{
+			"tsel10": digiflazz.RCInvalidNumber,
+		})
+		service, _ := newOtomaxServiceForTest(t, server.URL)
+
+		resp, err := service.ProcessTransaction(context.Background(), models.OtomaxTransactionRequest{
+			RefID: "TRX101",
+			// Passes the gateway's own check; Digiflazz rejects it
+			CustomerNo: "081234567890",
+			BuyerSKU:   "tsel10",
+			Amount:     "10000",
+			Type:       "prabayar",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "failed", resp.Status)
+		assert.Len(t, *received, 1)
+	})
+}
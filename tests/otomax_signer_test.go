@@ -0,0 +1,409 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/handlers"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/pkg/digiflazz"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signRequestForTest fills in the signature of req with algorithm and secret
+func signRequestForTest(t *testing.T, algorithm, secret string, req models.OtomaxRequestSignature) models.OtomaxRequestSignature {
+	sign, err := services.RequestSignature(algorithm, secret, req)
+	require.NoError(t, err)
+	req.Sign = sign
+	return req
+}
+
+func TestOtomaxSignerConfig(t *testing.T) {
+	_, err := services.NewOtomaxSigner(config.OtomaxConfig{Resellers: []config.OtomaxResellerConfig{
+		{ID: "R1", RequestSigning: "sha1", Secret: "s"},
+	}})
+	assert.Error(t, err)
+
+	_, err = services.NewOtomaxSigner(config.OtomaxConfig{Resellers: []config.OtomaxResellerConfig{
+		{ID: "R1", RequestSigning: services.SignatureHMACSHA256},
+	}})
+	assert.Error(t, err, "signing without any secret")
+
+	// The well-known default key must not stand in for a reseller secret
+	_, err = services.NewOtomaxSigner(config.OtomaxConfig{SecretKey: services.DefaultOtomaxSecretKey, Resellers: []config.OtomaxResellerConfig{
+		{ID: "R1", RequestSigning: services.SignatureHMACSHA256},
+	}})
+	assert.Error(t, err, "signing with the placeholder gateway secret")
+
+	_, err = services.NewOtomaxSigner(config.OtomaxConfig{SecretKey: services.DefaultOtomaxSecretKey, Resellers: []config.OtomaxResellerConfig{
+		{ID: "R1", RequestSigning: services.SignatureHMACSHA256, Secret: "r1-secret"},
+		{ID: "R2"},
+	}})
+	assert.NoError(t, err)
+
+	_, err = services.NewOtomaxSigner(config.OtomaxConfig{Resellers: []config.OtomaxResellerConfig{
+		{ID: "R1", Secret: "a"},
+		{ID: "R1", Secret: "b"},
+	}})
+	assert.Error(t, err)
+}
+
+func TestOtomaxRequestSigning(t *testing.T) {
+	signer, err := services.NewOtomaxSigner(config.OtomaxConfig{
+		SecretKey:    "gateway-secret",
+		MaxClockSkew: time.Minute,
+		Resellers: []config.OtomaxResellerConfig{
+			{ID: "R1", Secret: "r1-secret", RequestSigning: services.SignatureHMACSHA256},
+			{ID: "R2", RequestSigning: services.SignatureMD5},
+			{ID: "R3", Client: "otomax-r3", Secret: "r3-secret", RequestSigning: services.SignatureHMACSHA256},
+			{ID: "R4"},
+		},
+	})
+	require.NoError(t, err)
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	base := models.OtomaxRequestSignature{
+		ResellerID: "R1",
+		RefID:      "TRX1",
+		CustomerNo: "081234567890",
+		BuyerSKU:   "tsel10",
+		Timestamp:  now,
+		Nonce:      "n-1",
+	}
+	codeOf := func(err error) string {
+		sigErr, ok := services.AsSignatureError(err)
+		require.True(t, ok, "expected a signature error, got %v", err)
+		return sigErr.Code
+	}
+
+	t.Run("HMAC", func(t *testing.T) {
+		req := signRequestForTest(t, services.SignatureHMACSHA256, "r1-secret", base)
+		assert.NoError(t, signer.VerifyRequest(req))
+		assert.Equal(t, services.SignatureErrReplayed, codeOf(signer.VerifyRequest(req)))
+
+		tampered := req
+		tampered.BuyerSKU = "tsel100"
+		assert.Equal(t, services.SignatureErrInvalid, codeOf(signer.VerifyRequest(tampered)))
+
+		wrongSecret := base
+		wrongSecret.Nonce = "n-2"
+		wrongSecret = signRequestForTest(t, services.SignatureHMACSHA256, "gateway-secret", wrongSecret)
+		assert.Equal(t, services.SignatureErrInvalid, codeOf(signer.VerifyRequest(wrongSecret)))
+
+		noNonce := base
+		noNonce.Nonce = ""
+		noNonce = signRequestForTest(t, services.SignatureHMACSHA256, "r1-secret", noNonce)
+		assert.Equal(t, services.SignatureErrMissing, codeOf(signer.VerifyRequest(noNonce)))
+
+		unsigned := base
+		assert.Equal(t, services.SignatureErrMissing, codeOf(signer.VerifyRequest(unsigned)))
+	})
+
+	t.Run("TimestampSkew", func(t *testing.T) {
+		old := base
+		old.Nonce = "n-old"
+		old.Timestamp = time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339)
+		old = signRequestForTest(t, services.SignatureHMACSHA256, "r1-secret", old)
+		assert.Equal(t, services.SignatureErrExpired, codeOf(signer.VerifyRequest(old)))
+
+		recent := base
+		recent.Nonce = "n-recent"
+		recent.Timestamp = time.Now().Add(-30 * time.Second).UTC().Format(time.RFC3339)
+		recent = signRequestForTest(t, services.SignatureHMACSHA256, "r1-secret", recent)
+		assert.NoError(t, signer.VerifyRequest(recent))
+
+		garbage := base
+		garbage.Timestamp = "yesterday"
+		garbage = signRequestForTest(t, services.SignatureHMACSHA256, "r1-secret", garbage)
+		assert.Equal(t, services.SignatureErrExpired, codeOf(signer.VerifyRequest(garbage)))
+	})
+
+	t.Run("LegacyMD5", func(t *testing.T) {
+		req := base
+		req.ResellerID = "R2"
+		req.Nonce = ""
+		// Resellers without their own secret sign with the gateway secret key
+		req = signRequestForTest(t, services.SignatureMD5, "gateway-secret", req)
+		assert.NoError(t, signer.VerifyRequest(req))
+		assert.Equal(t, services.SignatureErrReplayed, codeOf(signer.VerifyRequest(req)))
+	})
+
+	t.Run("ClientTakesPrecedence", func(t *testing.T) {
+		// The authenticated client cannot escape signing by sending another reseller_id
+		req := base
+		req.ResellerID = "R4"
+		req.ClientID = "otomax-r3"
+		assert.Equal(t, services.SignatureErrMissing, codeOf(signer.VerifyRequest(req)))
+
+		req.Nonce = "n-r3"
+		req = signRequestForTest(t, services.SignatureHMACSHA256, "r3-secret", req)
+		assert.NoError(t, signer.VerifyRequest(req))
+	})
+
+	t.Run("OptIn", func(t *testing.T) {
+		req := base
+		req.ResellerID = "R4"
+		assert.NoError(t, signer.VerifyRequest(req))
+	})
+
+	t.Run("ResellerSwap", func(t *testing.T) {
+		// Dropping sign and claiming a reseller_id that never opted in must not bypass signing
+		for _, resellerID := range []string{"unknown", ""} {
+			req := base
+			req.ResellerID = resellerID
+			assert.Equal(t, services.SignatureErrUnknownReseller, codeOf(signer.VerifyRequest(req)))
+		}
+
+		// A reseller bound to an API key cannot be claimed by another client
+		req := base
+		req.ResellerID = "R3"
+		req.ClientID = "otomax-other"
+		req.Nonce = "n-swap"
+		req = signRequestForTest(t, services.SignatureHMACSHA256, "r3-secret", req)
+		assert.Equal(t, services.SignatureErrUnknownReseller, codeOf(signer.VerifyRequest(req)))
+	})
+}
+
+func TestOtomaxRequestSigningWithoutOptIn(t *testing.T) {
+	signer, err := services.NewOtomaxSigner(config.OtomaxConfig{
+		SecretKey: "gateway-secret",
+		Resellers: []config.OtomaxResellerConfig{{ID: "R1", Secret: "r1-secret"}},
+	})
+	require.NoError(t, err)
+
+	// Without any signing reseller unknown reseller_ids keep working
+	for _, resellerID := range []string{"R1", "unknown", ""} {
+		assert.NoError(t, signer.VerifyRequest(models.OtomaxRequestSignature{ResellerID: resellerID, RefID: "TRX1"}))
+	}
+}
+
+func TestOtomaxHandlerRequestSigning(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, _ := newFakeTopupServer(t, map[string]string{"tsel10": digiflazz.RCSuccess})
+	service, _ := newOtomaxServiceForTest(t, server.URL)
+	signer, err := services.NewOtomaxSigner(config.OtomaxConfig{Resellers: []config.OtomaxResellerConfig{
+		{ID: "R1", Secret: "r1-secret", RequestSigning: services.SignatureHMACSHA256},
+		{ID: "R9"},
+	}})
+	require.NoError(t, err)
+	service.SetSigner(signer)
+
+	handler := handlers.NewOtomaxHandler(service, nil, logrus.New())
+	router := gin.New()
+	router.GET("/otomax/transaction", handler.ProcessTransaction)
+
+	serve := func(query url.Values) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/otomax/transaction?"+query.Encode(), nil))
+		return rec
+	}
+
+	query := url.Values{
+		"ref_id":      {"TRX200"},
+		"customer_no": {"081234567890"},
+		"buyer_sku":   {"tsel10"},
+		"amount":      {"10000"},
+		"type":        {"prabayar"},
+		"reseller_id": {"R1"},
+		"timestamp":   {strconv.FormatInt(time.Now().Unix(), 10)},
+		"nonce":       {"abc123"},
+	}
+
+	rec := serve(query)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), services.SignatureErrMissing)
+
+	signed := signRequestForTest(t, services.SignatureHMACSHA256, "r1-secret", models.OtomaxRequestSignature{
+		RefID:      query.Get("ref_id"),
+		CustomerNo: query.Get("customer_no"),
+		BuyerSKU:   query.Get("buyer_sku"),
+		Timestamp:  query.Get("timestamp"),
+		Nonce:      query.Get("nonce"),
+	})
+	query.Set("sign", signed.Sign)
+	rec = serve(query)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = serve(query)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), services.SignatureErrReplayed)
+
+	// Resellers that did not opt in are unaffected
+	unsigned := url.Values{
+		"ref_id":      {"TRX201"},
+		"customer_no": {"081234567890"},
+		"buyer_sku":   {"tsel10"},
+		"amount":      {"10000"},
+		"type":        {"prabayar"},
+		"reseller_id": {"R9"},
+	}
+	assert.Equal(t, http.StatusOK, serve(unsigned).Code)
+
+	// An unknown reseller_id cannot be used to skip signing
+	unsigned.Set("ref_id", "TRX202")
+	unsigned.Set("reseller_id", "R404")
+	rec = serve(unsigned)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), services.SignatureErrUnknownReseller)
+}
+
+func TestOtomaxResponseSigning(t *testing.T) {
+	signer, err := services.NewOtomaxSigner(config.OtomaxConfig{
+		SecretKey: "gateway-secret",
+		Resellers: []config.OtomaxResellerConfig{
+			{ID: "R1", Secret: "r1-secret", ResponseSigning: services.SignatureHMACSHA256},
+			{ID: "R2", Client: "otomax-r2", Secret: "r2-secret"},
+		},
+	})
+	require.NoError(t, err)
+
+	resp := models.OtomaxResponseSignature{
+		RefID:      "TRX1",
+		CustomerNo: "081234567890",
+		BuyerSKU:   "tsel10",
+		Status:     "success",
+		RC:         "00",
+		SN:         "SN123",
+		Timestamp:  "2024-01-15T10:30:00+07:00",
+	}
+
+	t.Run("PerResellerAlgorithmAndSecret", func(t *testing.T) {
+		sign := signer.SignResponse("R1", "", resp)
+		assert.True(t, services.VerifyResponseSignature(services.SignatureHMACSHA256, "r1-secret", sign, resp))
+		assert.False(t, services.VerifyResponseSignature(services.SignatureHMACSHA256, "gateway-secret", sign, resp))
+
+		// Every signed field is covered by HMAC-SHA256
+		tampered := resp
+		tampered.SN = "SN999"
+		assert.False(t, services.VerifyResponseSignature(services.SignatureHMACSHA256, "r1-secret", sign, tampered))
+
+		// The default is the legacy MD5 over ref_id and status, with the reseller's secret
+		sign = signer.SignResponse("", "otomax-r2", resp)
+		assert.True(t, services.VerifyResponseSignature(services.SignatureMD5, "r2-secret", sign, resp))
+		sign = signer.SignResponse("unknown", "", resp)
+		assert.True(t, services.VerifyResponseSignature(services.SignatureMD5, "gateway-secret", sign, resp))
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		sign, err := services.ResponseSignature(services.SignatureMD5, "gateway-secret", models.OtomaxResponseSignature{RefID: "TRX1", Status: "success"})
+		require.NoError(t, err)
+		assert.True(t, signer.VerifyCallback(models.OtomaxCallback{RefID: "TRX1", Status: "success", Sign: sign}))
+		assert.False(t, signer.VerifyCallback(models.OtomaxCallback{RefID: "TRX1", Status: "failed", Sign: sign}))
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		check := signer.CheckResponse("R1", "", signer.SignResponse("R1", "", resp), resp)
+		assert.True(t, check.Valid)
+		assert.Equal(t, services.SignatureHMACSHA256, check.Algorithm)
+		assert.Equal(t, "TRX1|081234567890|tsel10|success|00|SN123|2024-01-15T10:30:00+07:00", check.Canonical)
+
+		check = signer.CheckResponse("R2", "", "bogus", resp)
+		assert.False(t, check.Valid)
+		assert.Equal(t, "TRX1success{secret}", check.Canonical)
+
+		req := models.OtomaxRequestSignature{ResellerID: "R1", RefID: "TRX1", CustomerNo: "081234567890", BuyerSKU: "tsel10", Timestamp: "1700000000", Nonce: "n"}
+		req = signRequestForTest(t, services.SignatureHMACSHA256, "r1-secret", req)
+		check = signer.CheckRequest(req)
+		assert.True(t, check.Valid, "checks ignore the timestamp and nonce cache")
+		assert.True(t, signer.CheckRequest(req).Valid)
+	})
+
+	_, err = services.NewOtomaxSigner(config.OtomaxConfig{ResponseSigning: "sha1"})
+	assert.Error(t, err)
+}
+
+func TestOtomaxSignedResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, _ := newFakeTopupServer(t, map[string]string{"tsel10": digiflazz.RCSuccess})
+	service, _ := newOtomaxServiceForTest(t, server.URL)
+	signer, err := services.NewOtomaxSigner(config.OtomaxConfig{
+		SecretKey: "secret",
+		Resellers: []config.OtomaxResellerConfig{
+			{ID: "R1", Secret: "r1-secret", ResponseSigning: services.SignatureHMACSHA256},
+		},
+	})
+	require.NoError(t, err)
+	service.SetSigner(signer)
+
+	resp, err := service.ProcessTransaction(context.Background(), models.OtomaxTransactionRequest{
+		RefID:      "TRX300",
+		CustomerNo: "081234567890",
+		BuyerSKU:   "tsel10",
+		Amount:     "10000",
+		Type:       "prabayar",
+		ResellerID: "R1",
+	})
+	require.NoError(t, err)
+	fields := models.OtomaxResponseSignature{
+		RefID:      resp.RefID,
+		CustomerNo: resp.CustomerNo,
+		BuyerSKU:   resp.BuyerSKU,
+		Status:     resp.Status,
+		RC:         resp.RC,
+		SN:         resp.SN,
+		Timestamp:  resp.Timestamp,
+	}
+	assert.True(t, services.VerifyResponseSignature(services.SignatureHMACSHA256, "r1-secret", resp.Sign, fields))
+
+	// The status response is signed for the reseller of the stored transaction
+	status, err := service.CheckStatus(context.Background(), models.OtomaxStatusRequest{RefID: "TRX300"})
+	require.NoError(t, err)
+	fields.Timestamp = status.Timestamp
+	assert.True(t, services.VerifyResponseSignature(services.SignatureHMACSHA256, "r1-secret", status.Sign, fields))
+
+	// Resellers can test their verification against the check endpoint
+	handler := handlers.NewOtomaxHandler(service, nil, logrus.New())
+	router := gin.New()
+	router.GET("/otomax/signature/check", handler.CheckSignature)
+	query := url.Values{
+		"reseller_id": {"R1"},
+		"ref_id":      {status.RefID},
+		"customer_no": {status.CustomerNo},
+		"buyer_sku":   {status.BuyerSKU},
+		"status":      {status.Status},
+		"rc":          {status.RC},
+		"sn":          {status.SN},
+		"timestamp":   {status.Timestamp},
+		"sign":        {status.Sign},
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/otomax/signature/check?"+query.Encode(), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"valid":true`)
+	assert.Contains(t, rec.Body.String(), `"algorithm":"hmac-sha256"`)
+	assert.NotContains(t, rec.Body.String(), "r1-secret")
+
+	// Unknown transactions are not reported as a signed success
+	_, err = service.CheckStatus(context.Background(), models.OtomaxStatusRequest{RefID: "TRX404", ResellerID: "R1"})
+	assert.ErrorIs(t, err, repositories.ErrTransactionNotFound)
+
+	router.GET("/otomax/status", handler.CheckStatus)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/otomax/status?ref_id=TRX404&reseller_id=R1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "TRANSACTION_NOT_FOUND")
+	assert.NotContains(t, rec.Body.String(), `"sign"`)
+
+	// The standalone bill check and payment are not implemented and never signed
+	router.GET("/otomax/pascabayar/check", handler.CheckPascabayarBill)
+	router.GET("/otomax/pascabayar/pay", handler.PayPascabayarBill)
+	for _, path := range []string{"/otomax/pascabayar/check", "/otomax/pascabayar/pay"} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"?ref_id=TRX500&customer_no=12345678901&buyer_sku=pln20&amount=50000", nil))
+		assert.Equal(t, http.StatusNotImplemented, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), "NOT_IMPLEMENTED", path)
+		assert.NotContains(t, rec.Body.String(), `"sign"`, path)
+	}
+}
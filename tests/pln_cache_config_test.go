@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/internal/validation"
+	"gateway-digiflazz/pkg/cache"
+	"gateway-digiflazz/pkg/digiflazz"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Customer numbers the fake PLN server answers with a definitive and a transient failure
+const (
+	notFoundCustomerNo  = "99999999999"
+	transientCustomerNo = "88888888888"
+)
+
+// newFakePLNServer returns a Digiflazz stand-in answering PLN inquiries after delay and counting calls
+func newFakePLNServer(t *testing.T, delay time.Duration) (*httptest.Server, *int64) {
+	var calls int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(delay)
+
+		var req models.PLNInquiryRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		var resp models.PLNInquiryResponse
+		switch req.CustomerNo {
+		case notFoundCustomerNo:
+			resp.Data.RC = digiflazz.RCInvalidNumber
+			resp.Data.Status = "Gagal"
+			resp.Data.Message = "Nomor tujuan salah"
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		case transientCustomerNo:
+			resp.Data.RC = digiflazz.RCTimeout
+			resp.Data.Status = "Gagal"
+			resp.Data.Message = "Timeout"
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		resp.Data.RC = digiflazz.RCSuccess
+		resp.Data.Status = "Sukses"
+		resp.Data.CustomerNo = req.CustomerNo
+		resp.Data.Name = "BUDI SANTOSO"
+		resp.Data.SegmentPower = "R1 /000001300"
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func newDigiflazzClientForTest(baseURL string) *digiflazz.Client {
+	return digiflazz.NewClient(config.DigiflazzConfig{
+		BaseURL:       baseURL,
+		Username:      "user",
+		APIKey:        "key",
+		Timeout:       5 * time.Second,
+		RetryAttempts: 1,
+	}, logrus.New())
+}
+
+func newPLNInquiryServiceForTest(t *testing.T, baseURL string) (*services.PLNInquiryService, *cache.SQLiteCache) {
+	client := newDigiflazzClientForTest(baseURL)
+
+	sqliteCache, err := cache.NewSQLiteCache(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteCache.Close() })
+
+	return services.NewPLNInquiryService(client, logrus.New(), sqliteCache), sqliteCache
+}
+
+func TestPLNCacheConfig(t *testing.T) {
+	customerNo := "12345678901"
+
+	t.Run("DisableCache", func(t *testing.T) {
+		server, calls := newFakePLNServer(t, 0)
+		service, _ := newPLNInquiryServiceForTest(t, server.URL)
+
+		config := service.GetCacheConfig()
+		config.CacheEnabled = false
+		require.NoError(t, service.SetCacheConfig(config))
+
+		for i := 0; i < 2; i++ {
+			_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: customerNo}, "REF1")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int64(2), atomic.LoadInt64(calls))
+	})
+
+	t.Run("TTLAndPrefixApplied", func(t *testing.T) {
+		server, calls := newFakePLNServer(t, 0)
+		service, sqliteCache := newPLNInquiryServiceForTest(t, server.URL)
+
+		require.NoError(t, service.SetCacheConfig(models.PLNInquiryConfig{
+			CacheEnabled:   true,
+			CacheTTL:       2 * time.Hour,
+			CacheKeyPrefix: "pln:",
+		}))
+
+		for i := 0; i < 2; i++ {
+			_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: customerNo}, "REF1")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int64(1), atomic.LoadInt64(calls))
+
+		raw, err := sqliteCache.Get(context.Background(), "pln:"+customerNo)
+		require.NoError(t, err)
+		var entry models.PLNInquiryCache
+		require.NoError(t, json.Unmarshal([]byte(raw), &entry))
+		assert.Equal(t, 2*time.Hour, entry.ExpiresAt.Sub(entry.CachedAt))
+
+		assert.Equal(t, 2*time.Hour, service.GetStats().Config.CacheTTL)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		service, _ := newPLNInquiryServiceForTest(t, "http://127.0.0.1:0")
+
+		err := service.SetCacheConfig(models.PLNInquiryConfig{CacheTTL: -time.Minute, CacheKeyPrefix: " "})
+		fieldErrors, ok := validation.AsErrors(err)
+		require.True(t, ok)
+		assert.Contains(t, fieldErrors, "cache_ttl")
+		assert.Contains(t, fieldErrors, "cache_key_prefix")
+		assert.Equal(t, "pln_inquiry:", service.GetCacheConfig().CacheKeyPrefix)
+	})
+
+	t.Run("PersistedAcrossRestarts", func(t *testing.T) {
+		store := repositories.NewFilePLNCacheConfigRepository(filepath.Join(t.TempDir(), "pln_cache_config.json"))
+
+		first, _ := newPLNInquiryServiceForTest(t, "http://127.0.0.1:0")
+		first.SetConfigRepository(store)
+		require.NoError(t, first.SetCacheConfig(models.PLNInquiryConfig{
+			CacheEnabled:   false,
+			CacheTTL:       30 * time.Minute,
+			CacheKeyPrefix: "pln:",
+		}))
+
+		second, _ := newPLNInquiryServiceForTest(t, "http://127.0.0.1:0")
+		second.SetConfigRepository(store)
+		require.NoError(t, second.LoadCacheConfig())
+		assert.Equal(t, first.GetCacheConfig(), second.GetCacheConfig())
+	})
+
+	t.Run("PartialJSONUpdate", func(t *testing.T) {
+		config := models.PLNInquiryConfig{CacheEnabled: true, CacheKeyPrefix: "pln_inquiry:"}
+		require.NoError(t, json.Unmarshal([]byte(`{"cache_ttl":"24h"}`), &config))
+		assert.Equal(t, 24*time.Hour, config.CacheTTL)
+		assert.True(t, config.CacheEnabled)
+		assert.Equal(t, "pln_inquiry:", config.CacheKeyPrefix)
+
+		require.NoError(t, json.Unmarshal([]byte(`{"cache_ttl":"90s"}`), &config))
+		assert.Equal(t, 90*time.Second, config.CacheTTL)
+
+		// Bare numbers are ambiguous (seconds or nanoseconds) and rejected
+		err := json.Unmarshal([]byte(`{"cache_ttl":90}`), &config)
+		assert.ErrorContains(t, err, "cache_ttl must be a duration string")
+		assert.Equal(t, 90*time.Second, config.CacheTTL)
+
+		data, err := json.Marshal(config)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"cache_ttl":"1m30s"`)
+	})
+}
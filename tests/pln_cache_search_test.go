@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/internal/validation"
+	"gateway-digiflazz/pkg/cache"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPLNCacheSearch(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newPLNInquiryServiceForTest(t, "http://127.0.0.1:1")
+	now := time.Now()
+
+	entries := []string{
+		`{"customer_no":"12345678901","meter_no":"56000000001","name":"BUDI SANTOSO","segment_power":"R1 /000001300","rc":"00","cached_at":"` + now.Format(time.RFC3339) + `"}`,
+		`{"customer_no":"12345678902","meter_no":"56000000002","name":"SITI AMINAH","segment_power":"R1 /000000900","rc":"00","cached_at":"` + now.Format(time.RFC3339) + `"}`,
+		`{"customer_no":"52345678903","meter_no":"14000000003","name":"Budiman","segment_power":"B2/000013200","rc":"00","cached_at":"` + now.Format(time.RFC3339) + `","expires_at":"` + now.Add(time.Hour).Format(time.RFC3339) + `"}`,
+	}
+	_, err := service.ImportCache(ctx, strings.NewReader(strings.Join(entries, "\n")), services.CacheFormatJSONL, services.ImportPolicyOverwrite)
+	require.NoError(t, err)
+
+	search := func(query models.PLNCacheSearchQuery) []string {
+		result, err := service.SearchCache(ctx, query)
+		require.NoError(t, err)
+		var customerNos []string
+		for _, entry := range result.Entries {
+			customerNos = append(customerNos, entry.CustomerNo)
+		}
+		return customerNos
+	}
+
+	assert.Equal(t, []string{"12345678901", "12345678902", "52345678903"}, search(models.PLNCacheSearchQuery{}))
+	assert.Equal(t, []string{"12345678901", "12345678902"}, search(models.PLNCacheSearchQuery{CustomerNo: "1234"}))
+	assert.Equal(t, []string{"52345678903"}, search(models.PLNCacheSearchQuery{MeterNo: "14"}))
+	assert.Equal(t, []string{"12345678901", "52345678903"}, search(models.PLNCacheSearchQuery{Name: "budi"}))
+	assert.Equal(t, []string{"12345678901", "12345678902"}, search(models.PLNCacheSearchQuery{SegmentPower: "r1"}))
+	assert.Equal(t, []string{"12345678901"}, search(models.PLNCacheSearchQuery{SegmentPower: "1300"}))
+	assert.Equal(t, []string{"52345678903"}, search(models.PLNCacheSearchQuery{SegmentPower: "B2/13200VA"}))
+	assert.Empty(t, search(models.PLNCacheSearchQuery{SegmentPower: "R1/13200"}))
+
+	result, err := service.SearchCache(ctx, models.PLNCacheSearchQuery{MeterNo: "14"})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 1)
+	require.NotNil(t, result.Entries[0].ExpiresAt)
+	assert.WithinDuration(t, now.Add(time.Hour), *result.Entries[0].ExpiresAt, time.Second)
+	assert.WithinDuration(t, now, result.Entries[0].CachedAt, time.Second)
+
+	result, err = service.SearchCache(ctx, models.PLNCacheSearchQuery{CustomerNo: "12345678901"})
+	require.NoError(t, err)
+	assert.Nil(t, result.Entries[0].ExpiresAt)
+
+	_, err = service.SearchCache(ctx, models.PLNCacheSearchQuery{PerPage: services.MaxCacheSearchPerPage + 1, Page: -1})
+	fieldErrors, ok := validation.AsErrors(err)
+	require.True(t, ok)
+	assert.Contains(t, fieldErrors, "page")
+	assert.Contains(t, fieldErrors, "per_page")
+}
+
+func TestPLNCacheSearchPagination(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newPLNInquiryServiceForTest(t, "http://127.0.0.1:1")
+
+	var lines []string
+	for i := 0; i < 25; i++ {
+		lines = append(lines, `{"customer_no":"`+strconv.Itoa(12345678900+i)+`","rc":"00","cached_at":"`+time.Now().Format(time.RFC3339)+`"}`)
+	}
+	_, err := service.ImportCache(ctx, strings.NewReader(strings.Join(lines, "\n")), services.CacheFormatJSONL, "")
+	require.NoError(t, err)
+
+	result, err := service.SearchCache(ctx, models.PLNCacheSearchQuery{Page: 3, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 25, result.Total)
+	assert.Equal(t, 3, result.TotalPages)
+	require.Len(t, result.Entries, 5)
+	assert.Equal(t, "12345678920", result.Entries[0].CustomerNo)
+
+	result, err = service.SearchCache(ctx, models.PLNCacheSearchQuery{Page: 4, PerPage: 10})
+	require.NoError(t, err)
+	assert.Empty(t, result.Entries)
+}
+
+// prefixRecordingCache records the prefixes the cache is scanned with
+type prefixRecordingCache struct {
+	*cache.MemoryCache
+	prefixes []string
+}
+
+// Scan records prefix and scans the memory cache
+func (p *prefixRecordingCache) Scan(ctx context.Context, prefix string, fn func(cache.Entry) error) error {
+	p.prefixes = append(p.prefixes, prefix)
+	return p.MemoryCache.Scan(ctx, prefix, fn)
+}
+
+func TestPLNCacheSearchScan(t *testing.T) {
+	ctx := context.Background()
+	backend := &prefixRecordingCache{MemoryCache: cache.NewMemoryCache()}
+	service := services.NewPLNInquiryService(newDigiflazzClientForTest("http://127.0.0.1:1"), logrus.New(), backend)
+
+	cachedAt := time.Now().Format(time.RFC3339)
+	for i := 0; i <= services.MaxCacheSearchScan; i++ {
+		customerNo := strconv.Itoa(12345600000 + i)
+		value := `{"customer_no":"` + customerNo + `","rc":"00","cached_at":"` + cachedAt + `"}`
+		require.NoError(t, backend.Set(ctx, "pln_inquiry:"+customerNo, value, 0))
+	}
+
+	// A customer_no search only reads the entries under its key prefix
+	result, err := service.SearchCache(ctx, models.PLNCacheSearchQuery{CustomerNo: " 1234560001"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pln_inquiry:1234560001"}, backend.prefixes)
+	assert.Equal(t, 10, result.Total)
+	assert.False(t, result.Truncated)
+
+	// Other searches stop at the scan limit and say so
+	result, err = service.SearchCache(ctx, models.PLNCacheSearchQuery{})
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, services.MaxCacheSearchScan, result.Total)
+}
+
+func TestSQLiteCacheScanPrefix(t *testing.T) {
+	ctx := context.Background()
+	_, sqliteCache := newPLNInquiryServiceForTest(t, "http://127.0.0.1:1")
+	for _, key := range []string{"a", "ab", "ab\xff", "ab\xff\xff", "ac", "b"} {
+		require.NoError(t, sqliteCache.Set(ctx, key, "v", 0))
+	}
+
+	scan := func(prefix string) []string {
+		var keys []string
+		require.NoError(t, sqliteCache.Scan(ctx, prefix, func(entry cache.Entry) error {
+			keys = append(keys, entry.Key)
+			return nil
+		}))
+		return keys
+	}
+	assert.Equal(t, []string{"ab", "ab\xff", "ab\xff\xff"}, scan("ab"))
+	assert.Equal(t, []string{"ab\xff", "ab\xff\xff"}, scan("ab\xff"))
+	assert.Len(t, scan(""), 6)
+}
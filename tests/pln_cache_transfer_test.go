@@ -0,0 +1,266 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/handlers"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/internal/validation"
+	"gateway-digiflazz/pkg/cache"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPLNCacheWarmUp(t *testing.T) {
+	ctx := context.Background()
+	server, calls := newFakePLNServer(t, 10*time.Millisecond)
+	service, _ := newPLNInquiryServiceForTest(t, server.URL)
+
+	customerNos := []string{"12345678901", "12345678902", " 12345678901 ", notFoundCustomerNo, transientCustomerNo, ""}
+	result, err := service.WarmCache(ctx, customerNos, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Requested)
+	assert.Equal(t, 2, result.Warmed)
+	assert.Equal(t, 1, result.NotFound)
+	assert.Equal(t, 1, result.Failed)
+	assert.Contains(t, result.Errors, transientCustomerNo)
+	assert.Equal(t, int64(4), atomic.LoadInt64(calls))
+
+	// Cached customers are not looked up again
+	result, err = service.WarmCache(ctx, []string{"12345678901", "12345678902"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.AlreadyCached)
+	assert.Equal(t, int64(4), atomic.LoadInt64(calls))
+
+	_, err = service.WarmCache(ctx, nil, 0)
+	_, ok := validation.AsErrors(err)
+	assert.True(t, ok)
+	_, err = service.WarmCache(ctx, []string{"12345678901"}, services.MaxWarmConcurrency+1)
+	_, ok = validation.AsErrors(err)
+	assert.True(t, ok)
+
+	// A disabled cache would store nothing, so warm-ups are refused without reaching Digiflazz
+	config := service.GetCacheConfig()
+	config.CacheEnabled = false
+	require.NoError(t, service.SetCacheConfig(config))
+	_, err = service.WarmCache(ctx, []string{"12345678903"}, 0)
+	fieldErrors, ok := validation.AsErrors(err)
+	require.True(t, ok)
+	assert.Contains(t, fieldErrors, "cache_enabled")
+	_, err = service.StartWarmCache(ctx, []string{"12345678903"}, 0)
+	_, ok = validation.AsErrors(err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), atomic.LoadInt64(calls))
+}
+
+func TestPLNCacheExportImport(t *testing.T) {
+	ctx := context.Background()
+	server, _ := newFakePLNServer(t, 0)
+
+	source, _ := newPLNInquiryServiceForTest(t, server.URL)
+	_, err := source.WarmCache(ctx, []string{"12345678901", "12345678902", notFoundCustomerNo}, 2)
+	require.NoError(t, err)
+
+	for _, format := range []string{services.CacheFormatJSONL, services.CacheFormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			count, err := source.ExportCache(ctx, &buf, format)
+			require.NoError(t, err)
+			// Negative entries are not exported
+			assert.Equal(t, 2, count)
+			assert.NotContains(t, buf.String(), notFoundCustomerNo)
+
+			target, _ := newPLNInquiryServiceForTest(t, "http://127.0.0.1:1")
+			result, err := target.ImportCache(ctx, bytes.NewReader(buf.Bytes()), format, services.ImportPolicySkip)
+			require.NoError(t, err)
+			assert.Equal(t, &models.PLNCacheImportResult{Total: 2, Imported: 2}, result)
+
+			// Served from the imported cache without reaching Digiflazz
+			resp, err := target.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: "12345678901"}, "REF1")
+			require.NoError(t, err)
+			assert.Equal(t, "BUDI SANTOSO", resp.Data.Name)
+			require.NotNil(t, resp.CachedAt)
+
+			result, err = target.ImportCache(ctx, bytes.NewReader(buf.Bytes()), format, services.ImportPolicySkip)
+			require.NoError(t, err)
+			assert.Equal(t, 2, result.Skipped)
+		})
+	}
+}
+
+func TestPLNCacheImportPolicies(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newPLNInquiryServiceForTest(t, "http://127.0.0.1:1")
+	now := time.Now()
+
+	line := func(name string, cachedAt time.Time) string {
+		return `{"customer_no":"12345678901","name":"` + name + `","rc":"00","cached_at":"` + cachedAt.Format(time.RFC3339Nano) + `"}` + "\n"
+	}
+	nameOf := func() string {
+		resp, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: "12345678901"}, "REF1")
+		require.NoError(t, err)
+		return resp.Data.Name
+	}
+
+	_, err := service.ImportCache(ctx, strings.NewReader(line("FIRST", now.Add(-time.Hour))), services.CacheFormatJSONL, "")
+	require.NoError(t, err)
+
+	_, err = service.ImportCache(ctx, strings.NewReader(line("SKIPPED", now)), services.CacheFormatJSONL, services.ImportPolicySkip)
+	require.NoError(t, err)
+	assert.Equal(t, "FIRST", nameOf())
+
+	_, err = service.ImportCache(ctx, strings.NewReader(line("OLDER", now.Add(-2*time.Hour))), services.CacheFormatJSONL, services.ImportPolicyNewer)
+	require.NoError(t, err)
+	assert.Equal(t, "FIRST", nameOf())
+
+	_, err = service.ImportCache(ctx, strings.NewReader(line("NEWER", now)), services.CacheFormatJSONL, services.ImportPolicyNewer)
+	require.NoError(t, err)
+	assert.Equal(t, "NEWER", nameOf())
+
+	_, err = service.ImportCache(ctx, strings.NewReader(line("OVERWRITTEN", now.Add(-3*time.Hour))), services.CacheFormatJSONL, services.ImportPolicyOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, "OVERWRITTEN", nameOf())
+
+	// Entries past the current hard TTL and malformed lines are counted, not stored
+	config := service.GetCacheConfig()
+	config.CacheTTL = time.Hour
+	require.NoError(t, service.SetCacheConfig(config))
+	input := line("EXPIRED", now.Add(-2*time.Hour)) + "not json\n" + `{"customer_no":"1","cached_at":"` + now.Format(time.RFC3339) + `"}` + "\n"
+	result, err := service.ImportCache(ctx, strings.NewReader(input), services.CacheFormatJSONL, services.ImportPolicyOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Expired)
+	assert.Equal(t, 2, result.Failed)
+	assert.Len(t, result.Errors, 2)
+
+	// Only successful inquiries are imported; failed ones would be served as cache hits
+	failedRows := `{"customer_no":"12345678902","name":"GAGAL","rc":"54","status":"Gagal","cached_at":"` + now.Format(time.RFC3339) + `"}` + "\n" +
+		`{"customer_no":"12345678903","name":"NO RC","cached_at":"` + now.Format(time.RFC3339) + `"}` + "\n"
+	result, err = service.ImportCache(ctx, strings.NewReader(failedRows), services.CacheFormatJSONL, services.ImportPolicyOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Failed)
+	assert.Zero(t, result.Imported)
+	require.Len(t, result.Errors, 2)
+	assert.Contains(t, result.Errors[0], "line 1: rc must be")
+	search, err := service.SearchCache(ctx, models.PLNCacheSearchQuery{CustomerNo: "12345678902"})
+	require.NoError(t, err)
+	assert.Zero(t, search.Total)
+
+	_, err = service.ImportCache(ctx, strings.NewReader(""), "xml", "replace")
+	fieldErrors, ok := validation.AsErrors(err)
+	require.True(t, ok)
+	assert.Contains(t, fieldErrors, "format")
+	assert.Contains(t, fieldErrors, "policy")
+}
+
+// failingScanCache fails a scan after the first entry, as a backend dropping mid-export would
+type failingScanCache struct {
+	*cache.MemoryCache
+}
+
+// Scan passes on the first entry and then fails
+func (f failingScanCache) Scan(ctx context.Context, prefix string, fn func(cache.Entry) error) error {
+	first := true
+	return f.MemoryCache.Scan(ctx, prefix, func(entry cache.Entry) error {
+		if !first {
+			return errors.New("backend unavailable")
+		}
+		first = false
+		return fn(entry)
+	})
+}
+
+func TestPLNCacheTransferHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	server, _ := newFakePLNServer(t, 0)
+
+	newRouter := func(service *services.PLNInquiryService) *gin.Engine {
+		handler := handlers.NewPLNInquiryHandler(service, logrus.New())
+		router := gin.New()
+		router.GET("/cache/export", handler.ExportCache)
+		router.POST("/cache/warm", handler.WarmCache)
+		router.GET("/cache/warm/:job_id", handler.GetWarmJob)
+		return router
+	}
+	serve := func(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("WarmRunsInBackground", func(t *testing.T) {
+		service, _ := newPLNInquiryServiceForTest(t, server.URL)
+		router := newRouter(service)
+
+		rec := serve(router, http.MethodPost, "/cache/warm", `{"customer_nos":["12345678901","12345678902"]}`)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		var started struct {
+			Data models.PLNCacheWarmJob `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &started))
+		require.NotEmpty(t, started.Data.JobID)
+		assert.Equal(t, 2, started.Data.Requested)
+
+		var job models.PLNCacheWarmJob
+		require.Eventually(t, func() bool {
+			rec := serve(router, http.MethodGet, "/cache/warm/"+started.Data.JobID, "")
+			var polled struct {
+				Data models.PLNCacheWarmJob `json:"data"`
+			}
+			if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &polled) != nil {
+				return false
+			}
+			job = polled.Data
+			return job.Status == services.WarmJobFinished
+		}, 5*time.Second, 10*time.Millisecond)
+		require.NotNil(t, job.Result)
+		assert.Equal(t, 2, job.Result.Warmed)
+		assert.NotNil(t, job.FinishedAt)
+
+		// Invalid warm-ups are still rejected up front
+		rec = serve(router, http.MethodPost, "/cache/warm", `{"customer_nos":[" "]}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = serve(router, http.MethodGet, "/cache/warm/unknown", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("ExportIsSentWhole", func(t *testing.T) {
+		service, _ := newPLNInquiryServiceForTest(t, server.URL)
+		_, err := service.WarmCache(ctx, []string{"12345678901", "12345678902"}, 2)
+		require.NoError(t, err)
+
+		rec := serve(newRouter(service), http.MethodGet, "/cache/export?format=csv", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+		assert.Equal(t, strconv.Itoa(rec.Body.Len()), rec.Header().Get("Content-Length"))
+		assert.Equal(t, 3, strings.Count(rec.Body.String(), "\n"))
+	})
+
+	t.Run("ExportFailureIsReported", func(t *testing.T) {
+		backend := failingScanCache{cache.NewMemoryCache()}
+		service := services.NewPLNInquiryService(newDigiflazzClientForTest(server.URL), logrus.New(), backend)
+		_, err := service.WarmCache(ctx, []string{"12345678901", "12345678902"}, 2)
+		require.NoError(t, err)
+
+		rec := serve(newRouter(service), http.MethodGet, "/cache/export", "")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "CACHE_EXPORT_FAILED")
+		assert.NotContains(t, rec.Body.String(), "BUDI SANTOSO")
+	})
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/pkg/cache"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPLNInquiryStatsConcurrent(t *testing.T) {
+	server, _ := newFakePLNServer(t, 0)
+	service, _ := newPLNInquiryServiceForTest(t, server.URL)
+
+	// Warm the cache so the concurrent requests are hits
+	_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: "12345678901"}, "WARM")
+	require.NoError(t, err)
+
+	const workers = 40
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: "12345678901"}, "REF")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	stats := service.GetStats()
+	assert.Equal(t, int64(workers+1), stats.TotalRequests)
+	assert.Equal(t, int64(workers), stats.CacheHits)
+	assert.Equal(t, int64(1), stats.CacheMisses)
+	assert.Equal(t, int64(1), stats.APIRequests)
+	assert.InDelta(t, float64(workers)/float64(workers+1), stats.HitRatio, 0.0001)
+
+	assert.Equal(t, int64(workers), stats.Latency.Cache.Count)
+	assert.Equal(t, int64(1), stats.Latency.API.Count)
+	assert.LessOrEqual(t, stats.Latency.Cache.P50Ms, stats.Latency.Cache.P99Ms)
+	assert.Greater(t, stats.Latency.API.P99Ms, 0.0)
+
+	for _, window := range []string{"1m", "15m", "1h"} {
+		rates, ok := stats.Rates[window]
+		require.True(t, ok, window)
+		assert.Equal(t, int64(workers+1), rates.Requests, window)
+		assert.Equal(t, int64(workers), rates.CacheHits, window)
+	}
+	assert.InDelta(t, float64(workers+1), stats.Rates["1m"].RequestsPerMinute, 0.0001)
+}
+
+func TestPLNInquiryCoalescing(t *testing.T) {
+	server, calls := newFakePLNServer(t, 300*time.Millisecond)
+	service, _ := newPLNInquiryServiceForTest(t, server.URL)
+
+	const workers = 10
+	refIDs := make([]string, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: "12345678901"}, fmt.Sprintf("REF%d", i))
+			if assert.NoError(t, err) {
+				refIDs[i] = resp.Data.RefID
+				assert.Equal(t, "BUDI SANTOSO", resp.Data.Name)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(calls))
+	for i, refID := range refIDs {
+		assert.Equal(t, fmt.Sprintf("REF%d", i), refID)
+	}
+
+	stats := service.GetStats()
+	assert.Equal(t, int64(1), stats.APIRequests)
+	assert.Equal(t, int64(workers-1), stats.CoalescedRequests)
+	assert.Equal(t, int64(workers-1), stats.Rates["1m"].CoalescedRequests)
+	assert.Equal(t, int64(0), stats.InflightRequests)
+}
+
+// panickingCache panics when an inquiry result is stored
+type panickingCache struct {
+	*cache.MemoryCache
+}
+
+// Set panics instead of storing value
+func (panickingCache) Set(context.Context, string, string, time.Duration) error {
+	panic("cache write failed")
+}
+
+func TestPLNInquiryCoalescingLeaderPanic(t *testing.T) {
+	server, calls := newFakePLNServer(t, 200*time.Millisecond)
+	service := services.NewPLNInquiryService(newDigiflazzClientForTest(server.URL), logrus.New(), panickingCache{cache.NewMemoryCache()})
+	req := models.PLNInquiryRequest{CustomerNo: "12345678901"}
+
+	leaderPanicked := make(chan interface{}, 1)
+	go func() {
+		defer func() { leaderPanicked <- recover() }()
+		_, _ = service.InquiryPLN(context.Background(), req, "REF1")
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt64(calls) == 1 }, 5*time.Second, time.Millisecond)
+
+	// The follower joined the call that panicked: it gets an error, not a nil response
+	_, err := service.InquiryPLN(context.Background(), req, "REF2")
+	assert.ErrorContains(t, err, "panicked")
+	assert.Equal(t, "cache write failed", <-leaderPanicked)
+	assert.Equal(t, int64(1), atomic.LoadInt64(calls))
+}
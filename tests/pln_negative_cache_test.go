@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/pkg/digiflazz"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPLNNegativeCache(t *testing.T) {
+	t.Run("NotFoundIsCached", func(t *testing.T) {
+		server, calls := newFakePLNServer(t, 0)
+		service, _ := newPLNInquiryServiceForTest(t, server.URL)
+
+		for i := 0; i < 3; i++ {
+			_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: notFoundCustomerNo}, "REF")
+			require.Error(t, err)
+			assert.Equal(t, digiflazz.RCInvalidNumber, digiflazz.RCFromError(err))
+		}
+		assert.Equal(t, int64(1), atomic.LoadInt64(calls))
+
+		stats := service.GetStats()
+		assert.Equal(t, int64(1), stats.NegativeCacheStores)
+		assert.Equal(t, int64(2), stats.NegativeCacheHits)
+		assert.Equal(t, int64(2), stats.Rates["1m"].NegativeCacheHits)
+
+		// Purging the customer's cache removes the negative entry too
+		require.NoError(t, service.ClearCache(notFoundCustomerNo))
+		_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: notFoundCustomerNo}, "REF")
+		require.Error(t, err)
+		assert.Equal(t, int64(2), atomic.LoadInt64(calls))
+
+		require.NoError(t, service.ClearAllCache())
+		_, err = service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: notFoundCustomerNo}, "REF")
+		require.Error(t, err)
+		assert.Equal(t, int64(3), atomic.LoadInt64(calls))
+	})
+
+	t.Run("TransientFailureIsNotCached", func(t *testing.T) {
+		server, calls := newFakePLNServer(t, 0)
+		service, _ := newPLNInquiryServiceForTest(t, server.URL)
+
+		for i := 0; i < 2; i++ {
+			_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: transientCustomerNo}, "REF")
+			require.Error(t, err)
+		}
+		assert.Equal(t, int64(2), atomic.LoadInt64(calls))
+		assert.Equal(t, int64(0), service.GetStats().NegativeCacheStores)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		server, calls := newFakePLNServer(t, 0)
+		service, _ := newPLNInquiryServiceForTest(t, server.URL)
+
+		config := service.GetCacheConfig()
+		config.NegativeCacheEnabled = false
+		require.NoError(t, service.SetCacheConfig(config))
+
+		for i := 0; i < 2; i++ {
+			_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: notFoundCustomerNo}, "REF")
+			require.Error(t, err)
+		}
+		assert.Equal(t, int64(2), atomic.LoadInt64(calls))
+	})
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPLNStaleWhileRevalidate(t *testing.T) {
+	customerNo := "12345678901"
+
+	server, calls := newFakePLNServer(t, 0)
+	service, sqliteCache := newPLNInquiryServiceForTest(t, server.URL)
+
+	config := service.GetCacheConfig()
+	config.CacheSoftTTL = time.Hour
+	config.CacheTTL = 48 * time.Hour
+	require.NoError(t, service.SetCacheConfig(config))
+
+	// Seed an entry cached before the soft TTL with an outdated name
+	cachedAt := time.Now().Add(-2 * time.Hour)
+	entry, err := json.Marshal(models.PLNInquiryCache{
+		CustomerNo: customerNo,
+		Name:       "OLD NAME",
+		RC:         "00",
+		Status:     "Sukses",
+		CachedAt:   cachedAt,
+		ExpiresAt:  cachedAt.Add(48 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.NoError(t, sqliteCache.Set(context.Background(), config.CacheKeyPrefix+customerNo, string(entry), 46*time.Hour))
+
+	// The stale entry is served immediately and refreshed in the background
+	resp, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: customerNo}, "REF1")
+	require.NoError(t, err)
+	assert.Equal(t, "OLD NAME", resp.Data.Name)
+	assert.True(t, resp.Stale)
+	require.NotNil(t, resp.CachedAt)
+	assert.WithinDuration(t, cachedAt, *resp.CachedAt, time.Second)
+
+	require.Eventually(t, func() bool {
+		return service.GetStats().BackgroundRefreshes == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), atomic.LoadInt64(calls))
+
+	resp, err = service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: customerNo}, "REF2")
+	require.NoError(t, err)
+	assert.Equal(t, "BUDI SANTOSO", resp.Data.Name)
+	assert.False(t, resp.Stale)
+	assert.Equal(t, int64(1), atomic.LoadInt64(calls))
+
+	stats := service.GetStats()
+	assert.Equal(t, int64(1), stats.StaleHits)
+	assert.Equal(t, int64(0), stats.BackgroundRefreshErrors)
+}
+
+func TestPLNSoftTTLValidation(t *testing.T) {
+	service, _ := newPLNInquiryServiceForTest(t, "http://127.0.0.1:0")
+
+	config := service.GetCacheConfig()
+	config.CacheTTL = time.Hour
+	config.CacheSoftTTL = 2 * time.Hour
+	fieldErrors, ok := validation.AsErrors(service.SetCacheConfig(config))
+	require.True(t, ok)
+	assert.Contains(t, fieldErrors, "cache_soft_ttl")
+}
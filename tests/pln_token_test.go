@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/pkg/digiflazz"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePLNTokenSN(t *testing.T) {
+	t.Run("FullSN", func(t *testing.T) {
+		token, err := services.ParsePLNTokenSN("1234-5678-9012-3456-7890/BUDI SANTOSO/R1/1300VA/32,1 kWh")
+		require.NoError(t, err)
+		assert.Equal(t, "12345678901234567890", token.Token)
+		assert.Equal(t, "1234-5678-9012-3456-7890", token.TokenFormatted)
+		assert.Equal(t, "BUDI SANTOSO", token.CustomerName)
+		assert.Equal(t, "R1", token.Tariff)
+		assert.Equal(t, "1300", token.Power)
+		assert.Equal(t, 32.1, token.KWh)
+	})
+
+	t.Run("TokenOnly", func(t *testing.T) {
+		token, err := services.ParsePLNTokenSN("12345678901234567890")
+		require.NoError(t, err)
+		assert.Equal(t, "1234-5678-9012-3456-7890", token.TokenFormatted)
+		assert.Empty(t, token.CustomerName)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		_, err := services.ParsePLNTokenSN("12345/BUDI/R1/900/10")
+		assert.Error(t, err)
+	})
+}
+
+func TestParsePLNTokenSNInvalidKWh(t *testing.T) {
+	// The token the customer paid for is kept even when the kWh field is unreadable
+	token, err := services.ParsePLNTokenSN("1234-5678-9012-3456-7890/BUDI SANTOSO/R1/1300VA/n.a.")
+	require.NoError(t, err)
+	assert.Equal(t, "12345678901234567890", token.Token)
+	assert.Equal(t, "BUDI SANTOSO", token.CustomerName)
+	assert.Zero(t, token.KWh)
+}
+
+// newPendingPLNServer fakes Digiflazz answering token purchases with Pending and
+// status checks with Sukses and the token SN
+func newPendingPLNServer(t *testing.T, sn string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.TopupRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		var resp models.TopupResponse
+		resp.Data.RefID = req.RefID
+		if r.URL.Path == "/cek-status" {
+			resp.Data.RC = digiflazz.RCSuccess
+			resp.Data.Status = "Sukses"
+			resp.Data.SN = sn
+		} else {
+			resp.Data.RC = digiflazz.RCPending
+			resp.Data.Status = "Pending"
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPLNTokenPurchaseFlow(t *testing.T) {
+	const sn = "1234-5678-9012-3456-7890/BUDI SANTOSO/R1/1300VA/-"
+	purchase := models.OtomaxPLNTokenRequest{
+		RefID:       "PLN100",
+		CustomerNo:  "12345678901",
+		BuyerSKU:    "pln20",
+		SkipInquiry: true,
+	}
+
+	t.Run("StatusCheckResolvesPending", func(t *testing.T) {
+		server := newPendingPLNServer(t, sn)
+		service, repository := newOtomaxServiceForTest(t, server.URL)
+
+		resp, err := service.PurchasePLNToken(context.Background(), purchase)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", resp.Status)
+		assert.Nil(t, resp.PLNToken)
+
+		status, err := service.CheckStatus(context.Background(), models.OtomaxStatusRequest{RefID: "PLN100"})
+		require.NoError(t, err)
+		assert.Equal(t, "success", status.Status)
+		require.NotNil(t, status.PLNToken)
+		assert.Equal(t, "12345678901234567890", status.PLNToken.Token)
+		assert.Zero(t, status.PLNToken.KWh)
+
+		stored, err := repository.GetByRefID("PLN100")
+		require.NoError(t, err)
+		assert.Equal(t, "success", stored.Status)
+		require.NotNil(t, stored.PLNToken)
+		assert.Equal(t, "12345678901234567890", stored.PLNToken.Token)
+	})
+
+	t.Run("CallbackResolvesPending", func(t *testing.T) {
+		server := newPendingPLNServer(t, "")
+		service, repository := newOtomaxServiceForTest(t, server.URL)
+
+		_, err := service.PurchasePLNToken(context.Background(), purchase)
+		require.NoError(t, err)
+
+		callback := models.OtomaxCallback{RefID: "PLN100", Status: "Sukses", RC: digiflazz.RCSuccess, SN: sn}
+		callback.Sign = fmt.Sprintf("%x", md5.Sum([]byte(callback.RefID+callback.Status+"secret")))
+		require.NoError(t, service.ProcessCallback(context.Background(), callback))
+
+		stored, err := repository.GetByRefID("PLN100")
+		require.NoError(t, err)
+		assert.Equal(t, "success", stored.Status)
+		assert.Equal(t, sn, stored.SN)
+		require.NotNil(t, stored.PLNToken)
+		assert.Equal(t, "1234-5678-9012-3456-7890", stored.PLNToken.TokenFormatted)
+
+		// A final status is never overwritten by a later callback
+		failed := models.OtomaxCallback{RefID: "PLN100", Status: "Gagal", RC: "40", SN: "forged"}
+		failed.Sign = fmt.Sprintf("%x", md5.Sum([]byte(failed.RefID+failed.Status+"secret")))
+		require.NoError(t, service.ProcessCallback(context.Background(), failed))
+		stored, err = repository.GetByRefID("PLN100")
+		require.NoError(t, err)
+		assert.Equal(t, "success", stored.Status)
+		assert.Equal(t, sn, stored.SN)
+
+		// Callbacks for unknown transactions are acknowledged without effect
+		callback = models.OtomaxCallback{RefID: "PLN404", Status: "Sukses"}
+		callback.Sign = fmt.Sprintf("%x", md5.Sum([]byte(callback.RefID+callback.Status+"secret")))
+		assert.NoError(t, service.ProcessCallback(context.Background(), callback))
+	})
+}
+
+func TestOtomaxCallbackRejectsPlaceholderKey(t *testing.T) {
+	server := newPendingPLNServer(t, "")
+	pool, err := digiflazz.NewPool(config.DigiflazzConfig{
+		BaseURL: server.URL, Username: "user", APIKey: "key", Timeout: 5 * time.Second, RetryAttempts: 1,
+	}, logrus.New())
+	require.NoError(t, err)
+	repository := repositories.NewMemoryOtomaxTransactionRepository()
+	service := services.NewOtomaxService(pool, repository, logrus.New(), services.DefaultOtomaxSecretKey)
+
+	_, err = service.PurchasePLNToken(context.Background(), models.OtomaxPLNTokenRequest{
+		RefID: "PLN200", CustomerNo: "12345678901", BuyerSKU: "pln20", SkipInquiry: true,
+	})
+	require.NoError(t, err)
+
+	// Anyone can sign with the published default key, so such callbacks change nothing
+	callback := models.OtomaxCallback{RefID: "PLN200", Status: "success", SN: "1111-2222-3333-4444-5555"}
+	callback.Sign = fmt.Sprintf("%x", md5.Sum([]byte(callback.RefID+callback.Status+services.DefaultOtomaxSecretKey)))
+	assert.Error(t, service.ProcessCallback(context.Background(), callback))
+
+	stored, err := repository.GetByRefID("PLN200")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", stored.Status)
+	assert.Empty(t, stored.SN)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/pkg/digiflazz"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolRoutingAndFailover(t *testing.T) {
+	// Fake Digiflazz: the primary account has no balance, the backup succeeds
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.TopupRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		var resp models.TopupResponse
+		resp.Data.RefID = req.RefID
+		if req.Username == "primary" {
+			resp.Data.RC = digiflazz.RCInsufficientBalance
+			resp.Data.Status = "Gagal"
+		} else {
+			resp.Data.RC = digiflazz.RCSuccess
+			resp.Data.Status = "Sukses"
+			resp.Data.BuyerLastSaldo = 150000
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	cfg := config.DigiflazzConfig{
+		BaseURL:       server.URL,
+		Username:      "primary",
+		APIKey:        "primary-key",
+		Timeout:       5 * time.Second,
+		RetryAttempts: 1,
+		Failover:      "backup",
+		Accounts: []config.DigiflazzAccountConfig{
+			{Name: "backup", Username: "backup", APIKey: "backup-key"},
+		},
+		Routes: []config.DigiflazzRouteConfig{
+			{Category: "Games", Account: "backup"},
+		},
+	}
+
+	pool, err := digiflazz.NewPool(cfg, logrus.New())
+	require.NoError(t, err)
+
+	t.Run("Route", func(t *testing.T) {
+		assert.Equal(t, "backup", pool.Route("R001", "games"))
+		assert.Equal(t, digiflazz.DefaultAccountName, pool.Route("R001", "Pulsa"))
+	})
+
+	t.Run("FailoverOnInsufficientBalance", func(t *testing.T) {
+		resp, account, err := pool.Topup(context.Background(), digiflazz.DefaultAccountName, models.TopupRequest{
+			RefID:      "TRX001",
+			CustomerNo: "08123456789",
+			BuyerSKU:   "tsel10",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "backup", account)
+		assert.Equal(t, digiflazz.RCSuccess, resp.Data.RC)
+
+		for _, balance := range pool.Balances() {
+			if balance.Account == "backup" {
+				assert.Equal(t, float64(150000), balance.Deposit)
+			}
+		}
+	})
+
+	t.Run("UnknownFailoverAccount", func(t *testing.T) {
+		bad := cfg
+		bad.Failover = "missing"
+		_, err := digiflazz.NewPool(bad, logrus.New())
+		assert.Error(t, err)
+	})
+}
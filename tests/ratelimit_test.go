@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/middleware"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRateLimitRouterForTest serves /inquiry and /transact behind authentication and their rate limits
+func newRateLimitRouterForTest(t *testing.T, limiter *middleware.RateLimiter, store *middleware.APIKeyStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/inquiry", middleware.APIKeyAuth(store, middleware.ScopeInquiry, logger),
+		middleware.RateLimit(limiter, middleware.ScopeInquiry), ok)
+	router.GET("/transact", middleware.APIKeyAuth(store, middleware.ScopeTransact, logger),
+		middleware.RateLimit(limiter, middleware.ScopeTransact), ok)
+	return router
+}
+
+// serveRateLimited sends a GET with an optional API key from remoteAddr
+func serveRateLimited(router *gin.Engine, path, apiKey, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set(middleware.APIKeyHeader, apiKey)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := config.RateLimitConfig{
+		Requests: 2,
+		Window:   time.Minute,
+		Groups: map[string]config.RateLimitRuleConfig{
+			middleware.ScopeTransact: {Requests: 1},
+		},
+	}
+
+	t.Run("PerClientAndGroup", func(t *testing.T) {
+		store, err := middleware.NewAPIKeyStore([]config.APIKeyConfig{
+			{Name: "otomax", KeyHash: middleware.HashAPIKey("otomax-key"), Scopes: []string{middleware.ScopeTransact, middleware.ScopeInquiry}},
+			{Name: "web", KeyHash: middleware.HashAPIKey("web-key"), Scopes: []string{middleware.ScopeInquiry}},
+		})
+		require.NoError(t, err)
+		limiter := middleware.NewRateLimiter(cfg, middleware.NewMemoryRateLimitStore(), logger)
+		router := newRateLimitRouterForTest(t, limiter, store)
+
+		rec := serveRateLimited(router, "/inquiry", "otomax-key", "10.0.0.1:1000")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, "30", rec.Header().Get("X-RateLimit-Reset"))
+
+		rec = serveRateLimited(router, "/inquiry", "otomax-key", "10.0.0.1:1000")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+		rec = serveRateLimited(router, "/inquiry", "otomax-key", "10.0.0.1:1000")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Contains(t, rec.Body.String(), "RATE_LIMITED")
+		assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+		// Another client from the same IP has its own bucket
+		rec = serveRateLimited(router, "/inquiry", "web-key", "10.0.0.1:1000")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// The transact group has its own, smaller budget
+		rec = serveRateLimited(router, "/transact", "otomax-key", "10.0.0.1:1000")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Limit"))
+		rec = serveRateLimited(router, "/transact", "otomax-key", "10.0.0.1:1000")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("AnonymousByIP", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter(cfg, middleware.NewMemoryRateLimitStore(), logger)
+		router := newRateLimitRouterForTest(t, limiter, nil)
+
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusOK, serveRateLimited(router, "/inquiry", "", "10.0.0.1:1000").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serveRateLimited(router, "/inquiry", "", "10.0.0.1:2000").Code)
+		assert.Equal(t, http.StatusOK, serveRateLimited(router, "/inquiry", "", "10.0.0.2:1000").Code)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		router := newRateLimitRouterForTest(t, nil, nil)
+		for i := 0; i < 5; i++ {
+			rec := serveRateLimited(router, "/inquiry", "", "10.0.0.1:1000")
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+		}
+	})
+}
+
+func TestRedisRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	store := middleware.NewRedisRateLimitStore(client, "gw:ratelimit:")
+	rule := middleware.RateLimitRule{Requests: 2, Window: time.Minute, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		result, err := store.Take(ctx, "inquiry:client:otomax", rule)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 1-i, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "inquiry:client:otomax", rule)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.InDelta(t, 30*time.Second, result.RetryAfter, float64(time.Second))
+
+	// Buckets are shared through Redis and expire once refilled
+	assert.True(t, mr.Exists("gw:ratelimit:inquiry:client:otomax"))
+	assert.LessOrEqual(t, mr.TTL("gw:ratelimit:inquiry:client:otomax"), time.Minute+time.Second)
+
+	result, err = store.Take(ctx, "inquiry:client:web", rule)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// A store outage lets requests through rather than failing them
+	mr.Close()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	limiter := middleware.NewRateLimiter(config.RateLimitConfig{Requests: 1}, store, logger)
+	router := newRateLimitRouterForTest(t, limiter, nil)
+	assert.Equal(t, http.StatusOK, serveRateLimited(router, "/inquiry", "", "10.0.0.1:1000").Code)
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/middleware"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/pkg/digiflazz"
+	"gateway-digiflazz/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.GET("/object", func(c *gin.Context) {
+		middleware.JSON(c, http.StatusOK, gin.H{"success": true, "context_id": requestid.FromContext(c.Request.Context())})
+	})
+	router.GET("/empty", func(c *gin.Context) { middleware.JSON(c, http.StatusOK, gin.H{}) })
+	router.GET("/model", func(c *gin.Context) {
+		middleware.JSON(c, http.StatusNotFound, &models.OtomaxError{Code: "TRANSACTION_NOT_FOUND"})
+	})
+	router.GET("/list", func(c *gin.Context) { middleware.JSON(c, http.StatusOK, []string{"a"}) })
+	router.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, "{plain}") })
+
+	serve := func(path, requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if requestID != "" {
+			req.Header.Set(requestid.Header, requestID)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/object", "")
+	id := rec.Header().Get(requestid.Header)
+	assert.Regexp(t, `^\d{14}-[0-9a-f]{16}$`, id)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, id, body["request_id"])
+	assert.Equal(t, id, body["context_id"])
+	assert.Equal(t, true, body["success"])
+
+	// Generated IDs are random, not derived from the clock alone
+	assert.NotEqual(t, id, serve("/object", "").Header().Get(requestid.Header))
+
+	// A safe client ID is kept, anything else is replaced
+	rec = serve("/object", "otomax-42")
+	assert.Equal(t, "otomax-42", rec.Header().Get(requestid.Header))
+	assert.Contains(t, rec.Body.String(), `"request_id":"otomax-42"`)
+	rec = serve("/object", `bad"id`)
+	assert.NotEqual(t, `bad"id`, rec.Header().Get(requestid.Header))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+	rec = serve("/empty", "abc")
+	assert.JSONEq(t, `{"request_id":"abc"}`, rec.Body.String())
+	rec = serve("/model", "abc")
+	assert.JSONEq(t, `{"code":"TRANSACTION_NOT_FOUND","message":"","request_id":"abc"}`, rec.Body.String())
+
+	// Only JSON objects carry the ID in the body
+	assert.JSONEq(t, `["a"]`, serve("/list", "abc").Body.String())
+	assert.Equal(t, "{plain}", serve("/text", "abc").Body.String())
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	server, _ := newFakeTopupServer(t, map[string]string{"tsel10": digiflazz.RCSuccess})
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	pool, err := digiflazz.NewPool(config.DigiflazzConfig{
+		BaseURL:       server.URL,
+		Username:      "user",
+		APIKey:        "key",
+		Timeout:       5 * time.Second,
+		RetryAttempts: 1,
+	}, logger)
+	require.NoError(t, err)
+	logger.SetOutput(&logs)
+
+	repository := repositories.NewMemoryOtomaxTransactionRepository()
+	service := services.NewOtomaxService(pool, repository, logger, "secret")
+
+	ctx := requestid.WithContext(context.Background(), "req-123")
+	_, err = service.ProcessTransaction(ctx, models.OtomaxTransactionRequest{
+		RefID:      "TRX400",
+		CustomerNo: "081234567890",
+		BuyerSKU:   "tsel10",
+		Amount:     "10000",
+		Type:       "prabayar",
+	})
+	require.NoError(t, err)
+
+	// Every service and Digiflazz client log line carries the request ID
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	assert.Greater(t, len(lines), 3)
+	for _, line := range lines {
+		assert.Contains(t, line, "request_id=req-123")
+	}
+
+	// The ID is stored on the transaction and returned by status checks
+	stored, err := repository.GetByRefID("TRX400")
+	require.NoError(t, err)
+	assert.Equal(t, "req-123", stored.RequestID)
+
+	status, err := service.CheckStatus(context.Background(), models.OtomaxStatusRequest{RefID: "TRX400"})
+	require.NoError(t, err)
+	assert.Equal(t, "req-123", status.TransactionRequestID)
+}
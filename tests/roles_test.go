@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/middleware"
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyRoles(t *testing.T) {
+	store, err := middleware.NewAPIKeyStore([]config.APIKeyConfig{
+		{Name: "root", KeyHash: middleware.HashAPIKey("root-key"), Scopes: []string{middleware.ScopeAdmin}},
+		{Name: "otomax", KeyHash: middleware.HashAPIKey("otomax-key"), Scopes: []string{middleware.ScopeTransact}},
+		{Name: "ops", KeyHash: middleware.HashAPIKey("ops-key"), Scopes: []string{middleware.ScopeAdmin}, Role: middleware.RoleOperator},
+	})
+	require.NoError(t, err)
+
+	// Keys without a role default by scope
+	usage := store.Usage()
+	require.Len(t, usage, 3)
+	assert.Equal(t, middleware.RoleOperator, usage[0].Role)
+	assert.Equal(t, middleware.RoleReseller, usage[1].Role)
+	assert.Equal(t, middleware.RoleAdmin, usage[2].Role)
+
+	_, err = middleware.NewAPIKeyStore([]config.APIKeyConfig{
+		{Name: "root", KeyHash: middleware.HashAPIKey("root-key"), Scopes: []string{middleware.ScopeAdmin}, Role: "superuser"},
+	})
+	assert.Error(t, err)
+}
+
+func TestManagementRolesAndAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store, err := middleware.NewAPIKeyStore([]config.APIKeyConfig{
+		{Name: "root", KeyHash: middleware.HashAPIKey("root-key"), Scopes: []string{middleware.ScopeAdmin}},
+		{Name: "ops", KeyHash: middleware.HashAPIKey("ops-key"), Scopes: []string{middleware.ScopeAdmin}, Role: middleware.RoleOperator},
+		{Name: "reseller", KeyHash: middleware.HashAPIKey("reseller-key"), Scopes: []string{middleware.ScopeAdmin}, Role: middleware.RoleReseller},
+	})
+	require.NoError(t, err)
+	auditLog := repositories.NewFileAuditLogRepository(filepath.Join(t.TempDir(), "audit.log"))
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	admin := middleware.APIKeyAuth(store, middleware.ScopeAdmin, logger)
+	router.GET("/pln/stats", admin, middleware.RequireRole(store, middleware.RoleOperator, logger), ok)
+	router.DELETE("/pln/cache/:customer_no", admin, middleware.Audit(auditLog, "pln.cache.clear", logger),
+		middleware.RequireRole(store, middleware.RoleAdmin, logger), ok)
+	router.PUT("/pln/cache/config", admin, middleware.Audit(auditLog, "pln.cache.config.update", logger),
+		middleware.RequireRole(store, middleware.RoleAdmin, logger), func(c *gin.Context) {
+			middleware.SetAuditDetails(c, map[string]interface{}{"enabled": false})
+			c.Status(http.StatusOK)
+		})
+
+	serve := func(method, path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "10.0.0.5:4000"
+		if apiKey != "" {
+			req.Header.Set(middleware.APIKeyHeader, apiKey)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/pln/stats", "ops-key").Code)
+	rec := serve(http.MethodGet, "/pln/stats", "reseller-key")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "INSUFFICIENT_ROLE")
+
+	rec = serve(http.MethodDelete, "/pln/cache/123456789012", "ops-key")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "INSUFFICIENT_ROLE")
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodDelete, "/pln/cache/123456789012", "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/pln/cache/123456789012", "root-key").Code)
+
+	// Every authenticated destructive attempt is audited, newest first; callers without
+	// a valid key cannot add entries
+	entries, err := auditLog.Recent(10)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "root", entries[0].Actor)
+	assert.Equal(t, middleware.RoleAdmin, entries[0].Role)
+	assert.Equal(t, "pln.cache.clear", entries[0].Action)
+	assert.Equal(t, "123456789012", entries[0].Target)
+	assert.Equal(t, "10.0.0.5", entries[0].IP)
+	assert.Equal(t, http.StatusOK, entries[0].Status)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.Equal(t, "ops", entries[1].Actor)
+	assert.Equal(t, http.StatusForbidden, entries[1].Status)
+
+	// Entries carry the values the action changed
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/pln/cache/config", "root-key").Code)
+	entries, err = auditLog.Recent(1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "pln.cache.config.update", entries[0].Action)
+	assert.Equal(t, map[string]interface{}{"enabled": false}, entries[0].Details)
+
+	entries, err = auditLog.Recent(0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "ops", entries[2].Actor)
+}
+
+func TestManagementRoutesFailClosedWithoutKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store, err := middleware.NewAPIKeyStore(nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/balance", middleware.APIKeyAuth(store, middleware.ScopeInquiry, logger), ok)
+	router.DELETE("/pln/cache", middleware.APIKeyAuth(store, middleware.ScopeAdmin, logger),
+		middleware.RequireRole(store, middleware.RoleAdmin, logger), ok)
+
+	// Ordinary routes stay open while no key is configured, management routes do not
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/balance", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/pln/cache", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "MANAGEMENT_DISABLED")
+}
+
+func TestAuditLogRecentReadsFromEnd(t *testing.T) {
+	auditLog := repositories.NewFileAuditLogRepository(filepath.Join(t.TempDir(), "audit.log"))
+	// Enough entries to span several read chunks
+	for i := 0; i < 2000; i++ {
+		require.NoError(t, auditLog.Append(models.AuditEntry{
+			Actor:  fmt.Sprintf("client-%d", i),
+			Action: "pln.cache.clear",
+			Target: strings.Repeat("1", 12),
+		}))
+	}
+
+	entries, err := auditLog.Recent(3)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "client-1999", entries[0].Actor)
+	assert.Equal(t, "client-1997", entries[2].Actor)
+
+	entries, err = auditLog.Recent(0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2000)
+	assert.Equal(t, "client-0", entries[1999].Actor)
+}
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/pkg/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteCacheNamespaces(t *testing.T) {
+	ctx := context.Background()
+	sqliteCache, err := cache.NewSQLiteCache(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer sqliteCache.Close()
+
+	products := sqliteCache.Namespace("products")
+	require.NoError(t, sqliteCache.Set(ctx, "key", "pln", 0))
+	require.NoError(t, products.Set(ctx, "key", "product", 0))
+	require.NoError(t, products.Set(ctx, "other", "product", 0))
+
+	value, err := sqliteCache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "pln", value)
+	value, err = products.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "product", value)
+
+	stats, err := products.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "products", stats["namespace"])
+	assert.Equal(t, 2, stats["total_entries"])
+
+	require.NoError(t, products.ClearAll(ctx))
+	_, err = products.Get(ctx, "key")
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	value, err = sqliteCache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "pln", value)
+
+	counts, err := sqliteCache.Namespaces(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{cache.DefaultNamespace: 1}, counts)
+
+	// Closing a namespaced view leaves the shared database open
+	require.NoError(t, products.Close())
+	assert.NoError(t, sqliteCache.Ping(ctx))
+}
+
+func TestSQLiteCacheDeleteExpiredKeepsPermanentEntries(t *testing.T) {
+	ctx := context.Background()
+	sqliteCache, err := cache.NewSQLiteCache(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer sqliteCache.Close()
+
+	require.NoError(t, sqliteCache.Set(ctx, "permanent", "value", 0))
+	require.NoError(t, sqliteCache.Set(ctx, "short", "value", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = sqliteCache.Get(ctx, "short")
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	require.NoError(t, sqliteCache.DeleteExpired(ctx))
+
+	value, err := sqliteCache.Get(ctx, "permanent")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	stats, err := sqliteCache.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats["total_entries"])
+	assert.Equal(t, 1, stats["persistent_entries"])
+}
+
+func TestSQLiteCacheMigratesLegacyTable(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "cache.db")
+
+	legacy, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+	CREATE TABLE pln_inquiry_cache (
+		customer_no TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL
+	)`)
+	require.NoError(t, err)
+	now := time.Now()
+	insert := `INSERT INTO pln_inquiry_cache (customer_no, data, created_at, expires_at) VALUES (?, ?, ?, ?)`
+	_, err = legacy.Exec(insert, "pln_inquiry:1", "permanent", now, time.Time{})
+	require.NoError(t, err)
+	_, err = legacy.Exec(insert, "pln_inquiry:2", "active", now, now.Add(time.Hour))
+	require.NoError(t, err)
+	_, err = legacy.Exec(insert, "pln_inquiry:3", "expired", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	sqliteCache, err := cache.NewSQLiteCache(dbPath)
+	require.NoError(t, err)
+	defer sqliteCache.Close()
+
+	version, err := sqliteCache.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	value, err := sqliteCache.Get(ctx, "pln_inquiry:1")
+	require.NoError(t, err)
+	assert.Equal(t, "permanent", value)
+	value, err = sqliteCache.Get(ctx, "pln_inquiry:2")
+	require.NoError(t, err)
+	assert.Equal(t, "active", value)
+	_, err = sqliteCache.Get(ctx, "pln_inquiry:3")
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	// Reopening does not run the migrations again
+	require.NoError(t, sqliteCache.Close())
+	sqliteCache, err = cache.NewSQLiteCache(dbPath)
+	require.NoError(t, err)
+	version, err = sqliteCache.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	var tables int
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'pln_inquiry_cache'`).Scan(&tables))
+	assert.Equal(t, 0, tables)
+
+	var journalMode string
+	require.NoError(t, db.QueryRow(`PRAGMA journal_mode`).Scan(&journalMode))
+	assert.Equal(t, "wal", journalMode)
+}
+
+func TestSQLiteCacheConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	sqliteCache, err := cache.NewSQLiteCacheWithOptions(filepath.Join(t.TempDir(), "cache.db"), cache.SQLiteOptions{MaxOpenConns: 4})
+	require.NoError(t, err)
+	defer sqliteCache.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := "key:" + strconv.Itoa(i)
+			if err := sqliteCache.Set(ctx, key, "value", time.Hour); err != nil {
+				errs <- err
+				return
+			}
+			if _, err := sqliteCache.Get(ctx, key); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	stats, err := sqliteCache.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 200, stats["total_entries"])
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"gateway-digiflazz/internal/models"
+	"gateway-digiflazz/internal/services"
+	"gateway-digiflazz/pkg/cache"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tierStats returns the hit counters of one tier from TieredCache stats
+func tierStats(t *testing.T, tiered *cache.TieredCache, tier string) map[string]interface{} {
+	stats, err := tiered.GetStats(context.Background())
+	require.NoError(t, err)
+	return stats[tier].(map[string]interface{})
+}
+
+// hookedCache runs afterGet once an entry has been read from the backend
+type hookedCache struct {
+	*cache.MemoryCache
+	afterGet func()
+}
+
+// GetEntry reads the entry and then runs the hook
+func (h *hookedCache) GetEntry(ctx context.Context, key string) (cache.Entry, error) {
+	entry, err := h.MemoryCache.GetEntry(ctx, key)
+	if h.afterGet != nil {
+		h.afterGet()
+	}
+	return entry, err
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ReadsFromMemoryAfterWriteThrough", func(t *testing.T) {
+		backend := cache.NewMemoryCache()
+		tiered := cache.NewTieredCache(backend, cache.TieredOptions{MaxEntries: 10})
+
+		require.NoError(t, tiered.Set(ctx, "key", "value", 0))
+		stored, err := backend.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", stored)
+
+		value, err := tiered.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+		assert.Equal(t, int64(1), tierStats(t, tiered, "memory_tier")["hits"])
+		assert.Equal(t, int64(0), tierStats(t, tiered, "backend_tier")["hits"])
+	})
+
+	t.Run("FillsMemoryFromBackend", func(t *testing.T) {
+		backend := cache.NewMemoryCache()
+		require.NoError(t, backend.Set(ctx, "key", "value", 0))
+		tiered := cache.NewTieredCache(backend, cache.TieredOptions{MaxEntries: 10})
+
+		for i := 0; i < 2; i++ {
+			_, err := tiered.Get(ctx, "key")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int64(1), tierStats(t, tiered, "backend_tier")["hits"])
+		assert.Equal(t, int64(1), tierStats(t, tiered, "memory_tier")["hits"])
+
+		_, err := tiered.Get(ctx, "missing")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+		assert.Equal(t, int64(1), tierStats(t, tiered, "backend_tier")["misses"])
+	})
+
+	t.Run("EvictsByEntriesAndBytes", func(t *testing.T) {
+		tiered := cache.NewTieredCache(cache.NewMemoryCache(), cache.TieredOptions{MaxEntries: 3, MaxBytes: 1 << 20})
+		for i := 0; i < 5; i++ {
+			require.NoError(t, tiered.Set(ctx, "key:"+strconv.Itoa(i), "value", 0))
+		}
+		memory := tierStats(t, tiered, "memory_tier")
+		assert.Equal(t, 3, memory["entries"])
+		assert.Equal(t, int64(2), memory["evictions"])
+
+		bounded := cache.NewTieredCache(cache.NewMemoryCache(), cache.TieredOptions{MaxEntries: 100, MaxBytes: 20})
+		require.NoError(t, bounded.Set(ctx, "a", "0123456789", 0))
+		require.NoError(t, bounded.Set(ctx, "b", "0123456789", 0))
+		memory = tierStats(t, bounded, "memory_tier")
+		assert.Equal(t, 1, memory["entries"])
+		assert.LessOrEqual(t, memory["bytes"].(int64), int64(20))
+	})
+
+	t.Run("CoherentDeletes", func(t *testing.T) {
+		backend := cache.NewMemoryCache()
+		tiered := cache.NewTieredCache(backend, cache.TieredOptions{})
+
+		require.NoError(t, tiered.Set(ctx, "a", "1", 0))
+		require.NoError(t, tiered.Set(ctx, "b", "2", 0))
+		require.NoError(t, tiered.Delete(ctx, "a"))
+		_, err := tiered.Get(ctx, "a")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+
+		require.NoError(t, tiered.ClearAll(ctx))
+		_, err = tiered.Get(ctx, "b")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+		_, err = backend.Get(ctx, "b")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+	})
+
+	t.Run("MemoryTTL", func(t *testing.T) {
+		backend := cache.NewMemoryCache()
+		tiered := cache.NewTieredCache(backend, cache.TieredOptions{TTL: 10 * time.Millisecond})
+
+		require.NoError(t, tiered.Set(ctx, "key", "old", 0))
+		require.NoError(t, backend.Set(ctx, "key", "new", 0))
+		time.Sleep(20 * time.Millisecond)
+
+		value, err := tiered.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "new", value)
+	})
+
+	t.Run("MemoryTTLCappedAtBackendExpiry", func(t *testing.T) {
+		backend := cache.NewMemoryCache()
+		require.NoError(t, backend.Set(ctx, "key", "value", 30*time.Millisecond))
+		tiered := cache.NewTieredCache(backend, cache.TieredOptions{TTL: time.Hour})
+
+		_, err := tiered.Get(ctx, "key")
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+
+		_, err = tiered.Get(ctx, "key")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+	})
+
+	t.Run("DeleteDuringBackendRead", func(t *testing.T) {
+		for name, remove := range map[string]func(tiered *cache.TieredCache) error{
+			"Delete":   func(tiered *cache.TieredCache) error { return tiered.Delete(ctx, "key") },
+			"ClearAll": func(tiered *cache.TieredCache) error { return tiered.ClearAll(ctx) },
+		} {
+			t.Run(name, func(t *testing.T) {
+				backend := &hookedCache{MemoryCache: cache.NewMemoryCache()}
+				require.NoError(t, backend.Set(ctx, "key", "value", 0))
+				tiered := cache.NewTieredCache(backend, cache.TieredOptions{})
+
+				// The entry is removed after the backend read but before the read returns
+				backend.afterGet = func() {
+					backend.afterGet = nil
+					require.NoError(t, remove(tiered))
+				}
+				value, err := tiered.Get(ctx, "key")
+				require.NoError(t, err)
+				assert.Equal(t, "value", value)
+
+				_, err = tiered.Get(ctx, "key")
+				assert.ErrorIs(t, err, cache.ErrNotFound)
+			})
+		}
+	})
+
+	t.Run("PLNInquiryService", func(t *testing.T) {
+		server, _ := newFakePLNServer(t, 0)
+		tiered := cache.NewTieredCache(cache.NewMemoryCache(), cache.TieredOptions{})
+		service := services.NewPLNInquiryService(newDigiflazzClientForTest(server.URL), logrus.New(), tiered)
+
+		for i := 0; i < 3; i++ {
+			_, err := service.InquiryPLN(context.Background(), models.PLNInquiryRequest{CustomerNo: "12345678901"}, "REF")
+			require.NoError(t, err)
+		}
+		require.NoError(t, service.ClearCache("12345678901"))
+		_, err := tiered.Get(context.Background(), "pln_inquiry:12345678901")
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+
+		stats := service.GetStats()
+		require.Contains(t, stats.Cache, "memory_tier")
+		assert.Equal(t, int64(2), stats.Cache["memory_tier"].(map[string]interface{})["hits"])
+	})
+}
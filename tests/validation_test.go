@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"testing"
+
+	"gateway-digiflazz/internal/config"
+	"gateway-digiflazz/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorRegistry(t *testing.T) {
+	registry := validation.NewRegistry(config.ValidationConfig{
+		SKUPrefixes: map[string]string{"ml": "mobile legends", "pdamsby": "pdam kota surabaya"},
+		Lengths: []config.ValidationLengthConfig{
+			{Key: "pdam kota surabaya", MinLength: 8, MaxLength: 8},
+		},
+	})
+
+	valid := []struct {
+		name, key, input, expected string
+	}{
+		{"PLNMeter", "pln", "123 4567 8901", "12345678901"},
+		{"PLNIDPelanggan", "PLN", "123456789012", "123456789012"},
+		{"EWallet", "dana", "+6281234567890", "081234567890"},
+		{"BPJS", "bpjs", "8888801234567890", "8888801234567890"},
+		{"MobileLegends", "mobile legends", "12345678(1234)", "123456781234"},
+		{"Unregistered", "voucher", "anything", "anything"},
+	}
+	for _, tc := range valid {
+		t.Run(tc.name, func(t *testing.T) {
+			normalized, err := registry.Validate(tc.key, tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, normalized)
+		})
+	}
+
+	invalid := []struct {
+		name, key, input string
+	}{
+		{"PLNTooShort", "pln", "12345"},
+		{"BPJSPrefix", "bpjs", "1234501234567890"},
+		{"GameID", "games", "ab"},
+		{"EWalletLandline", "ovo", "0215551234"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := registry.Validate(tc.key, tc.input)
+			fieldErrors, ok := validation.AsErrors(err)
+			require.True(t, ok)
+			assert.Contains(t, fieldErrors, "customer_no")
+		})
+	}
+
+	t.Run("PLNLengthRange", func(t *testing.T) {
+		for _, customerNo := range []string{"1234567890", "123456789012345"} {
+			normalized, err := registry.Validate("pln", customerNo)
+			require.NoError(t, err)
+			assert.Equal(t, customerNo, normalized)
+		}
+		for _, customerNo := range []string{"123456789", "1234567890123456"} {
+			_, err := registry.Validate("pln", customerNo)
+			assert.Error(t, err)
+		}
+
+		// A configured length rule replaces the built-in PLN validator
+		strict := validation.NewRegistry(config.ValidationConfig{
+			Lengths: []config.ValidationLengthConfig{{Key: "pln", MinLength: 11, MaxLength: 12}},
+		})
+		_, err := strict.Validate("pln", "1234567890")
+		assert.Error(t, err)
+		_, err = strict.Validate("pln", "12345678901")
+		assert.NoError(t, err)
+	})
+
+	t.Run("CategoryTakesPrecedence", func(t *testing.T) {
+		// The generic pdam validator accepts what the pdamsby prefix would reject
+		normalized, err := registry.ValidateCustomerNo("pdamsby50", "pdam", "12345")
+		require.NoError(t, err)
+		assert.Equal(t, "12345", normalized)
+
+		_, err = registry.ValidateCustomerNo("ml86", "games", "12345678 1234")
+		assert.Error(t, err)
+
+		// Categories without a validator fall back to the SKU prefix
+		_, err = registry.ValidateCustomerNo("pdamsby50", "air", "12345")
+		assert.Error(t, err)
+
+		normalized, err = registry.ValidateCustomerNo("ml86", "", "12345678 1234")
+		require.NoError(t, err)
+		assert.Equal(t, "123456781234", normalized)
+	})
+
+	t.Run("DefaultSKUPrefixes", func(t *testing.T) {
+		// Requests without a category are validated by the built-in prefixes
+		_, err := registry.ValidateCustomerNo("pln20", "", "12345")
+		assert.Error(t, err)
+
+		normalized, err := registry.ValidateCustomerNo("tsel10", "", "+6281234567890")
+		require.NoError(t, err)
+		assert.Equal(t, "081234567890", normalized)
+
+		// A configured prefix with an empty key turns the default off
+		registry := validation.NewRegistry(config.ValidationConfig{SKUPrefixes: map[string]string{"pln": ""}})
+		normalized, err = registry.ValidateCustomerNo("pln20", "", "12345")
+		require.NoError(t, err)
+		assert.Equal(t, "12345", normalized)
+	})
+}